@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pierrec/lz4/v4"
+	"gocloud.dev/blob"
+)
+
+type (
+	downloader struct {
+		bucket *blob.Bucket
+		root   string
+		// decompress stores objects as plain JSON instead of LZ4.
+		decompress bool
+		since      time.Time
+		until      time.Time
+	}
+)
+
+const partialExtension = ".part"
+
+// prefix returns the prefix of the objects for an organization, a project
+// and a profiler, each one requiring the previous one.
+func prefix(organizationID, projectID uint64, profilerID string) (string, error) {
+	switch {
+	case organizationID == 0 && (projectID != 0 || profilerID != ""):
+		return "", errors.New("an organization is required to filter by project")
+	case projectID == 0 && profilerID != "":
+		return "", errors.New("a project is required to filter by profiler")
+	case profilerID != "":
+		return fmt.Sprintf("%d/%d/%s/", organizationID, projectID, profilerID), nil
+	case projectID != 0:
+		return fmt.Sprintf("%d/%d/", organizationID, projectID), nil
+	case organizationID != 0:
+		return fmt.Sprintf("%d/", organizationID), nil
+	}
+	return "", nil
+}
+
+// objectKey returns the key of an object from a path relative to the bucket
+// or a URL like the ones listed by gsutil.
+func objectKey(path string) string {
+	if i := strings.Index(path, "://"); i != -1 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j != -1 {
+			return path[j+1:]
+		}
+		return ""
+	}
+	return strings.TrimPrefix(path, "/")
+}
+
+// list calls fn for each object under the prefix modified in the time range.
+func (d *downloader) list(ctx context.Context, prefix string, fn func(key string)) error {
+	it := d.bucket.List(&blob.ListOptions{Prefix: prefix})
+	for {
+		obj, err := it.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if obj.IsDir || !d.inTimeRange(obj.ModTime) {
+			continue
+		}
+		fn(obj.Key)
+	}
+}
+
+func (d *downloader) filterByTime() bool {
+	return !d.since.IsZero() || !d.until.IsZero()
+}
+
+func (d *downloader) inTimeRange(t time.Time) bool {
+	if !d.since.IsZero() && t.Before(d.since) {
+		return false
+	}
+	if !d.until.IsZero() && !t.Before(d.until) {
+		return false
+	}
+	return true
+}
+
+func (d *downloader) destination(key string) string {
+	extension := ".lz4"
+	if d.decompress {
+		extension = ".json"
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)+extension)
+}
+
+// download downloads an object, skipping it if it was already downloaded.
+// The compressed object is first written to a partial file, resumed from
+// where it stopped if it exists, and moved once complete.
+func (d *downloader) download(ctx context.Context, key string) (bool, error) {
+	path := d.destination(key)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if d.filterByTime() {
+		attrs, err := d.bucket.Attributes(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if !d.inTimeRange(attrs.ModTime) {
+			return false, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+
+	partialPath := path + partialExtension
+	f, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return false, err
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+	complete := false
+	if offset > 0 {
+		attrs, err := d.bucket.Attributes(ctx, key)
+		if err != nil {
+			f.Close()
+			return false, err
+		}
+		complete = offset >= attrs.Size
+	}
+	if !complete {
+		r, err := d.bucket.NewRangeReader(ctx, key, offset, -1, nil)
+		if err != nil {
+			f.Close()
+			return false, err
+		}
+		_, err = io.Copy(f, r)
+		r.Close()
+		if err != nil {
+			f.Close()
+			return false, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+
+	if !d.decompress {
+		return true, os.Rename(partialPath, path)
+	}
+	if err := decompress(partialPath, path); err != nil {
+		return false, err
+	}
+	return true, os.Remove(partialPath)
+}
+
+// decompress writes the decompressed content of src to dst.
+func decompress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmpPath := dst + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, lz4.NewReader(in)); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, dst)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pierrec/lz4/v4"
+	"gocloud.dev/blob"
+)
+
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		name           string
+		organizationID uint64
+		projectID      uint64
+		profilerID     string
+		want           string
+		wantErr        bool
+	}{
+		{name: "everything"},
+		{name: "organization", organizationID: 1, want: "1/"},
+		{name: "project", organizationID: 1, projectID: 2, want: "1/2/"},
+		{name: "profiler", organizationID: 1, projectID: 2, profilerID: "abc", want: "1/2/abc/"},
+		{name: "project without organization", projectID: 2, wantErr: true},
+		{name: "profiler without project", organizationID: 1, profilerID: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prefix(tt.organizationID, tt.projectID, tt.profilerID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	for path, want := range map[string]string{
+		"gs://sentryio-profiles/1/2/abc": "1/2/abc",
+		"/1/2/abc":                       "1/2/abc",
+		"1/2/abc":                        "1/2/abc",
+		"gs://sentryio-profiles":         "",
+	} {
+		if got := objectKey(path); got != want {
+			t.Fatalf("objectKey(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ctx := context.Background()
+	bucket, err := blob.OpenBucket(ctx, "file://localhost/"+t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bucket.Close()
+
+	content := []byte(`{"profile_id":"abc","platform":"python"}`)
+	var compressed bytes.Buffer
+	zw := lz4.NewWriter(&compressed)
+	if _, err := zw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"1/2/abc", "1/3/def"} {
+		if err := bucket.WriteAll(ctx, key, compressed.Bytes(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("resume and decompress", func(t *testing.T) {
+		d := &downloader{bucket: bucket, root: t.TempDir(), decompress: true}
+		path := d.destination("1/2/abc")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		// simulate an interrupted download
+		partial := compressed.Bytes()[:compressed.Len()/2]
+		if err := os.WriteFile(path+partialExtension, partial, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		downloaded, err := d.download(ctx, "1/2/abc")
+		if err != nil || !downloaded {
+			t.Fatalf("expected a download, got %v, %v", downloaded, err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, content) {
+			t.Fatalf("unexpected content: %s", b)
+		}
+		if _, err := os.Stat(path + partialExtension); !os.IsNotExist(err) {
+			t.Fatalf("expected the partial file to be removed: %v", err)
+		}
+		downloaded, err = d.download(ctx, "1/2/abc")
+		if err != nil || downloaded {
+			t.Fatalf("expected the object to be skipped, got %v, %v", downloaded, err)
+		}
+	})
+
+	t.Run("list with prefix and time range", func(t *testing.T) {
+		d := &downloader{
+			bucket: bucket,
+			root:   t.TempDir(),
+			since:  time.Now().Add(-time.Hour),
+		}
+		var keys []string
+		if err := d.list(ctx, "1/2/", func(key string) { keys = append(keys, key) }); err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "1/2/abc" {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+		d.since = time.Now().Add(time.Hour)
+		keys = nil
+		if err := d.list(ctx, "", func(key string) { keys = append(keys, key) }); err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+	})
+}
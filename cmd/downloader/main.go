@@ -3,131 +3,132 @@ package main
 import (
 	"bufio"
 	"context"
-	"errors"
-	"fmt"
-	"io"
+	"flag"
 	"log"
 	"os"
-	"strings"
 	"sync"
+	"time"
 
-	"cloud.google.com/go/storage"
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/s3blob"
 )
 
-func download(
-	client *storage.Client,
-	root string,
-	objects chan string,
-	errorsChan chan error,
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
-
-	b := client.Bucket("sentryio-profiles")
-	for objectName := range objects {
-		parts := strings.Split(objectName, "/")
-		count := len(parts)
-		dirPath := fmt.Sprintf("%s/%s/%s", root, parts[count-3], parts[count-2])
-
-		if _, err := os.Stat(dirPath); errors.Is(err, os.ErrNotExist) {
-			err := os.MkdirAll(dirPath, 0755)
-			if err != nil {
-				errorsChan <- err
-				continue
-			}
-		}
-
-		objectName := fmt.Sprintf("%s/%s/%s", parts[count-3], parts[count-2], parts[count-1])
-		fileName := fmt.Sprintf("%s.json", objectName)
-		path := fmt.Sprintf("%s/%s", root, fileName)
-
-		if _, err := os.Stat(path); err == nil {
-			continue
-		}
-
-		f, err := os.Create(path)
-		if err != nil {
-			errorsChan <- err
-			continue
-		}
-
-		ctx := context.Background()
-		rc, err := b.Object(objectName).NewReader(ctx)
-		if err != nil {
-			errorsChan <- err
-			continue
-		}
-
-		if _, err := io.Copy(f, rc); err != nil {
-			errorsChan <- err
-			continue
-		}
-
-		err = rc.Close()
-		if err != nil {
-			errorsChan <- err
-			continue
-		}
-
-		err = f.Close()
-		if err != nil {
-			errorsChan <- err
-			continue
-		}
-
-		log.Println(objectName)
+func parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatal(err)
 	}
+	return t
 }
 
 func main() {
-	args := os.Args[1:]
-	if len(args) != 2 {
-		fmt.Println( // nolint
-			"./downloader <file of relative object paths> <destination directory>",
-		)
-		return
+	bucketURL := flag.String("bucket", "", "URL of the bucket to download from (gs://, s3://, azblob://, file://)")
+	destination := flag.String("destination", "", "directory where the objects will be stored")
+	objectsPath := flag.String("objects", "", "path to a file with one object path per line, instead of listing the bucket")
+	organizationID := flag.Uint64("organization", 0, "only download objects of this organization")
+	projectID := flag.Uint64("project", 0, "only download objects of this project")
+	profilerID := flag.String("profiler", "", "only download chunks of this profiler")
+	since := flag.String("since", "", "only download objects modified at or after this time (RFC 3339)")
+	until := flag.String("until", "", "only download objects modified before this time (RFC 3339)")
+	decompress := flag.Bool("decompress", false, "decompress objects and store them as plain JSON")
+	workers := flag.Int("workers", 128, "number of concurrent downloads")
+
+	flag.Parse()
+
+	if *bucketURL == "" || *destination == "" {
+		flag.Usage()
+		os.Exit(2)
 	}
-
-	ctx := context.Background()
-	storageClient, err := storage.NewClient(ctx)
+	p, err := prefix(*organizationID, *projectID, *profilerID)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer storageClient.Close()
+	sinceTime, untilTime := parseTime(*since), parseTime(*until)
 
-	objectPathList := args[0]
-	destination := args[1]
-	file, err := os.Open(objectPathList)
+	ctx := context.Background()
+	bucket, err := blob.OpenBucket(ctx, *bucketURL)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer file.Close()
+
+	d := &downloader{
+		bucket:     bucket,
+		root:       *destination,
+		decompress: *decompress,
+		since:      sinceTime,
+		until:      untilTime,
+	}
 
 	var wg sync.WaitGroup
 
 	objects := make(chan string)
 	errorsChan := make(chan error)
-	for i := 0; i < 128; i++ {
+	for i := 0; i < *workers; i++ {
 		wg.Add(1)
-		go download(storageClient, destination, objects, errorsChan, &wg)
+		go func() {
+			defer wg.Done()
+			for key := range objects {
+				downloaded, err := d.download(ctx, key)
+				if err != nil {
+					errorsChan <- err
+					continue
+				}
+				if downloaded {
+					log.Println(key)
+				}
+			}
+		}()
 	}
 
+	printed := make(chan struct{})
 	go func() {
+		defer close(printed)
 		for err := range errorsChan {
 			log.Println(err)
 		}
 	}()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		objects <- scanner.Text()
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
-	}
+	err = feed(ctx, d, *objectsPath, p, objects)
 
 	close(objects)
 	wg.Wait()
 	close(errorsChan)
+	<-printed
+
+	bucket.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// feed sends the keys of the objects to download, read from the objects
+// file if set or listed under the prefix otherwise.
+func feed(ctx context.Context, d *downloader, objectsPath, prefix string, objects chan<- string) error {
+	if objectsPath == "" {
+		return d.list(ctx, prefix, func(key string) {
+			objects <- key
+		})
+	}
+
+	file, err := os.Open(objectsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key := objectKey(scanner.Text())
+		if key == "" {
+			continue
+		}
+		objects <- key
+	}
+	return scanner.Err()
 }
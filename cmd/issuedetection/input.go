@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+type (
+	// input lists and opens the compressed profiles to analyze.
+	input interface {
+		Walk(ctx context.Context, fn func(name string) error) error
+		Open(ctx context.Context, name string) (io.ReadCloser, error)
+		Close() error
+	}
+
+	// localInput reads profiles from a file or a directory.
+	localInput struct {
+		root string
+	}
+
+	// bucketInput reads profiles from a bucket, only listing objects
+	// under the given prefixes.
+	bucketInput struct {
+		bucket   *blob.Bucket
+		prefixes []string
+	}
+)
+
+func newBucketInput(ctx context.Context, url string, prefixes []string) (*bucketInput, error) {
+	b, err := blob.OpenBucket(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	return &bucketInput{bucket: b, prefixes: prefixes}, nil
+}
+
+func (i localInput) Walk(_ context.Context, fn func(name string) error) error {
+	return filepath.WalkDir(i.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+func (i localInput) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (i localInput) Close() error {
+	return nil
+}
+
+func (i *bucketInput) Walk(ctx context.Context, fn func(name string) error) error {
+	for _, prefix := range i.prefixes {
+		it := i.bucket.List(&blob.ListOptions{Prefix: prefix})
+		for {
+			obj, err := it.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if obj.IsDir {
+				continue
+			}
+			if err := fn(obj.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (i *bucketInput) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return i.bucket.NewReader(ctx, name, nil)
+}
+
+func (i *bucketInput) Close() error {
+	return i.bucket.Close()
+}
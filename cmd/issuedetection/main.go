@@ -1,15 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
-	"fmt"
 	"io"
-	"io/fs"
 	"log"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"strings"
 	"sync"
 
 	gojson "github.com/goccy/go-json"
@@ -23,95 +23,174 @@ const (
 	workersCount int = 512
 )
 
+type result struct {
+	records []record
+	err     error
+}
+
 func main() {
 	debug := flag.Bool("debug", false, "activate debug logs")
 	root := flag.String("path", ".", "path to a profile or a directory with profiles")
+	bucketURL := flag.String("bucket", "", "URL of a bucket with profiles (gs://, s3://, azblob://, file://), used instead of path")
+	prefixes := flag.String("prefix", "", "comma-separated list of prefixes of the objects to read from the bucket")
+	format := flag.String("format", formatText, "output format: text, jsonl or csv")
+	summaryPath := flag.String("summary", "", "path to write a JSON summary of the occurrences found")
+	rulesPath := flag.String("rules", "", "path to a YAML or JSON file with occurrence detection rules")
 
 	flag.Parse()
 
+	if *rulesPath != "" {
+		b, err := os.ReadFile(*rulesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rs, err := occurrence.ParseRules(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		occurrence.SetRuleSet(rs)
+	}
+
 	if *debug {
 		opts := slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		}
-		handler := slog.NewTextHandler(os.Stdout, &opts)
+		handler := slog.NewTextHandler(os.Stderr, &opts)
 		slog.SetDefault(slog.New(handler))
 	}
 
-	f, err := os.Open(*root)
+	w, err := newRecordWriter(*format, os.Stdout)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer f.Close()
 
+	ctx := context.Background()
+	var in input
+	if *bucketURL != "" {
+		var p []string
+		if *prefixes != "" {
+			p = strings.Split(*prefixes, ",")
+		}
+		in, err = newBucketInput(ctx, *bucketURL, p)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if _, err := os.Stat(*root); err != nil {
+			log.Fatal(err)
+		}
+		in = localInput{root: *root}
+	}
+	err = detect(ctx, in, w, *summaryPath)
+	in.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// detect analyzes every profile of the input, writes the occurrences found
+// and the summary. Records already written are flushed even if the input
+// can't be walked to the end.
+func detect(ctx context.Context, in input, w recordWriter, summaryPath string) error {
 	pathChannel := make(chan string, workersCount)
-	errChannel := make(chan error)
+	resultChannel := make(chan result, workersCount)
 
+	s := newSummary()
+	done := make(chan struct{})
+	var writeErr error
 	go func() {
-		for err := range errChannel {
-			log.Println(err)
+		defer close(done)
+		for res := range resultChannel {
+			if res.err != nil {
+				s.errors++
+				log.Println(res.err)
+				continue
+			}
+			s.profiles++
+			for _, r := range res.records {
+				s.add(r)
+				if writeErr == nil {
+					writeErr = w.Write(r)
+				}
+			}
 		}
 	}()
 
 	var wg sync.WaitGroup
 
-	for w := 0; w < workersCount; w++ {
+	for i := 0; i < workersCount; i++ {
 		wg.Add(1)
-		go AnalyzeProfile(pathChannel, errChannel, &wg)
+		go AnalyzeProfile(ctx, in, pathChannel, resultChannel, &wg)
 	}
 
-	err = filepath.WalkDir(*root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
+	walkErr := in.Walk(ctx, func(path string) error {
 		pathChannel <- path
 		return nil
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
 
 	close(pathChannel)
 	wg.Wait()
-	close(errChannel)
+	close(resultChannel)
+	<-done
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if summaryPath != "" {
+		b, err := json.MarshalIndent(s.report(), "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(summaryPath, b, 0o644)
+	}
+	return nil
 }
 
-func AnalyzeProfile(pathChannel chan string, errChan chan error, wg *sync.WaitGroup) {
+func AnalyzeProfile(
+	ctx context.Context,
+	in input,
+	pathChannel chan string,
+	resultChannel chan result,
+	wg *sync.WaitGroup,
+) {
 	defer wg.Done()
 
 	for path := range pathChannel {
-		f, err := os.Open(path)
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				errChan <- err
-			}
-			continue
-		}
-		zr := lz4.NewReader(f)
-		var p profile.Profile
-		err = gojson.NewDecoder(zr).Decode(&p)
-		if err != nil {
-			if !errors.Is(err, io.EOF) {
-				errChan <- err
-			}
+		records, err := analyzeProfile(ctx, in, path)
+		if errors.Is(err, io.EOF) {
 			continue
 		}
-		callTrees, err := p.CallTrees()
-		if err != nil {
-			errChan <- err
-			continue
-		}
-		for _, o := range occurrence.Find(p, callTrees) {
-			fmt.Println( // nolint
-				o.Event.Platform,
-				o.Event.ProjectID,
-				o.EvidenceData["profile_id"],
-				o.EvidenceData["frame_duration_ns"],
-				o.IssueTitle,
-				o.Subtitle,
-			)
-		}
+		resultChannel <- result{records: records, err: err}
+	}
+}
+
+func analyzeProfile(ctx context.Context, in input, path string) ([]record, error) {
+	f, err := in.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr := lz4.NewReader(f)
+	var p profile.Profile
+	err = gojson.NewDecoder(zr).Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	callTrees, err := p.CallTrees()
+	if err != nil {
+		return nil, err
+	}
+	occurrences := occurrence.Find(p, callTrees)
+	records := make([]record, 0, len(occurrences))
+	for _, o := range occurrences {
+		records = append(records, newRecord(path, o))
 	}
+	return records, nil
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/platform"
+)
+
+type (
+	// record is an occurrence flattened for output.
+	record struct {
+		Object      string              `json:"object"`
+		Platform    platform.Platform   `json:"platform"`
+		ProjectID   uint64              `json:"project_id"`
+		ProfileID   string              `json:"profile_id"`
+		Category    occurrence.Category `json:"category"`
+		Type        occurrence.Type     `json:"type"`
+		IssueTitle  string              `json:"issue_title"`
+		Function    string              `json:"function"`
+		Package     string              `json:"package"`
+		DurationNS  uint64              `json:"duration_ns"`
+		SampleCount int                 `json:"sample_count"`
+		Rule        string              `json:"rule"`
+		Fingerprint string              `json:"fingerprint"`
+	}
+
+	recordWriter interface {
+		Write(r record) error
+		Flush() error
+	}
+
+	// textWriter writes space-separated fields, one occurrence per line.
+	textWriter struct {
+		w *bufio.Writer
+	}
+
+	jsonlWriter struct {
+		w *bufio.Writer
+		e *json.Encoder
+	}
+
+	csvWriter struct {
+		w *csv.Writer
+	}
+)
+
+const (
+	formatText  = "text"
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+)
+
+var csvHeader = []string{
+	"object",
+	"platform",
+	"project_id",
+	"profile_id",
+	"category",
+	"type",
+	"issue_title",
+	"function",
+	"package",
+	"duration_ns",
+	"sample_count",
+	"rule",
+	"fingerprint",
+}
+
+func newRecord(object string, o *occurrence.Occurrence) record {
+	r := record{
+		Object:      object,
+		Platform:    o.Event.Platform,
+		ProjectID:   o.Event.ProjectID,
+		Category:    o.Category(),
+		Type:        o.Type,
+		IssueTitle:  string(o.IssueTitle),
+		Function:    o.Subtitle,
+		DurationNS:  o.DurationNS(),
+		SampleCount: o.SampleCount(),
+		Rule:        o.Rule(),
+	}
+	if profileID, ok := o.EvidenceData[occurrence.ProfileID].(string); ok {
+		r.ProfileID = profileID
+	}
+	if pkg, ok := o.EvidenceData["frame_package"].(string); ok {
+		r.Package = pkg
+	}
+	if len(o.Fingerprint) > 0 {
+		r.Fingerprint = o.Fingerprint[0]
+	}
+	return r
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case formatText:
+		return &textWriter{w: bufio.NewWriter(w)}, nil
+	case formatJSONL:
+		bw := bufio.NewWriter(w)
+		return &jsonlWriter{w: bw, e: json.NewEncoder(bw)}, nil
+	case formatCSV:
+		// the header is written up front for a run without any occurrence
+		// to still produce a valid CSV
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func (t *textWriter) Write(r record) error {
+	_, err := fmt.Fprintln(
+		t.w,
+		r.Platform,
+		r.ProjectID,
+		r.ProfileID,
+		r.DurationNS,
+		r.IssueTitle,
+		r.Function,
+	)
+	return err
+}
+
+func (t *textWriter) Flush() error {
+	return t.w.Flush()
+}
+
+func (j *jsonlWriter) Write(r record) error {
+	return j.e.Encode(r)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+func (c *csvWriter) Write(r record) error {
+	return c.w.Write([]string{
+		r.Object,
+		string(r.Platform),
+		strconv.FormatUint(r.ProjectID, 10),
+		r.ProfileID,
+		string(r.Category),
+		strconv.Itoa(int(r.Type)),
+		r.IssueTitle,
+		r.Function,
+		r.Package,
+		strconv.FormatUint(r.DurationNS, 10),
+		strconv.Itoa(r.SampleCount),
+		r.Rule,
+		r.Fingerprint,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+type (
+	// summary aggregates occurrences by category, platform, project and
+	// function, to compare the output of different rules on a corpus.
+	summary struct {
+		profiles    uint64
+		errors      uint64
+		occurrences uint64
+		groups      map[string]map[string][]uint64
+	}
+
+	groupReport struct {
+		Key   string `json:"key"`
+		Count int    `json:"count"`
+		P50NS uint64 `json:"p50_ns"`
+		P75NS uint64 `json:"p75_ns"`
+		P95NS uint64 `json:"p95_ns"`
+		P99NS uint64 `json:"p99_ns"`
+		MaxNS uint64 `json:"max_ns"`
+	}
+
+	summaryReport struct {
+		Profiles    uint64                   `json:"profiles"`
+		Errors      uint64                   `json:"errors"`
+		Occurrences uint64                   `json:"occurrences"`
+		Groups      map[string][]groupReport `json:"groups"`
+	}
+)
+
+const (
+	groupCategory = "category"
+	groupPlatform = "platform"
+	groupProject  = "project"
+	groupFunction = "function"
+)
+
+func newSummary() *summary {
+	return &summary{
+		groups: map[string]map[string][]uint64{
+			groupCategory: {},
+			groupPlatform: {},
+			groupProject:  {},
+			groupFunction: {},
+		},
+	}
+}
+
+func (s *summary) add(r record) {
+	s.occurrences++
+	function := r.Function
+	if r.Package != "" {
+		function = r.Package + ":" + r.Function
+	}
+	for group, key := range map[string]string{
+		groupCategory: string(r.Category),
+		groupPlatform: string(r.Platform),
+		groupProject:  strconv.FormatUint(r.ProjectID, 10),
+		groupFunction: function,
+	} {
+		s.groups[group][key] = append(s.groups[group][key], r.DurationNS)
+	}
+}
+
+// report returns the groups sorted by count, then by key.
+func (s *summary) report() summaryReport {
+	report := summaryReport{
+		Profiles:    s.profiles,
+		Errors:      s.errors,
+		Occurrences: s.occurrences,
+		Groups:      make(map[string][]groupReport, len(s.groups)),
+	}
+	for group, keys := range s.groups {
+		groups := make([]groupReport, 0, len(keys))
+		for key, durations := range keys {
+			sort.Slice(durations, func(i, j int) bool {
+				return durations[i] < durations[j]
+			})
+			groups = append(groups, groupReport{
+				Key:   key,
+				Count: len(durations),
+				P50NS: quantile(durations, 0.50),
+				P75NS: quantile(durations, 0.75),
+				P95NS: quantile(durations, 0.95),
+				P99NS: quantile(durations, 0.99),
+				MaxNS: durations[len(durations)-1],
+			})
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if groups[i].Count != groups[j].Count {
+				return groups[i].Count > groups[j].Count
+			}
+			return groups[i].Key < groups[j].Key
+		})
+		report.Groups[group] = groups
+	}
+	return report
+}
+
+// quantile returns the nearest-rank quantile of sorted values.
+func quantile(values []uint64, q float64) uint64 {
+	index := int(math.Ceil(float64(len(values))*q)) - 1
+	return values[max(index, 0)]
+}
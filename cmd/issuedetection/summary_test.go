@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestSummaryReport(t *testing.T) {
+	s := newSummary()
+	s.profiles = 3
+	for _, r := range []record{
+		{Platform: platform.Cocoa, ProjectID: 1, Category: occurrence.FileRead, Package: "Foundation", Function: "read", DurationNS: 30},
+		{Platform: platform.Cocoa, ProjectID: 1, Category: occurrence.FileRead, Package: "Foundation", Function: "read", DurationNS: 10},
+		{Platform: platform.Cocoa, ProjectID: 2, Category: occurrence.FileRead, Package: "Foundation", Function: "read", DurationNS: 20},
+		{Platform: platform.Android, ProjectID: 2, Category: occurrence.JSONDecode, Package: "gson", Function: "fromJson", DurationNS: 40},
+	} {
+		s.add(r)
+	}
+
+	want := summaryReport{
+		Profiles:    3,
+		Occurrences: 4,
+		Groups: map[string][]groupReport{
+			groupCategory: {
+				{Key: "file_read", Count: 3, P50NS: 20, P75NS: 30, P95NS: 30, P99NS: 30, MaxNS: 30},
+				{Key: "json_decode", Count: 1, P50NS: 40, P75NS: 40, P95NS: 40, P99NS: 40, MaxNS: 40},
+			},
+			groupPlatform: {
+				{Key: "cocoa", Count: 3, P50NS: 20, P75NS: 30, P95NS: 30, P99NS: 30, MaxNS: 30},
+				{Key: "android", Count: 1, P50NS: 40, P75NS: 40, P95NS: 40, P99NS: 40, MaxNS: 40},
+			},
+			groupProject: {
+				{Key: "1", Count: 2, P50NS: 10, P75NS: 30, P95NS: 30, P99NS: 30, MaxNS: 30},
+				{Key: "2", Count: 2, P50NS: 20, P75NS: 40, P95NS: 40, P99NS: 40, MaxNS: 40},
+			},
+			groupFunction: {
+				{Key: "Foundation:read", Count: 3, P50NS: 20, P75NS: 30, P95NS: 30, P99NS: 30, MaxNS: 30},
+				{Key: "gson:fromJson", Count: 1, P50NS: 40, P75NS: 40, P95NS: 40, P99NS: 40, MaxNS: 40},
+			},
+		},
+	}
+	if diff := testutil.Diff(s.report(), want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var b bytes.Buffer
+	w, err := newRecordWriter(formatCSV, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Write(record{
+		Object:     "1/2/abc",
+		Platform:   platform.Python,
+		ProjectID:  2,
+		ProfileID:  "abc",
+		Category:   occurrence.Regex,
+		Type:       occurrence.RegexType,
+		IssueTitle: "Regex on Main Thread",
+		Function:   "compile",
+		Package:    "re",
+		DurationNS: 100,
+		Rule:       "builtin:python:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "object,platform,project_id,profile_id,category,type,issue_title,function,package,duration_ns,sample_count,rule,fingerprint\n" +
+		"1/2/abc,python,2,abc,regex,2007,Regex on Main Thread,compile,re,100,0,builtin:python:0,\n"
+	if b.String() != want {
+		t.Fatalf("unexpected output:\n%s", b.String())
+	}
+}
+
+func TestCSVWriterWithoutRecords(t *testing.T) {
+	var b bytes.Buffer
+	w, err := newRecordWriter(formatCSV, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "object,platform,project_id,profile_id,category,type,issue_title,function,package,duration_ns,sample_count,rule,fingerprint\n"
+	if b.String() != want {
+		t.Fatalf("unexpected output:\n%s", b.String())
+	}
+}
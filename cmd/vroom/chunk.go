@@ -17,6 +17,7 @@ import (
 
 	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/metrics"
+	"github.com/getsentry/vroom/internal/occurrence"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/storageutil"
 )
@@ -80,7 +81,14 @@ func (env *environment) postChunk(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c.Normalize()
+	env.symbolicateChunk(ctx, c)
+
+	rules, err := env.loadStackTraceRules(ctx, c.GetOrganizationID(), c.GetProjectID(), c.GetOptions())
+	if err != nil && hub != nil {
+		hub.CaptureException(err)
+	}
+
+	c.Normalize(rules)
 
 	if hub != nil {
 		hub.Scope().SetContext("Profile metadata", map[string]interface{}{
@@ -157,6 +165,62 @@ func (env *environment) postChunk(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	s = sentry.StartSpan(ctx, "processing")
+	s.Description = "Find occurrences"
+	occurrences, err := occurrence.FindInChunk(c, callTrees)
+	s.Finish()
+	if err != nil {
+		// Report the error but don't fail chunk insertion
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	// Filter in-place occurrences without a type.
+	var i int
+	for _, o := range occurrences {
+		if o.Type != occurrence.NoneType {
+			occurrences[i] = o
+			i++
+		}
+	}
+	occurrences = occurrences[:i]
+	if env.suppressor != nil && len(occurrences) > 0 {
+		s = sentry.StartSpan(ctx, "processing")
+		s.Description = "Suppress occurrences"
+		occurrences, err = env.suppressor.Filter(ctx, occurrences)
+		s.Finish()
+		if err != nil {
+			// Report the error but don't fail chunk insertion
+			if hub != nil {
+				hub.CaptureException(err)
+			}
+		}
+	}
+	if len(occurrences) > 0 {
+		s = sentry.StartSpan(ctx, "processing")
+		s.Description = "Build Kafka message batch"
+		occurrenceMessages, err := occurrence.GenerateKafkaMessageBatch(occurrences)
+		s.Finish()
+		if err != nil {
+			// Report the error but don't fail chunk insertion
+			if hub != nil {
+				hub.CaptureException(err)
+			}
+		} else {
+			s = sentry.StartSpan(ctx, "processing")
+			s.Description = "Send occurrences to Kafka"
+			err = env.occurrencesWriter.WriteMessages(ctx, occurrenceMessages...)
+			s.Finish()
+			if err != nil {
+				// Report the error but don't fail chunk insertion
+				if hub != nil {
+					hub.CaptureException(err)
+				}
+			}
+		}
+	}
+
 	s = sentry.StartSpan(ctx, "processing")
 	s.Description = "Extract functions"
 	functions := metrics.ExtractFunctionsFromCallTrees(callTrees, minDepth)
@@ -211,6 +275,44 @@ type postProfileFromChunkIDsResponse struct {
 	Chunk chunk.SampleChunk `json:"chunk"`
 }
 
+// symbolicateChunk deobfuscates and symbolicates the frames of a chunk
+// before it's normalized. Errors are reported and don't stop the other
+// steps.
+func (env *environment) symbolicateChunk(ctx context.Context, c chunk.Chunk) {
+	hub := sentry.GetHubFromContext(ctx)
+
+	if t, debugID, ok := c.ObfuscatedAndroidTrace(); ok {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Deobfuscate chunk"
+		err := env.deobfuscateAndroidTrace(ctx, c.GetOrganizationID(), c.GetProjectID(), t, debugID)
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	if d, ok := c.SampleData(); ok && env.symbolicator != nil {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Symbolicate native frames"
+		leaves, callers := d.FramePositions()
+		err := env.symbolicateNativeFrames(ctx, c.GetOrganizationID(), c.GetProjectID(), c.GetDebugMeta(), d.Frames, leaves, callers, d.SplitCallerFrames, d.ReplaceFrames)
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	if d, ok := c.SampleData(); ok && env.sourceMaps != nil {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Symbolicate JavaScript frames"
+		err := env.symbolicateJavaScriptFrames(ctx, c.GetOrganizationID(), c.GetProjectID(), c.GetRelease(), c.GetDist(), c.GetPlatform(), d.Frames, d.CallerFrameIDs())
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+}
+
 // This is more of a GET method, but since we're receiving a list of chunk IDs as part of a
 // body request, we use a POST method instead (similarly to the flamegraph endpoint).
 func (env *environment) postProfileFromChunkIDs(w http.ResponseWriter, r *http.Request) {
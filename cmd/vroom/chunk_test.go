@@ -103,8 +103,9 @@ func TestPostAndReadSampleChunk(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			env := environment{
-				storage:         test.blobBucket,
-				profilingWriter: KafkaWriterMock{},
+				storage:           test.blobBucket,
+				profilingWriter:   KafkaWriterMock{},
+				occurrencesWriter: KafkaWriterMock{},
 				config: ServiceConfig{
 					ProfileChunksKafkaTopic: "snuba-profile-chunks",
 				},
@@ -261,8 +262,9 @@ func TestPostAndReadAndroidChunk(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			env := environment{
-				storage:         test.blobBucket,
-				profilingWriter: KafkaWriterMock{},
+				storage:           test.blobBucket,
+				profilingWriter:   KafkaWriterMock{},
+				occurrencesWriter: KafkaWriterMock{},
 				config: ServiceConfig{
 					ProfileChunksKafkaTopic: "snuba-profile-chunks",
 				},
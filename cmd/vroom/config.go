@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 type (
 	ServiceConfig struct {
 		Environment    string `env:"SENTRY_ENVIRONMENT" env-default:"development"`
@@ -27,5 +29,42 @@ type (
 		SnubaHost string `env:"SENTRY_SNUBA_HOST" env-default:"http://localhost:1218"`
 
 		BucketURL string `env:"SENTRY_BUCKET_PROFILES" env-default:"file://./test/gcs/sentry-profiles"`
+
+		// Occurrence detection rules are loaded from a local file or, if not
+		// set, from an object in the profiles bucket. They're reloaded on SIGHUP.
+		OccurrenceRulesPath   string `env:"SENTRY_OCCURRENCE_RULES_PATH"`
+		OccurrenceRulesObject string `env:"SENTRY_OCCURRENCE_RULES_OBJECT"`
+
+		// Occurrences sharing a fingerprint are sampled over a window, only
+		// sending a few of them, and the number of occurrences sent per project
+		// over the same window is capped if a budget is set. Disabled if the
+		// window is 0.
+		OccurrencesSuppressionWindow            time.Duration `env:"SENTRY_OCCURRENCES_SUPPRESSION_WINDOW"`
+		OccurrencesSuppressionMaxPerFingerprint int64         `env:"SENTRY_OCCURRENCES_SUPPRESSION_MAX_PER_FINGERPRINT" env-default:"1"`
+		OccurrencesSuppressionProjectBudget     int64         `env:"SENTRY_OCCURRENCES_SUPPRESSION_PROJECT_BUDGET"`
+
+		// Obfuscated Android traces are deobfuscated with the mapping file
+		// stored under proguard/<org>/<project>/<debug_id>, for setups where
+		// nothing upstream does it.
+		ProguardDeobfuscation bool `env:"SENTRY_PROGUARD_DEOBFUSCATION"`
+		ProguardCacheSize     int  `env:"SENTRY_PROGUARD_CACHE_SIZE" env-default:"32"`
+
+		// Unsymbolicated native frames are resolved, inlined functions
+		// included, from the ELF/DWARF file stored under
+		// debug-files/<org>/<project>/<debug_id> for their image.
+		NativeSymbolication          bool `env:"SENTRY_NATIVE_SYMBOLICATION"`
+		NativeSymbolicationCacheSize int  `env:"SENTRY_NATIVE_SYMBOLICATION_CACHE_SIZE" env-default:"8"`
+
+		// Minified JavaScript and Hermes frames are mapped back to their
+		// original file, line and function with the source map of their file
+		// for the profile's release and dist.
+		SourceMapSymbolication bool `env:"SENTRY_SOURCEMAP_SYMBOLICATION"`
+		SourceMapCacheSize     int  `env:"SENTRY_SOURCEMAP_CACHE_SIZE" env-default:"32"`
+
+		// Projects can store their own in-app and grouping rules under
+		// stacktrace-rules/<org>/<project>. They run during normalization,
+		// before any rules sent with a request.
+		StackTraceRules          bool `env:"SENTRY_STACKTRACE_RULES"`
+		StackTraceRulesCacheSize int  `env:"SENTRY_STACKTRACE_RULES_CACHE_SIZE" env-default:"256"`
 	}
 )
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+)
+
+// deobfuscateAndroidTrace deobfuscates an Android trace with the mapping
+// file uploaded for its debug ID. The trace is left as is if deobfuscation
+// is disabled or no mapping file was uploaded.
+func (e *environment) deobfuscateAndroidTrace(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	t *profile.Android,
+	debugID string,
+) error {
+	if e.proguard == nil {
+		return nil
+	}
+	m, err := e.proguard.Mapping(ctx, organizationID, projectID, debugID)
+	if err != nil {
+		if errors.Is(err, storageutil.ErrObjectNotFound) {
+			return nil
+		}
+		return err
+	}
+	t.Deobfuscate(m)
+	return nil
+}
@@ -26,7 +26,12 @@ import (
 
 	"github.com/getsentry/vroom/internal/httputil"
 	"github.com/getsentry/vroom/internal/logutil"
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/proguard"
+	"github.com/getsentry/vroom/internal/sourcemap"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/symbolication"
 )
 
 type environment struct {
@@ -34,6 +39,16 @@ type environment struct {
 
 	occurrencesWriter KafkaWriter
 	profilingWriter   KafkaWriter
+	// suppressor is nil if occurrences are not suppressed.
+	suppressor *occurrence.Suppressor
+	// proguard is nil if Android profiles are not deobfuscated.
+	proguard *proguard.Store
+	// symbolicator is nil if native frames are not symbolicated.
+	symbolicator *symbolication.Store
+	// sourceMaps is nil if JavaScript frames are not symbolicated.
+	sourceMaps *sourcemap.Store
+	// stackTraceRules is nil if the rules of projects are not loaded.
+	stackTraceRules *storageutil.ObjectCache[stacktracerules.Rules]
 
 	storage *blob.Bucket
 }
@@ -71,6 +86,39 @@ func newEnvironment() (*environment, error) {
 		WriteTimeout: 3 * time.Second,
 		Transport:    createKafkaRoundTripper(e.config),
 	}
+	if e.config.OccurrencesSuppressionWindow > 0 {
+		e.suppressor, err = occurrence.NewSuppressor(
+			occurrence.NewMemorySuppressionStore(),
+			occurrence.SuppressionOptions{
+				Window:            e.config.OccurrencesSuppressionWindow,
+				MaxPerFingerprint: e.config.OccurrencesSuppressionMaxPerFingerprint,
+				ProjectBudget:     e.config.OccurrencesSuppressionProjectBudget,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.config.ProguardDeobfuscation {
+		e.proguard = proguard.NewStore(e.storage, e.config.ProguardCacheSize)
+	}
+	if e.config.NativeSymbolication {
+		e.symbolicator = symbolication.NewStore(e.storage, e.config.NativeSymbolicationCacheSize)
+	}
+	if e.config.SourceMapSymbolication {
+		e.sourceMaps = sourcemap.NewStore(e.storage, e.config.SourceMapCacheSize)
+	}
+	if e.config.StackTraceRules {
+		// rules are only kept for a minute so changes are picked up quickly
+		e.stackTraceRules = storageutil.NewObjectCache(
+			e.storage,
+			storageutil.ObjectCacheOptions{
+				Size: e.config.StackTraceRulesCacheSize,
+				TTL:  time.Minute,
+			},
+			stacktracerules.Read,
+		)
+	}
 	e.profilingWriter = &kafka.Writer{
 		Addr:         kafka.TCP(e.config.ProfilingKafkaBrokers...),
 		Async:        true,
@@ -137,6 +185,26 @@ func (e *environment) newRouter() (*httprouter.Router, error) {
 			"/organizations/:organization_id/metrics",
 			e.postMetrics,
 		},
+		{
+			http.MethodPost,
+			"/organizations/:organization_id/metrics/timeseries",
+			e.postMetricsTimeSeries,
+		},
+		{
+			http.MethodPost,
+			"/organizations/:organization_id/regressed/detect",
+			e.postRegressedDetect,
+		},
+		{
+			http.MethodPost,
+			"/organizations/:organization_id/packages",
+			e.postPackages,
+		},
+		{
+			http.MethodPost,
+			"/organizations/:organization_id/occurrences/dry_run",
+			e.postOccurrencesDryRun,
+		},
 		{http.MethodGet, "/health", e.getHealth},
 		{http.MethodPost, "/chunk", e.postChunk},
 		{http.MethodPost, "/profile", e.postProfile},
@@ -189,6 +257,13 @@ func main() {
 		log.Fatal("can't initialize sentry", err)
 	}
 
+	err = env.loadOccurrenceRules(context.Background())
+	if err != nil {
+		sentry.CaptureException(err)
+		log.Fatal("error loading occurrence rules", err)
+	}
+	go env.reloadOccurrenceRulesOnSignal()
+
 	router, err := env.newRouter()
 	if err != nil {
 		sentry.CaptureException(err)
@@ -21,6 +21,22 @@ type (
 	postMetricsResponse struct {
 		FunctionsMetrics []utils.FunctionMetrics `json:"functions_metrics"`
 	}
+
+	postMetricsTimeSeriesRequestBody struct {
+		Transaction  []utils.TransactionProfileCandidate `json:"transaction"`
+		Continuous   []utils.ContinuousProfileCandidate  `json:"continuous"`
+		Fingerprints []uint32                            `json:"fingerprints"`
+		// Interval is the width of a bucket, in seconds.
+		Interval uint64 `json:"interval"`
+		// Start and End are optional, in seconds. When set, empty buckets
+		// in the range are returned as well.
+		Start uint64 `json:"start"`
+		End   uint64 `json:"end"`
+	}
+
+	postMetricsTimeSeriesResponse struct {
+		TimeSeries []metrics.FunctionTimeSeries `json:"time_series"`
+	}
 )
 
 func (env *environment) postMetrics(w http.ResponseWriter, r *http.Request) {
@@ -88,3 +104,80 @@ func (env *environment) postMetrics(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(b)
 }
+
+func (env *environment) postMetricsTimeSeries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	hub := sentry.GetHubFromContext(ctx)
+	ps := httprouter.ParamsFromContext(ctx)
+	rawOrganizationID := ps.ByName("organization_id")
+	organizationID, err := strconv.ParseUint(rawOrganizationID, 10, 64)
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hub.Scope().SetTag("organization_id", rawOrganizationID)
+
+	var body postMetricsTimeSeriesRequestBody
+	s := sentry.StartSpan(ctx, "processing")
+	s.Description = "Decoding data"
+	err = json.NewDecoder(r.Body).Decode(&body)
+	s.Finish()
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(body.Fingerprints) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = metrics.CheckTimeSeriesRange(body.Start, body.End, body.Interval)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s = sentry.StartSpan(ctx, "processing")
+	ta := metrics.NewTimeSeriesAggregator(body.Fingerprints, body.Interval, 5, minDepth)
+	err = ta.GetTimeSeriesFromCandidates(
+		ctx,
+		env.storage,
+		organizationID,
+		body.Transaction,
+		body.Continuous,
+		readJobs,
+	)
+	s.Finish()
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s = sentry.StartSpan(ctx, "json.marshal")
+	defer s.Finish()
+	b, err := json.Marshal(postMetricsTimeSeriesResponse{
+		TimeSeries: ta.ToTimeSeries(body.Start, body.End),
+	})
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
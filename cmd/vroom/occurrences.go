@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+)
+
+type (
+	// postOccurrencesDryRunRequest accepts either a raw profile or chunk,
+	// as sent to /profile and /chunk, or a reference to a stored one.
+	postOccurrencesDryRunRequest struct {
+		Profile json.RawMessage `json:"profile"`
+		Chunk   json.RawMessage `json:"chunk"`
+
+		ProjectID  uint64 `json:"project_id"`
+		ProfileID  string `json:"profile_id"`
+		ProfilerID string `json:"profiler_id"`
+		ChunkID    string `json:"chunk_id"`
+	}
+
+	dryRunOccurrence struct {
+		*occurrence.Occurrence
+
+		Rule string `json:"rule"`
+		// WouldSend is false for occurrences without a type, they are
+		// dropped before being sent.
+		WouldSend bool `json:"would_send"`
+	}
+
+	postOccurrencesDryRunResponse struct {
+		Occurrences []dryRunOccurrence `json:"occurrences"`
+		// Warnings holds the errors met loading stack trace rules, the
+		// valid ones being applied anyway.
+		Warnings []string `json:"warnings,omitempty"`
+	}
+)
+
+var (
+	errNoProfileOrChunk     = errors.New("a profile or a chunk is required")
+	errOrganizationMismatch = errors.New("the organization doesn't match the one of the request")
+)
+
+// postOccurrencesDryRun runs occurrence detection on a profile or a chunk
+// and returns what would be sent, without storing or sending anything.
+func (env *environment) postOccurrencesDryRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	hub := sentry.GetHubFromContext(ctx)
+	ps := httprouter.ParamsFromContext(ctx)
+	rawOrganizationID := ps.ByName("organization_id")
+	organizationID, err := strconv.ParseUint(rawOrganizationID, 10, 64)
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hub.Scope().SetTag("organization_id", rawOrganizationID)
+
+	var body postOccurrencesDryRunRequest
+	s := sentry.StartSpan(ctx, "processing")
+	s.Description = "Decoding data"
+	err = json.NewDecoder(r.Body).Decode(&body)
+	s.Finish()
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s = sentry.StartSpan(ctx, "processing")
+	s.Description = "Find occurrences"
+	occurrences, warnings, err := env.findOccurrences(ctx, organizationID, body)
+	s.Finish()
+	if err != nil {
+		switch {
+		case errors.Is(err, storageutil.ErrObjectNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, errNoProfileOrChunk), errors.Is(err, errOrganizationMismatch),
+			errors.As(err, new(*json.SyntaxError)),
+			errors.As(err, new(*json.UnmarshalTypeError)):
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			if hub != nil {
+				hub.CaptureException(err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := postOccurrencesDryRunResponse{
+		Occurrences: make([]dryRunOccurrence, 0, len(occurrences)),
+		Warnings:    warnings,
+	}
+	for _, o := range occurrences {
+		response.Occurrences = append(response.Occurrences, dryRunOccurrence{
+			Occurrence: o,
+			Rule:       o.Rule(),
+			WouldSend:  o.Type != occurrence.NoneType,
+		})
+	}
+
+	s = sentry.StartSpan(ctx, "json.marshal")
+	defer s.Finish()
+	b, err := json.Marshal(response)
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
+// findOccurrences processes a raw profile or chunk like it would be on
+// ingestion, or reads a stored one, and looks for occurrences. Errors
+// loading stack trace rules are returned as warnings.
+func (env *environment) findOccurrences(
+	ctx context.Context,
+	organizationID uint64,
+	body postOccurrencesDryRunRequest,
+) ([]*occurrence.Occurrence, []string, error) {
+	switch {
+	case isSet(body.Profile):
+		var p profile.Profile
+		if err := json.Unmarshal(body.Profile, &p); err != nil {
+			return nil, nil, err
+		}
+		if p.OrganizationID() != organizationID {
+			return nil, nil, errOrganizationMismatch
+		}
+		env.symbolicateProfile(ctx, &p)
+		rules, err := env.loadStackTraceRules(ctx, p.OrganizationID(), p.ProjectID(), p.GetOptions())
+		p.Normalize(rules)
+		occurrences, findErr := findProfileOccurrences(p)
+		return occurrences, warnings(err), findErr
+	case isSet(body.Chunk):
+		var cp chunkPlatform
+		if err := json.Unmarshal(body.Chunk, &cp); err != nil {
+			return nil, nil, err
+		}
+		var c chunk.Chunk
+		switch cp.Platform {
+		case platform.Android:
+			c = chunk.New(new(chunk.AndroidChunk))
+		default:
+			c = chunk.New(new(chunk.SampleChunk))
+		}
+		if err := json.Unmarshal(body.Chunk, &c); err != nil {
+			return nil, nil, err
+		}
+		if c.GetOrganizationID() != organizationID {
+			return nil, nil, errOrganizationMismatch
+		}
+		env.symbolicateChunk(ctx, c)
+		rules, err := env.loadStackTraceRules(ctx, c.GetOrganizationID(), c.GetProjectID(), c.GetOptions())
+		c.Normalize(rules)
+		occurrences, findErr := findChunkOccurrences(c)
+		return occurrences, warnings(err), findErr
+	case body.ProjectID != 0 && body.ProfileID != "":
+		var p profile.Profile
+		err := storageutil.UnmarshalCompressed(
+			ctx,
+			env.storage,
+			profile.StoragePath(organizationID, body.ProjectID, body.ProfileID),
+			&p,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		occurrences, err := findProfileOccurrences(p)
+		return occurrences, nil, err
+	case body.ProjectID != 0 && body.ProfilerID != "" && body.ChunkID != "":
+		var c chunk.Chunk
+		err := storageutil.UnmarshalCompressed(
+			ctx,
+			env.storage,
+			chunk.StoragePath(organizationID, body.ProjectID, body.ProfilerID, body.ChunkID),
+			&c,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		occurrences, err := findChunkOccurrences(c)
+		return occurrences, nil, err
+	}
+	return nil, nil, errNoProfileOrChunk
+}
+
+// warnings splits joined errors into messages.
+func warnings(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var messages []string
+		for _, e := range joined.Unwrap() {
+			messages = append(messages, e.Error())
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
+func isSet(raw json.RawMessage) bool {
+	return len(raw) > 0 && !bytes.Equal(raw, []byte("null"))
+}
+
+func findProfileOccurrences(p profile.Profile) ([]*occurrence.Occurrence, error) {
+	callTrees, err := p.CallTrees()
+	if err != nil {
+		return nil, err
+	}
+	return occurrence.Find(p, callTrees), nil
+}
+
+func findChunkOccurrences(c chunk.Chunk) ([]*occurrence.Occurrence, error) {
+	callTrees, err := c.CallTrees(nil)
+	if err != nil {
+		return nil, err
+	}
+	return occurrence.FindInChunk(c, callTrees)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/sample"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestPostOccurrencesDryRun(t *testing.T) {
+	// hangs are only detected and sent if enabled by the rules
+	rs, err := occurrence.ParseRules([]byte(`
+hang_threshold: 2s
+categories:
+  hang:
+    issue_title: Main Thread Hang
+    type: 2013
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrence.SetRuleSet(rs)
+	defer occurrence.SetRuleSet(nil)
+
+	c := chunk.SampleChunk{
+		ID:             "chunk",
+		ProfilerID:     "profiler",
+		Platform:       platform.Cocoa,
+		OrganizationID: 1,
+		ProjectID:      1,
+		Version:        "2",
+		Profile: chunk.SampleData{
+			Frames: []frame.Frame{
+				{
+					Function: "main",
+					InApp:    &testutil.True,
+					Platform: platform.Cocoa,
+				},
+				{
+					Function: "loadData",
+					InApp:    &testutil.True,
+					Platform: platform.Cocoa,
+				},
+			},
+			Stacks: [][]int{
+				{1, 0},
+			},
+			Samples: []chunk.Sample{
+				{StackID: 0, ThreadID: "1", Timestamp: 1000.0},
+				{StackID: 0, ThreadID: "1", Timestamp: 1001.0},
+				{StackID: 0, ThreadID: "1", Timestamp: 1002.0},
+				{StackID: 0, ThreadID: "1", Timestamp: 1003.0},
+			},
+			ThreadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "main"},
+			},
+		},
+	}
+	rawChunk, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(postOccurrencesDryRunRequest{Chunk: rawChunk})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No writer is set, sending anything would panic.
+	env := environment{storage: fileBlobBucket}
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(body))
+	ctx := sentry.SetHubOnContext(req.Context(), sentry.CurrentHub().Clone())
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, httprouter.Params{
+		{Key: "organization_id", Value: "1"},
+	})
+	w := httptest.NewRecorder()
+	env.postOccurrencesDryRun(w, req.WithContext(ctx))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status code 200. Found: %d", resp.StatusCode)
+	}
+	var got struct {
+		Occurrences []struct {
+			Subtitle  string `json:"subtitle"`
+			Rule      string `json:"rule"`
+			WouldSend bool   `json:"would_send"`
+		} `json:"occurrences"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Occurrences) != 1 {
+		t.Fatalf("expected 1 occurrence, got %d", len(got.Occurrences))
+	}
+	o := got.Occurrences[0]
+	if o.Subtitle != "loadData" || o.Rule != "hang" || !o.WouldSend {
+		t.Fatalf("unexpected occurrence: %+v", o)
+	}
+}
+
+func TestPostOccurrencesDryRunWithoutInput(t *testing.T) {
+	env := environment{storage: fileBlobBucket}
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{}`))
+	ctx := sentry.SetHubOnContext(req.Context(), sentry.CurrentHub().Clone())
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, httprouter.Params{
+		{Key: "organization_id", Value: "1"},
+	})
+	w := httptest.NewRecorder()
+	env.postOccurrencesDryRun(w, req.WithContext(ctx))
+	if w.Code != 400 {
+		t.Fatalf("Expected status code 400. Found: %d", w.Code)
+	}
+}
+
+func TestPostOccurrencesDryRunRequestErrors(t *testing.T) {
+	c := chunk.SampleChunk{
+		ID:             "chunk",
+		ProfilerID:     "profiler",
+		Platform:       platform.Python,
+		OrganizationID: 1,
+		ProjectID:      1,
+		Version:        "2",
+		Profile: chunk.SampleData{
+			Frames: []frame.Frame{
+				{Function: "main", Platform: platform.Python},
+			},
+			Stacks: [][]int{{0}},
+			Samples: []chunk.Sample{
+				{StackID: 0, ThreadID: "1", Timestamp: 1000.0},
+				{StackID: 0, ThreadID: "1", Timestamp: 1001.0},
+			},
+		},
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// options are never marshaled, add them to the raw chunk
+	var rawChunk map[string]interface{}
+	if err := json.Unmarshal(b, &rawChunk); err != nil {
+		t.Fatal(err)
+	}
+	rawChunk["options"] = map[string]string{"stacktrace_rules": "+app"}
+	b, err = json.Marshal(rawChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(postOccurrencesDryRunRequest{Chunk: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		organizationID string
+		statusCode     int
+		warnings       int
+	}{
+		{
+			name:           "invalid stack trace rules",
+			organizationID: "1",
+			statusCode:     200,
+			warnings:       1,
+		},
+		{
+			name:           "other organization",
+			organizationID: "2",
+			statusCode:     400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := environment{storage: fileBlobBucket}
+			req := httptest.NewRequest("POST", "/", bytes.NewBuffer(body))
+			ctx := sentry.SetHubOnContext(req.Context(), sentry.CurrentHub().Clone())
+			ctx = context.WithValue(ctx, httprouter.ParamsKey, httprouter.Params{
+				{Key: "organization_id", Value: test.organizationID},
+			})
+			w := httptest.NewRecorder()
+			env.postOccurrencesDryRun(w, req.WithContext(ctx))
+			if w.Code != test.statusCode {
+				t.Fatalf("Expected status code %d. Found: %d", test.statusCode, w.Code)
+			}
+			if test.statusCode != 200 {
+				return
+			}
+			var got postOccurrencesDryRunResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Warnings) != test.warnings {
+				t.Fatalf("expected %d warnings, got %v", test.warnings, got.Warnings)
+			}
+		})
+	}
+}
@@ -15,8 +15,10 @@ import (
 	"gocloud.dev/gcerrors"
 	"google.golang.org/api/googleapi"
 
+	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/metrics"
 	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/profile"
 	"github.com/getsentry/vroom/internal/storageutil"
 )
@@ -67,9 +69,16 @@ func (env *environment) postProfile(w http.ResponseWriter, r *http.Request) {
 		"platform": string(profilePlatform),
 	})
 
+	env.symbolicateProfile(ctx, &p)
+
+	rules, err := env.loadStackTraceRules(ctx, orgID, p.ProjectID(), p.GetOptions())
+	if err != nil {
+		hub.CaptureException(err)
+	}
+
 	s = sentry.StartSpan(ctx, "processing")
 	s.Description = "Normalize profile"
-	p.Normalize()
+	p.Normalize(rules)
 	s.Finish()
 
 	if !p.IsSampled() {
@@ -134,6 +143,16 @@ func (env *environment) postProfile(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			occurrences = occurrences[:i]
+			if env.suppressor != nil {
+				s = sentry.StartSpan(ctx, "processing")
+				s.Description = "Suppress occurrences"
+				occurrences, err = env.suppressor.Filter(ctx, occurrences)
+				s.Finish()
+				if err != nil {
+					// Report the error but don't fail profile insertion
+					hub.CaptureException(err)
+				}
+			}
 			s = sentry.StartSpan(ctx, "processing")
 			s.Description = "Build Kafka message batch"
 			occurrenceMessages, err := occurrence.GenerateKafkaMessageBatch(occurrences)
@@ -218,6 +237,56 @@ func (env *environment) postProfile(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// symbolicateProfile deobfuscates and symbolicates the frames of a profile
+// before it's normalized. Errors are reported and don't stop the other
+// steps.
+func (env *environment) symbolicateProfile(ctx context.Context, p *profile.Profile) {
+	hub := sentry.GetHubFromContext(ctx)
+	orgID := p.OrganizationID()
+
+	if t, debugID, ok := p.ObfuscatedAndroidTrace(); ok {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Deobfuscate profile"
+		err := env.deobfuscateAndroidTrace(ctx, orgID, p.ProjectID(), t, debugID)
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	if t, ok := p.SampleTrace(); ok && env.symbolicator != nil {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Symbolicate native frames"
+		leaves, callers := t.FramePositions()
+		err := env.symbolicateNativeFrames(ctx, orgID, p.ProjectID(), p.DebugMeta(), t.Frames, leaves, callers, t.SplitCallerFrames, t.ReplaceFrames)
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	if env.sourceMaps != nil {
+		s := sentry.StartSpan(ctx, "processing")
+		s.Description = "Symbolicate JavaScript frames"
+		err := p.UpdateJavaScriptFrames(func(frames []frame.Frame, callers []int) error {
+			return env.symbolicateJavaScriptFrames(
+				ctx,
+				orgID,
+				p.ProjectID(),
+				p.Release(),
+				p.TransactionMetadata().Dist,
+				platform.JavaScript,
+				frames,
+				callers,
+			)
+		})
+		s.Finish()
+		if err != nil && hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+}
+
 func (env *environment) getRawProfile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	hub := sentry.GetHubFromContext(ctx)
@@ -4,11 +4,77 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/getsentry/vroom/internal/metrics"
 	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/regression"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+type (
+	postRegressedDetectRequestBody struct {
+		// Series are pre-computed time series, one per function.
+		Series []regression.Series `json:"series"`
+
+		// Alternatively, time series can be computed from candidates
+		// for the given fingerprints, bucketed by interval (in seconds).
+		Transaction  []utils.TransactionProfileCandidate `json:"transaction"`
+		Continuous   []utils.ContinuousProfileCandidate  `json:"continuous"`
+		Fingerprints []uint32                            `json:"fingerprints"`
+		Interval     uint64                              `json:"interval"`
+	}
+
+	postRegressedDetectResponse struct {
+		Regressed []occurrence.RegressedFunction `json:"regressed"`
+	}
+
+	projectCandidates struct {
+		transaction []utils.TransactionProfileCandidate
+		continuous  []utils.ContinuousProfileCandidate
+	}
 )
 
+// candidatesByProject groups candidates by project.
+func candidatesByProject(
+	transaction []utils.TransactionProfileCandidate,
+	continuous []utils.ContinuousProfileCandidate,
+) map[uint64]*projectCandidates {
+	candidates := make(map[uint64]*projectCandidates)
+	get := func(projectID uint64) *projectCandidates {
+		c, exists := candidates[projectID]
+		if !exists {
+			c = &projectCandidates{}
+			candidates[projectID] = c
+		}
+		return c
+	}
+	for _, candidate := range transaction {
+		c := get(candidate.ProjectID)
+		c.transaction = append(c.transaction, candidate)
+	}
+	for _, candidate := range continuous {
+		c := get(candidate.ProjectID)
+		c.continuous = append(c.continuous, candidate)
+	}
+	return candidates
+}
+
+func sortedProjectIDs(candidates map[uint64]*projectCandidates) []uint64 {
+	projectIDs := make([]uint64, 0, len(candidates))
+	for projectID := range candidates {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Slice(projectIDs, func(i, j int) bool {
+		return projectIDs[i] < projectIDs[j]
+	})
+	return projectIDs
+}
+
 func (env *environment) postRegressed(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	hub := sentry.GetHubFromContext(ctx)
@@ -81,3 +147,101 @@ func decodeRegressedFunctionPayload(ctx context.Context, r *http.Request) ([]occ
 	err := json.NewDecoder(r.Body).Decode(&regressedFunctions)
 	return regressedFunctions, err
 }
+
+func (env *environment) postRegressedDetect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	hub := sentry.GetHubFromContext(ctx)
+	ps := httprouter.ParamsFromContext(ctx)
+	rawOrganizationID := ps.ByName("organization_id")
+	organizationID, err := strconv.ParseUint(rawOrganizationID, 10, 64)
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hub.Scope().SetTag("organization_id", rawOrganizationID)
+
+	var body postRegressedDetectRequestBody
+	s := sentry.StartSpan(ctx, "processing")
+	s.Description = "Decoding data"
+	err = json.NewDecoder(r.Body).Decode(&body)
+	s.Finish()
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	series := body.Series
+	if len(body.Transaction) > 0 || len(body.Continuous) > 0 {
+		if len(body.Fingerprints) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		err = metrics.CheckTimeSeriesRange(0, 0, body.Interval)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// aggregate each project separately, for a series to only hold the
+		// functions of one project
+		candidates := candidatesByProject(body.Transaction, body.Continuous)
+		s = sentry.StartSpan(ctx, "processing")
+		s.Description = "Computing time series"
+		for _, projectID := range sortedProjectIDs(candidates) {
+			ta := metrics.NewTimeSeriesAggregator(body.Fingerprints, body.Interval, 5, minDepth)
+			err = ta.GetTimeSeriesFromCandidates(
+				ctx,
+				env.storage,
+				organizationID,
+				candidates[projectID].transaction,
+				candidates[projectID].continuous,
+				readJobs,
+			)
+			if err != nil {
+				break
+			}
+			series = append(series, regression.SeriesFromTimeSeries(projectID, ta.ToTimeSeries(0, 0))...)
+		}
+		s.Finish()
+		if err != nil {
+			if hub != nil {
+				hub.CaptureException(err)
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s = sentry.StartSpan(ctx, "processing")
+	s.Description = "Detecting breakpoints"
+	regressed := make([]occurrence.RegressedFunction, 0, len(series))
+	for _, ts := range series {
+		if rf, ok := regression.Detect(organizationID, ts, regression.DefaultOptions); ok {
+			regressed = append(regressed, rf)
+		}
+	}
+	s.Finish()
+
+	s = sentry.StartSpan(ctx, "json.marshal")
+	defer s.Finish()
+	b, err := json.Marshal(postRegressedDetectResponse{
+		Regressed: regressed,
+	})
+	if err != nil {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
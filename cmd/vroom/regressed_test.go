@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/getsentry/vroom/internal/testutil"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+func TestCandidatesByProject(t *testing.T) {
+	candidates := candidatesByProject(
+		[]utils.TransactionProfileCandidate{
+			{ProjectID: 2, ProfileID: "a"},
+			{ProjectID: 1, ProfileID: "b"},
+			{ProjectID: 2, ProfileID: "c"},
+		},
+		[]utils.ContinuousProfileCandidate{
+			{ProjectID: 3, ProfilerID: "d", ChunkID: "e"},
+		},
+	)
+
+	if diff := testutil.Diff(sortedProjectIDs(candidates), []uint64{1, 2, 3}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	want := projectCandidates{
+		transaction: []utils.TransactionProfileCandidate{
+			{ProjectID: 2, ProfileID: "a"},
+			{ProjectID: 2, ProfileID: "c"},
+		},
+	}
+	if diff := testutil.Diff(*candidates[2], want, cmp.AllowUnexported(projectCandidates{})); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
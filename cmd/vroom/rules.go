@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/getsentry/vroom/internal/occurrence"
+)
+
+// loadOccurrenceRules loads the occurrence detection rules from the
+// configured source. Built-in rules are used if no source is configured.
+func (e *environment) loadOccurrenceRules(ctx context.Context) error {
+	var b []byte
+	var err error
+	switch {
+	case e.config.OccurrenceRulesPath != "":
+		b, err = os.ReadFile(e.config.OccurrenceRulesPath)
+	case e.config.OccurrenceRulesObject != "":
+		b, err = e.storage.ReadAll(ctx, e.config.OccurrenceRulesObject)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rs, err := occurrence.ParseRules(b)
+	if err != nil {
+		return err
+	}
+	occurrence.SetRuleSet(rs)
+	return nil
+}
+
+// reloadOccurrenceRulesOnSignal reloads the rules every time the process
+// receives a SIGHUP. Invalid rules are reported and the previous ones kept.
+func (e *environment) reloadOccurrenceRulesOnSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if err := e.loadOccurrenceRules(context.Background()); err != nil {
+			sentry.CaptureException(err)
+			slog.Error("error reloading occurrence rules", "err", err)
+			continue
+		}
+		slog.Info("occurrence rules reloaded")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/getsentry/vroom/internal/stacktracerules"
+	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+// loadStackTraceRules returns the stack trace rules of a project followed by the
+// ones sent in the options of the request, so the latter win. Valid rules
+// are returned even if some couldn't be loaded or parsed.
+func (e *environment) loadStackTraceRules(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	options utils.Options,
+) (stacktracerules.Rules, error) {
+	var rules stacktracerules.Rules
+	var errs []error
+	if e.stackTraceRules != nil {
+		projectRules, err := e.stackTraceRules.Get(ctx, stacktracerules.StoragePath(organizationID, projectID))
+		if err != nil && !errors.Is(err, storageutil.ErrObjectNotFound) {
+			errs = append(errs, err)
+		}
+		rules = append(rules, projectRules...)
+	}
+	if options.StackTraceRules != "" {
+		requestRules, err := stacktracerules.Parse(options.StackTraceRules)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("request options: %w", err))
+		}
+		rules = append(rules, requestRules...)
+	}
+	return rules, errors.Join(errs...)
+}
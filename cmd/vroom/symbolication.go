@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/getsentry/vroom/internal/debugmeta"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+)
+
+// symbolicateNativeFrames symbolicates the native frames with the debug
+// files uploaded for their image, leaves and callers telling which ones are
+// at the top of the stacks and which are below it. If any was symbolicated,
+// split is called with the copies of the frames symbolicated differently
+// below the top, then replace with the new frames. Frames are left as is if
+// symbolication is disabled.
+func (e *environment) symbolicateNativeFrames(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	dm debugmeta.DebugMeta,
+	frames []frame.Frame,
+	leaves, callers []bool,
+	split func(copies map[int]int),
+	replace func(frames []frame.Frame, indexes [][]int),
+) error {
+	if e.symbolicator == nil {
+		return nil
+	}
+	symbolicated, indexes, copies, err := e.symbolicator.Symbolicate(ctx, organizationID, projectID, dm, frames, leaves, callers)
+	if symbolicated != nil {
+		split(copies)
+		replace(symbolicated, indexes)
+	}
+	return err
+}
+
+// symbolicateJavaScriptFrames applies the source maps uploaded for a release
+// to the JavaScript frames, callers holding the ID of a frame calling each
+// frame or -1. Frames are left as is if symbolication is disabled.
+func (e *environment) symbolicateJavaScriptFrames(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	release, dist string,
+	defaultPlatform platform.Platform,
+	frames []frame.Frame,
+	callers []int,
+) error {
+	if e.sourceMaps == nil {
+		return nil
+	}
+	return e.sourceMaps.Symbolicate(ctx, organizationID, projectID, release, dist, defaultPlatform, frames, callers)
+}
@@ -0,0 +1,198 @@
+package attribution
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/debugmeta"
+	"github.com/getsentry/vroom/internal/metrics"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/utils"
+	"gocloud.dev/blob"
+)
+
+type (
+	PackageMetrics struct {
+		Package string `json:"package"`
+		// Image is the code file of the debug image containing the
+		// frames, only set for native frames.
+		Image         string `json:"image,omitempty"`
+		IsApplication bool   `json:"is_application"`
+		// SelfTimeNS is the time spent in functions of the package itself.
+		SelfTimeNS uint64 `json:"self_time_ns"`
+		// InclusiveTimeNS is the time spent in functions of the package,
+		// including the functions they called.
+		InclusiveTimeNS uint64 `json:"inclusive_time_ns"`
+		// ApplicationSelfTimeNS is the part of the self time spent in
+		// application frames.
+		ApplicationSelfTimeNS uint64 `json:"application_self_time_ns"`
+		// Profiles is the number of profiles or chunks the package was found in.
+		Profiles uint64 `json:"profiles"`
+	}
+
+	Aggregator struct {
+		Packages map[string]*PackageMetrics
+
+		// TotalTimeNS is the sum of the duration of all the root frames.
+		TotalTimeNS           uint64
+		ApplicationSelfTimeNS uint64
+		SystemSelfTimeNS      uint64
+	}
+
+	Output struct {
+		Packages              []PackageMetrics `json:"packages"`
+		TotalTimeNS           uint64           `json:"total_time_ns"`
+		ApplicationSelfTimeNS uint64           `json:"application_self_time_ns"`
+		SystemSelfTimeNS      uint64           `json:"system_self_time_ns"`
+	}
+)
+
+func NewAggregator() Aggregator {
+	return Aggregator{
+		Packages: make(map[string]*PackageMetrics),
+	}
+}
+
+// AddCallTrees rolls the call trees of a profile or a chunk up by package.
+// For native frames, the package is the debug image containing the
+// instruction address, when it can be found.
+func AddCallTrees[T comparable](
+	a *Aggregator,
+	callTrees map[T][]*nodetree.Node,
+	dm debugmeta.DebugMeta,
+) {
+	seen := make(map[string]struct{})
+	for _, callTreesForThread := range callTrees {
+		for _, root := range callTreesForThread {
+			a.TotalTimeNS += root.DurationNS
+			a.addNode(root, make(map[string]int), seen, dm)
+		}
+	}
+	for key := range seen {
+		a.Packages[key].Profiles++
+	}
+}
+
+func (a *Aggregator) addNode(
+	n *nodetree.Node,
+	ancestors map[string]int,
+	seen map[string]struct{},
+	dm debugmeta.DebugMeta,
+) {
+	pkg, image := packageForNode(n, dm)
+	key := pkg + "\x00" + image
+	pm, ok := a.Packages[key]
+	if !ok {
+		pm = &PackageMetrics{
+			Package: pkg,
+			Image:   image,
+		}
+		a.Packages[key] = pm
+	}
+	seen[key] = struct{}{}
+
+	// only count the inclusive time for the outermost frame of a package
+	// to not count recursive calls more than once
+	if ancestors[key] == 0 {
+		pm.InclusiveTimeNS += n.DurationNS
+	}
+
+	var childrenDurationNS uint64
+	for _, c := range n.Children {
+		childrenDurationNS += c.DurationNS
+	}
+	if n.DurationNS > childrenDurationNS {
+		selfTimeNS := n.DurationNS - childrenDurationNS
+		pm.SelfTimeNS += selfTimeNS
+		if n.IsApplication {
+			pm.ApplicationSelfTimeNS += selfTimeNS
+			a.ApplicationSelfTimeNS += selfTimeNS
+		} else {
+			a.SystemSelfTimeNS += selfTimeNS
+		}
+	}
+
+	ancestors[key]++
+	for _, c := range n.Children {
+		a.addNode(c, ancestors, seen, dm)
+	}
+	ancestors[key]--
+}
+
+func packageForNode(n *nodetree.Node, dm debugmeta.DebugMeta) (string, string) {
+	if n.Frame.InstructionAddr != "" {
+		if addr, err := debugmeta.ParseAddress(n.Frame.InstructionAddr); err == nil {
+			if image, ok := dm.ImageContainingAddress(addr); ok && image.CodeFile != "" {
+				return imageName(image.CodeFile), image.CodeFile
+			}
+		}
+	}
+	return n.Frame.ModuleOrPackage(), ""
+}
+
+// imageName returns the file name of a code file, for both
+// unix and windows paths.
+func imageName(codeFile string) string {
+	if i := strings.LastIndexAny(codeFile, "/\\"); i != -1 {
+		return codeFile[i+1:]
+	}
+	return codeFile
+}
+
+// ToOutput returns the packages, sorted by self time.
+// A package is classified as in-app when most of its self time was
+// spent in application frames.
+func (a *Aggregator) ToOutput() Output {
+	packages := make([]PackageMetrics, 0, len(a.Packages))
+	for _, pm := range a.Packages {
+		pm.IsApplication = pm.ApplicationSelfTimeNS*2 > pm.SelfTimeNS
+		packages = append(packages, *pm)
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].SelfTimeNS != packages[j].SelfTimeNS {
+			return packages[i].SelfTimeNS > packages[j].SelfTimeNS
+		}
+		if packages[i].InclusiveTimeNS != packages[j].InclusiveTimeNS {
+			return packages[i].InclusiveTimeNS > packages[j].InclusiveTimeNS
+		}
+		return packages[i].Package < packages[j].Package
+	})
+	return Output{
+		Packages:              packages,
+		TotalTimeNS:           a.TotalTimeNS,
+		ApplicationSelfTimeNS: a.ApplicationSelfTimeNS,
+		SystemSelfTimeNS:      a.SystemSelfTimeNS,
+	}
+}
+
+func (a *Aggregator) GetPackagesFromCandidates(
+	ctx context.Context,
+	storage *blob.Bucket,
+	organizationID uint64,
+	transactionProfileCandidates []utils.TransactionProfileCandidate,
+	continuousProfileCandidates []utils.ContinuousProfileCandidate,
+	jobs chan storageutil.ReadJob,
+) (Output, error) {
+	err := metrics.ReadCandidates(
+		ctx,
+		storage,
+		organizationID,
+		transactionProfileCandidates,
+		continuousProfileCandidates,
+		jobs,
+		func(result profile.ReadJobResult, profileCallTrees map[uint64][]*nodetree.Node) {
+			AddCallTrees(a, profileCallTrees, result.Profile.DebugMeta())
+		},
+		func(result chunk.ReadJobResult, chunkCallTrees map[string][]*nodetree.Node) {
+			AddCallTrees(a, chunkCallTrees, result.Chunk.GetDebugMeta())
+		},
+	)
+	if err != nil {
+		return Output{}, err
+	}
+	return a.ToOutput(), nil
+}
@@ -0,0 +1,111 @@
+package attribution
+
+import (
+	"testing"
+
+	"github.com/getsentry/vroom/internal/debugmeta"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func node(f frame.Frame, start, end uint64, children ...*nodetree.Node) *nodetree.Node {
+	n := nodetree.NodeFromFrame(f, start, end, 0)
+	n.Children = children
+	return n
+}
+
+func TestAddCallTrees(t *testing.T) {
+	tests := []struct {
+		name      string
+		callTrees map[uint64][]*nodetree.Node
+		debugMeta debugmeta.DebugMeta
+		want      Output
+	}{
+		{
+			name: "group by module and count recursive calls once",
+			callTrees: map[uint64][]*nodetree.Node{
+				1: {
+					node(frame.Frame{Function: "handler", Module: "app", InApp: &testutil.True}, 0, 100,
+						node(frame.Frame{Function: "request", Module: "sdk", InApp: &testutil.False}, 0, 60,
+							node(frame.Frame{Function: "send", Module: "sdk", InApp: &testutil.False}, 0, 40),
+						),
+						node(frame.Frame{Function: "render", Module: "app", InApp: &testutil.True}, 60, 90),
+					),
+				},
+			},
+			want: Output{
+				Packages: []PackageMetrics{
+					{
+						Package:               "sdk",
+						IsApplication:         false,
+						SelfTimeNS:            60,
+						InclusiveTimeNS:       60,
+						ApplicationSelfTimeNS: 0,
+						Profiles:              1,
+					},
+					{
+						Package:               "app",
+						IsApplication:         true,
+						SelfTimeNS:            40,
+						InclusiveTimeNS:       100,
+						ApplicationSelfTimeNS: 40,
+						Profiles:              1,
+					},
+				},
+				TotalTimeNS:           100,
+				ApplicationSelfTimeNS: 40,
+				SystemSelfTimeNS:      60,
+			},
+		},
+		{
+			name: "group native frames by image",
+			callTrees: map[uint64][]*nodetree.Node{
+				1: {
+					node(frame.Frame{Function: "main", Package: "/private/var/containers/App", InstructionAddr: "0x1010", InApp: &testutil.True}, 0, 100,
+						node(frame.Frame{Function: "objc_msgSend", InstructionAddr: "0x2010", InApp: &testutil.False}, 0, 80),
+					),
+				},
+			},
+			debugMeta: debugmeta.DebugMeta{
+				Images: []debugmeta.Image{
+					{CodeFile: "/private/var/containers/App", ImageAddr: "0x1000", ImageSize: 0x1000},
+					{CodeFile: "/usr/lib/libobjc.A.dylib", ImageAddr: "0x2000", ImageSize: 0x1000},
+				},
+			},
+			want: Output{
+				Packages: []PackageMetrics{
+					{
+						Package:         "libobjc.A.dylib",
+						Image:           "/usr/lib/libobjc.A.dylib",
+						SelfTimeNS:      80,
+						InclusiveTimeNS: 80,
+						Profiles:        1,
+					},
+					{
+						Package:               "App",
+						Image:                 "/private/var/containers/App",
+						IsApplication:         true,
+						SelfTimeNS:            20,
+						InclusiveTimeNS:       100,
+						ApplicationSelfTimeNS: 20,
+						Profiles:              1,
+					},
+				},
+				TotalTimeNS:           100,
+				ApplicationSelfTimeNS: 20,
+				SystemSelfTimeNS:      80,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAggregator()
+			AddCallTrees(&a, tt.callTrees, tt.debugMeta)
+			if diff := testutil.Diff(a.ToOutput(), tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
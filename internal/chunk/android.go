@@ -8,9 +8,11 @@ import (
 	"github.com/getsentry/vroom/internal/clientsdk"
 	"github.com/getsentry/vroom/internal/debugmeta"
 	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/measurements"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/utils"
 )
 
@@ -23,6 +25,7 @@ type (
 		DebugMeta debugmeta.DebugMeta `json:"debug_meta"`
 
 		ClientSDK   clientsdk.ClientSDK `json:"client_sdk"`
+		Dist        string              `json:"dist,omitempty"`
 		DurationNS  uint64              `json:"duration_ns"`
 		Environment string              `json:"environment"`
 		Platform    platform.Platform   `json:"platform"`
@@ -65,6 +68,21 @@ func (c AndroidChunk) CallTrees(_ *string) (map[string][]*nodetree.Node, error)
 	return stringThreadCallTrees, nil
 }
 
+// MainThreadID returns the ID of the main thread, the only thread
+// call trees are generated for.
+func (c AndroidChunk) MainThreadID() string {
+	return strconv.FormatUint(c.Profile.ActiveThreadID(), 10)
+}
+
+func (c AndroidChunk) ThreadName(threadID string) string {
+	for _, t := range c.Profile.Threads {
+		if strconv.FormatUint(t.ID, 10) == threadID {
+			return t.Name
+		}
+	}
+	return ""
+}
+
 func (c AndroidChunk) SDKName() string {
 	return c.ClientSDK.Name
 }
@@ -77,6 +95,14 @@ func (c AndroidChunk) EndTimestamp() float64 {
 	return c.Timestamp + float64(c.DurationNS)*1e-9
 }
 
+func (c AndroidChunk) GetDebugMeta() debugmeta.DebugMeta {
+	return c.DebugMeta
+}
+
+func (c AndroidChunk) GetDist() string {
+	return c.Dist
+}
+
 func (c AndroidChunk) GetEnvironment() string {
 	return c.Environment
 }
@@ -85,6 +111,10 @@ func (c AndroidChunk) GetID() string {
 	return c.ID
 }
 
+func (c AndroidChunk) GetMeasurements() (map[string]measurements.MeasurementV2, error) {
+	return unmarshalMeasurements(c.Measurements)
+}
+
 func (c AndroidChunk) GetPlatform() platform.Platform {
 	return c.Platform
 }
@@ -131,5 +161,6 @@ func (c AndroidChunk) GetFrameWithFingerprint(target uint32) (frame.Frame, error
 	return frame.Frame{}, frame.ErrFrameNotFound
 }
 
-func (c *AndroidChunk) Normalize() {
+func (c *AndroidChunk) Normalize(rules stacktracerules.Rules) {
+	c.Profile.ApplyStackTraceRules(rules)
 }
@@ -4,16 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/getsentry/vroom/internal/debugmeta"
 	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/measurements"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/utils"
 )
 
 type (
 	chunkInterface interface {
+		GetDebugMeta() debugmeta.DebugMeta
+		GetDist() string
 		GetEnvironment() string
 		GetID() string
+		GetMeasurements() (map[string]measurements.MeasurementV2, error)
 		GetOrganizationID() uint64
 		GetPlatform() platform.Platform
 		GetProfilerID() string
@@ -24,6 +31,8 @@ type (
 		GetOptions() utils.Options
 		GetFrameWithFingerprint(uint32) (frame.Frame, error)
 		CallTrees(activeThreadID *string) (map[string][]*nodetree.Node, error)
+		MainThreadID() string
+		ThreadName(threadID string) string
 
 		DurationMS() uint64
 		EndTimestamp() float64
@@ -32,7 +41,7 @@ type (
 		StartTimestamp() float64
 		StoragePath() string
 
-		Normalize()
+		Normalize(rules stacktracerules.Rules)
 	}
 
 	Chunk struct {
@@ -83,6 +92,23 @@ func StoragePath(OrganizationID uint64, ProjectID uint64, ProfilerID string, ID
 	)
 }
 
+// unmarshalMeasurements decodes the raw measurements of a chunk, their
+// timestamps being absolute.
+func unmarshalMeasurements(b json.RawMessage) (map[string]measurements.MeasurementV2, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var m map[string]measurements.MeasurementV2
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c Chunk) GetDebugMeta() debugmeta.DebugMeta {
+	return c.chunk.GetDebugMeta()
+}
+
 func (c Chunk) GetEnvironment() string {
 	return c.chunk.GetEnvironment()
 }
@@ -91,6 +117,10 @@ func (c Chunk) GetID() string {
 	return c.chunk.GetID()
 }
 
+func (c Chunk) GetMeasurements() (map[string]measurements.MeasurementV2, error) {
+	return c.chunk.GetMeasurements()
+}
+
 func (c Chunk) GetOrganizationID() uint64 {
 	return c.chunk.GetOrganizationID()
 }
@@ -111,6 +141,10 @@ func (c Chunk) GetReceived() float64 {
 	return c.chunk.GetReceived()
 }
 
+func (c Chunk) GetDist() string {
+	return c.chunk.GetDist()
+}
+
 func (c Chunk) GetRelease() string {
 	return c.chunk.GetRelease()
 }
@@ -131,6 +165,14 @@ func (c Chunk) CallTrees(activeThreadID *string) (map[string][]*nodetree.Node, e
 	return c.chunk.CallTrees(activeThreadID)
 }
 
+func (c Chunk) MainThreadID() string {
+	return c.chunk.MainThreadID()
+}
+
+func (c Chunk) ThreadName(threadID string) string {
+	return c.chunk.ThreadName(threadID)
+}
+
 func (c Chunk) DurationMS() uint64 {
 	return c.chunk.DurationMS()
 }
@@ -150,6 +192,29 @@ func (c Chunk) StoragePath() string {
 	return c.chunk.StoragePath()
 }
 
-func (c *Chunk) Normalize() {
-	c.chunk.Normalize()
+func (c *Chunk) Normalize(rules stacktracerules.Rules) {
+	c.chunk.Normalize(rules)
+}
+
+// ObfuscatedAndroidTrace returns the trace of an Android chunk not
+// deobfuscated yet and the debug ID of its mapping file, if it has one.
+func (c Chunk) ObfuscatedAndroidTrace() (*profile.Android, string, bool) {
+	ac, ok := c.chunk.(*AndroidChunk)
+	if !ok || !ac.Profile.IsObfuscated() {
+		return nil, "", false
+	}
+	debugID := ac.BuildID
+	if debugID == "" {
+		debugID = ac.DebugMeta.ProguardDebugID()
+	}
+	return &ac.Profile, debugID, debugID != ""
+}
+
+// SampleData returns the data of a chunk in the sample format.
+func (c Chunk) SampleData() (*SampleData, bool) {
+	sc, ok := c.chunk.(*SampleChunk)
+	if !ok {
+		return nil, false
+	}
+	return &sc.Profile, true
 }
@@ -0,0 +1,286 @@
+package chunk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/sample"
+)
+
+type (
+	// stackprofProfile holds the results of stackprof run with raw: true.
+	stackprofProfile struct {
+		Frames map[string]stackprofFrame `json:"frames"`
+		// Raw holds each stack, the outermost frame first, prefixed by its
+		// length and followed by the number of consecutive samples having it.
+		Raw []uint64 `json:"raw"`
+		// RawSampleTimestamps holds the timestamp of each sample, in
+		// microseconds since the epoch.
+		RawSampleTimestamps []uint64 `json:"raw_sample_timestamps"`
+	}
+
+	stackprofFrame struct {
+		Name string `json:"name"`
+		File string `json:"file"`
+		Line uint32 `json:"line"`
+	}
+
+	// vernierProfile holds a profile written by vernier, in the processed
+	// format of the Firefox Profiler.
+	vernierProfile struct {
+		Meta struct {
+			// StartTime is in milliseconds since the epoch.
+			StartTime float64 `json:"startTime"`
+		} `json:"meta"`
+		Shared struct {
+			StringArray []string `json:"stringArray"`
+		} `json:"shared"`
+		Threads []vernierThread `json:"threads"`
+	}
+
+	vernierThread struct {
+		Name         string      `json:"name"`
+		TID          json.Number `json:"tid"`
+		IsMainThread bool        `json:"isMainThread"`
+		StringArray  []string    `json:"stringArray"`
+		Samples      struct {
+			// Stack is nil for samples without a stack.
+			Stack []*int `json:"stack"`
+			// Time is in milliseconds since the start of the profile.
+			Time       []float64 `json:"time"`
+			TimeDeltas []float64 `json:"timeDeltas"`
+		} `json:"samples"`
+		StackTable struct {
+			Frame []int `json:"frame"`
+			// Prefix is the index of the calling stack, nil for the root.
+			Prefix []*int `json:"prefix"`
+		} `json:"stackTable"`
+		FrameTable struct {
+			Func []int  `json:"func"`
+			Line []*int `json:"line"`
+		} `json:"frameTable"`
+		FuncTable struct {
+			Name       []int  `json:"name"`
+			FileName   []*int `json:"fileName"`
+			LineNumber []*int `json:"lineNumber"`
+		} `json:"funcTable"`
+	}
+)
+
+// stackprofThreadID is the thread of stackprof profiles, only sampling the
+// thread running Ruby code.
+const stackprofThreadID = "0"
+
+var (
+	ErrInvalidRubyProfile = errors.New("invalid ruby profile")
+
+	errUnknownRubyProfile = fmt.Errorf("%w: unknown format", ErrInvalidRubyProfile)
+)
+
+// RubySampleData converts a stackprof profile, collected with raw samples
+// and their timestamps, or a vernier profile to sample data.
+func RubySampleData(b []byte) (SampleData, error) {
+	var format struct {
+		Raw     json.RawMessage `json:"raw"`
+		Threads json.RawMessage `json:"threads"`
+	}
+	if err := json.Unmarshal(b, &format); err != nil {
+		return SampleData{}, fmt.Errorf("%w: %w", ErrInvalidRubyProfile, err)
+	}
+	switch {
+	case len(format.Raw) > 0:
+		var p stackprofProfile
+		if err := json.Unmarshal(b, &p); err != nil {
+			return SampleData{}, fmt.Errorf("%w: %w", ErrInvalidRubyProfile, err)
+		}
+		return p.sampleData()
+	case len(format.Threads) > 0:
+		var p vernierProfile
+		if err := json.Unmarshal(b, &p); err != nil {
+			return SampleData{}, fmt.Errorf("%w: %w", ErrInvalidRubyProfile, err)
+		}
+		return p.sampleData()
+	}
+	return SampleData{}, errUnknownRubyProfile
+}
+
+func (p stackprofProfile) sampleData() (SampleData, error) {
+	if len(p.RawSampleTimestamps) == 0 {
+		return SampleData{}, fmt.Errorf("%w: no raw sample timestamps", ErrInvalidRubyProfile)
+	}
+	d := SampleData{
+		ThreadMetadata: map[string]sample.ThreadMetadata{
+			stackprofThreadID: {Name: "main"},
+		},
+	}
+	frameIndexes := make(map[string]int)
+	for i := 0; i < len(p.Raw); {
+		length := int(p.Raw[i])
+		if i+length+1 >= len(p.Raw) {
+			return SampleData{}, fmt.Errorf("%w: truncated raw samples", ErrInvalidRubyProfile)
+		}
+		stack := make([]int, 0, length)
+		// samples have the innermost frame first
+		for j := i + length; j > i; j-- {
+			id := strconv.FormatUint(p.Raw[j], 10)
+			index, exists := frameIndexes[id]
+			if !exists {
+				f, exists := p.Frames[id]
+				if !exists {
+					return SampleData{}, fmt.Errorf("%w: unknown frame %s", ErrInvalidRubyProfile, id)
+				}
+				index = len(d.Frames)
+				frameIndexes[id] = index
+				d.Frames = append(d.Frames, rubyFrame(f.Name, f.File, f.Line))
+			}
+			stack = append(stack, index)
+		}
+		stackID := len(d.Stacks)
+		d.Stacks = append(d.Stacks, stack)
+		count := int(p.Raw[i+length+1])
+		for ; count > 0; count-- {
+			n := len(d.Samples)
+			if n >= len(p.RawSampleTimestamps) {
+				return SampleData{}, fmt.Errorf("%w: missing sample timestamps", ErrInvalidRubyProfile)
+			}
+			d.Samples = append(d.Samples, Sample{
+				StackID:   stackID,
+				ThreadID:  stackprofThreadID,
+				Timestamp: float64(p.RawSampleTimestamps[n]) / 1e6,
+			})
+		}
+		i += length + 2
+	}
+	return d, nil
+}
+
+func (p vernierProfile) sampleData() (SampleData, error) {
+	d := SampleData{
+		ThreadMetadata: make(map[string]sample.ThreadMetadata, len(p.Threads)),
+	}
+	for i, t := range p.Threads {
+		tid := t.TID.String()
+		if tid == "" {
+			tid = strconv.Itoa(i)
+		}
+		name := t.Name
+		if t.IsMainThread {
+			name = "main"
+		}
+		d.ThreadMetadata[tid] = sample.ThreadMetadata{Name: name}
+		if err := t.addSamples(&d, tid, p.Meta.StartTime, p.Shared.StringArray); err != nil {
+			return SampleData{}, err
+		}
+	}
+	return d, nil
+}
+
+// addSamples adds the samples of the thread, converting the stacks and the
+// frames they use.
+func (t vernierThread) addSamples(d *SampleData, tid string, startTime float64, sharedStrings []string) error {
+	stringArray := t.StringArray
+	if len(stringArray) == 0 {
+		stringArray = sharedStrings
+	}
+	str := func(i *int) string {
+		if i == nil || *i < 0 || *i >= len(stringArray) {
+			return ""
+		}
+		return stringArray[*i]
+	}
+	// frameIndexes and stackIndexes map the indexes of the thread's tables
+	// to the indexes in the sample data
+	frameIndexes := make(map[int]int)
+	stackIndexes := make(map[int]int)
+	frameIndex := func(i int) (int, error) {
+		if index, exists := frameIndexes[i]; exists {
+			return index, nil
+		}
+		if i < 0 || i >= len(t.FrameTable.Func) {
+			return 0, fmt.Errorf("%w: invalid frame %d", ErrInvalidRubyProfile, i)
+		}
+		fn := t.FrameTable.Func[i]
+		if fn < 0 || fn >= len(t.FuncTable.Name) {
+			return 0, fmt.Errorf("%w: invalid function %d", ErrInvalidRubyProfile, fn)
+		}
+		var fileName, lineNumber *int
+		if fn < len(t.FuncTable.FileName) {
+			fileName = t.FuncTable.FileName[fn]
+		}
+		if fn < len(t.FuncTable.LineNumber) {
+			lineNumber = t.FuncTable.LineNumber[fn]
+		}
+		if i < len(t.FrameTable.Line) && t.FrameTable.Line[i] != nil {
+			lineNumber = t.FrameTable.Line[i]
+		}
+		var line uint32
+		if lineNumber != nil && *lineNumber > 0 {
+			line = uint32(*lineNumber)
+		}
+		name := t.FuncTable.Name[fn]
+		index := len(d.Frames)
+		d.Frames = append(d.Frames, rubyFrame(str(&name), str(fileName), line))
+		frameIndexes[i] = index
+		return index, nil
+	}
+
+	var elapsed float64
+	for i, s := range t.Samples.Stack {
+		switch {
+		case i < len(t.Samples.Time):
+			elapsed = t.Samples.Time[i]
+		case i < len(t.Samples.TimeDeltas):
+			elapsed += t.Samples.TimeDeltas[i]
+		default:
+			return fmt.Errorf("%w: missing sample times", ErrInvalidRubyProfile)
+		}
+		if s == nil {
+			continue
+		}
+		stackID, exists := stackIndexes[*s]
+		if !exists {
+			var stack []int
+			// walk from the innermost frame to the root
+			for si := s; si != nil; si = t.StackTable.Prefix[*si] {
+				if *si < 0 || *si >= len(t.StackTable.Frame) || *si >= len(t.StackTable.Prefix) ||
+					len(stack) > len(t.StackTable.Frame) {
+					return fmt.Errorf("%w: invalid stack %d", ErrInvalidRubyProfile, *si)
+				}
+				index, err := frameIndex(t.StackTable.Frame[*si])
+				if err != nil {
+					return err
+				}
+				stack = append(stack, index)
+			}
+			stackID = len(d.Stacks)
+			stackIndexes[*s] = stackID
+			d.Stacks = append(d.Stacks, stack)
+		}
+		d.Samples = append(d.Samples, Sample{
+			StackID:   stackID,
+			ThreadID:  tid,
+			Timestamp: (startTime + elapsed) / 1e3,
+		})
+	}
+	return nil
+}
+
+func rubyFrame(name, file string, line uint32) frame.Frame {
+	f := frame.Frame{
+		Function: name,
+		Line:     line,
+		Path:     file,
+		Platform: platform.Ruby,
+	}
+	if file != "" {
+		f.File = path.Base(file)
+	}
+	f.SetRubyModule()
+	f.SetRubyPackage()
+	return f
+}
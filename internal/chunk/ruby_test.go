@@ -0,0 +1,167 @@
+package chunk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/sample"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+// rubyFrames are in the order they are added, from the innermost frame of
+// the first stack.
+var rubyFrames = []frame.Frame{
+	{
+		File:     "association.rb",
+		Function: "Association#load_target",
+		Line:     160,
+		Module:   "ActiveRecord::Associations",
+		Package:  "activerecord",
+		Path:     "/usr/local/bundle/gems/activerecord-7.1.3/lib/active_record/associations/association.rb",
+		Platform: platform.Ruby,
+	},
+	{
+		File:     "posts_controller.rb",
+		Function: "PostsController#index",
+		Line:     5,
+		Path:     "/app/app/controllers/posts_controller.rb",
+		Platform: platform.Ruby,
+	},
+	{
+		File:     "common.rb",
+		Function: "JSON.parse",
+		Line:     219,
+		Package:  "json",
+		Path:     "/usr/local/lib/ruby/3.3.0/json/common.rb",
+		Platform: platform.Ruby,
+	},
+}
+
+func TestUnmarshalRubyChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    SampleData
+	}{
+		{
+			name: "stackprof",
+			profile: `{
+				"mode": "wall",
+				"interval": 1000,
+				"frames": {
+					"1": {"name": "PostsController#index", "file": "/app/app/controllers/posts_controller.rb", "line": 5},
+					"2": {"name": "ActiveRecord::Associations::Association#load_target", "file": "/usr/local/bundle/gems/activerecord-7.1.3/lib/active_record/associations/association.rb", "line": 160},
+					"3": {"name": "JSON.parse", "file": "/usr/local/lib/ruby/3.3.0/json/common.rb", "line": 219}
+				},
+				"raw": [2, 1, 2, 2, 2, 1, 3, 1],
+				"raw_sample_timestamps": [1700000000000000, 1700000000001000, 1700000000002000]
+			}`,
+			want: SampleData{
+				Frames: rubyFrames,
+				Samples: []Sample{
+					{StackID: 0, ThreadID: "0", Timestamp: 1700000000},
+					{StackID: 0, ThreadID: "0", Timestamp: 1700000000.001},
+					{StackID: 1, ThreadID: "0", Timestamp: 1700000000.002},
+				},
+				Stacks: [][]int{
+					{0, 1},
+					{2, 1},
+				},
+				ThreadMetadata: map[string]sample.ThreadMetadata{
+					"0": {Name: "main"},
+				},
+			},
+		},
+		{
+			name: "vernier",
+			profile: `{
+				"meta": {"interval": 1, "startTime": 1700000000000, "product": "Ruby/Vernier"},
+				"threads": [
+					{
+						"name": "puma srv tp 001",
+						"tid": 42,
+						"isMainThread": false,
+						"samples": {"stack": [1, 1, null, 2], "time": [0, 1, 2, 3], "length": 4},
+						"stackTable": {"frame": [0, 1, 2], "prefix": [null, 0, 0], "length": 3},
+						"frameTable": {"func": [0, 1, 2], "line": [5, 160, null], "length": 3},
+						"funcTable": {"name": [0, 1, 2], "fileName": [3, 4, 5], "lineNumber": [5, 158, 219], "length": 3},
+						"stringArray": [
+							"PostsController#index",
+							"ActiveRecord::Associations::Association#load_target",
+							"JSON.parse",
+							"/app/app/controllers/posts_controller.rb",
+							"/usr/local/bundle/gems/activerecord-7.1.3/lib/active_record/associations/association.rb",
+							"/usr/local/lib/ruby/3.3.0/json/common.rb"
+						]
+					}
+				]
+			}`,
+			want: SampleData{
+				Frames: rubyFrames,
+				Samples: []Sample{
+					{StackID: 0, ThreadID: "42", Timestamp: 1700000000},
+					{StackID: 0, ThreadID: "42", Timestamp: 1700000000.001},
+					{StackID: 1, ThreadID: "42", Timestamp: 1700000000.003},
+				},
+				Stacks: [][]int{
+					{0, 1},
+					{2, 1},
+				},
+				ThreadMetadata: map[string]sample.ThreadMetadata{
+					"42": {Name: "puma srv tp 001"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := []byte(`{"version": "2", "platform": "ruby", "profile": ` + test.profile + `}`)
+			var c SampleChunk
+			if err := json.Unmarshal(b, &c); err != nil {
+				t.Fatalf("couldn't unmarshal chunk: %v", err)
+			}
+			if diff := testutil.Diff(c.Profile, test.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRubyChunkErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+	}{
+		{
+			name:    "stackprof without timestamps",
+			profile: `{"frames": {"1": {"name": "main"}}, "raw": [1, 1, 1]}`,
+		},
+		{
+			name:    "truncated stackprof samples",
+			profile: `{"frames": {"1": {"name": "main"}}, "raw": [2, 1], "raw_sample_timestamps": [1]}`,
+		},
+		{
+			name:    "unknown stackprof frame",
+			profile: `{"frames": {}, "raw": [1, 1, 1], "raw_sample_timestamps": [1]}`,
+		},
+		{
+			name:    "invalid vernier stack",
+			profile: `{"meta": {}, "threads": [{"samples": {"stack": [3], "time": [0]}}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := []byte(`{"version": "2", "platform": "ruby", "profile": ` + test.profile + `}`)
+			var c SampleChunk
+			err := json.Unmarshal(b, &c)
+			if !errors.Is(err, ErrInvalidRubyProfile) {
+				t.Fatalf("expected an invalid ruby profile error, got %v", err)
+			}
+		})
+	}
+}
@@ -6,19 +6,28 @@ import (
 	"hash/fnv"
 	"math"
 	"sort"
+	"strconv"
 
 	"github.com/getsentry/vroom/internal/clientsdk"
 	"github.com/getsentry/vroom/internal/debugmeta"
 	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/measurements"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/sample"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/utils"
 )
 
 var (
 	ErrInvalidStackID = errors.New("profile contains invalid stack id")
 	ErrInvalidFrameID = errors.New("profile contains invalid frame id")
+
+	// mainThreadNames are the names SDKs give to the main thread.
+	mainThreadNames = map[string]struct{}{
+		"main":       {},
+		"MainThread": {},
+	}
 )
 
 type (
@@ -30,6 +39,7 @@ type (
 		DebugMeta debugmeta.DebugMeta `json:"debug_meta"`
 
 		ClientSDK   clientsdk.ClientSDK `json:"client_sdk"`
+		Dist        string              `json:"dist,omitempty"`
 		Environment string              `json:"environment"`
 		Platform    platform.Platform   `json:"platform"`
 		Release     string              `json:"release"`
@@ -49,19 +59,57 @@ type (
 	}
 
 	SampleData struct {
-		Frames         []frame.Frame                    `json:"frames"`
-		Samples        []Sample                         `json:"samples"`
-		Stacks         [][]int                          `json:"stacks"`
-		ThreadMetadata map[string]sample.ThreadMetadata `json:"thread_metadata"`
+		AsyncTaskMetadata map[string]sample.AsyncTaskMetadata `json:"async_task_metadata,omitempty"`
+		Frames            []frame.Frame                       `json:"frames"`
+		Samples           []Sample                            `json:"samples"`
+		Stacks            [][]int                             `json:"stacks"`
+		ThreadMetadata    map[string]sample.ThreadMetadata    `json:"thread_metadata"`
 	}
 
 	Sample struct {
-		StackID   int     `json:"stack_id"`
+		StackID int `json:"stack_id"`
+		// TaskID is the ID of the async task running when the sample was
+		// taken, if any.
+		TaskID    string  `json:"task_id,omitempty"`
 		ThreadID  string  `json:"thread_id"`
 		Timestamp float64 `json:"timestamp"`
 	}
 )
 
+// UnmarshalJSON decodes a chunk in the sample format or, for Ruby, with a
+// stackprof or vernier profile converted to the sample format.
+func (c *SampleChunk) UnmarshalJSON(b []byte) error {
+	type sampleChunk SampleChunk
+	err := json.Unmarshal(b, (*sampleChunk)(c))
+	if c.Platform != platform.Ruby {
+		return err
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		return err
+	}
+	if err == nil && len(c.Profile.Samples) > 0 {
+		return nil
+	}
+	// stackprof frames are not a list and vernier profiles don't have
+	// samples at the top level, so decode the profile again
+	var raw struct {
+		Profile json.RawMessage `json:"profile"`
+	}
+	if rawErr := json.Unmarshal(b, &raw); rawErr != nil {
+		return rawErr
+	}
+	d, convErr := RubySampleData(raw.Profile)
+	if errors.Is(convErr, errUnknownRubyProfile) {
+		return err
+	}
+	if convErr != nil {
+		return convErr
+	}
+	c.Profile = d
+	return nil
+}
+
 func (c SampleChunk) StoragePath() string {
 	return StoragePath(
 		c.OrganizationID,
@@ -71,16 +119,46 @@ func (c SampleChunk) StoragePath() string {
 	)
 }
 
-func (c *SampleChunk) Normalize() {
+// ReplaceFrames replaces the frames, each frame of the stacks and of the
+// async task metadata being replaced by the frames at the given indexes.
+func (d *SampleData) ReplaceFrames(frames []frame.Frame, indexes [][]int) {
+	sample.ReplaceStackFrames(d.Stacks, d.AsyncTaskMetadata, indexes, len(frames))
+	d.Frames = frames
+}
+
+// FramePositions reports which frames are at the top of the stacks and
+// which are below it, see sample.FramePositions.
+func (d *SampleData) FramePositions() ([]bool, []bool) {
+	return sample.FramePositions(d.Stacks, len(d.Frames))
+}
+
+// SplitCallerFrames makes the stacks use the copies of frames below their
+// top, see sample.SplitCallerFrames.
+func (d *SampleData) SplitCallerFrames(copies map[int]int) {
+	sample.SplitCallerFrames(d.Stacks, copies)
+}
+
+// CallerFrameIDs returns the ID of a frame calling each frame or -1, see
+// sample.CallerFrameIDs.
+func (d *SampleData) CallerFrameIDs() []int {
+	return sample.CallerFrameIDs(d.Stacks, len(d.Frames))
+}
+
+func (c *SampleChunk) Normalize(rules stacktracerules.Rules) {
 	for i := range c.Profile.Frames {
 		f := c.Profile.Frames[i]
-		f.Normalize(c.Platform)
+		f.NormalizeForRuntime(c.Platform, frame.Runtime{GoMainModule: c.Options.GoMainModule})
+		rules.Apply(&f)
 		c.Profile.Frames[i] = f
 	}
 
 	if c.Platform == platform.Python {
 		c.Profile.trimPythonStacks()
 	}
+
+	if c.Options.StitchAsyncStacks {
+		c.Profile.stitchAsyncStacks()
+	}
 }
 
 // CallTrees generates call trees from samples.
@@ -186,10 +264,58 @@ func (d *SampleData) trimPythonStacks() {
 	}
 }
 
+// stitchAsyncStacks replaces the stacks of the samples taken while an async
+// task was running by their logical stacks, see sample.StitchAsyncStacks.
+func (d *SampleData) stitchAsyncStacks() {
+	if len(d.AsyncTaskMetadata) == 0 {
+		return
+	}
+	stackIDs := make([]int, len(d.Samples))
+	taskIDs := make([]string, len(d.Samples))
+	for i, s := range d.Samples {
+		stackIDs[i] = s.StackID
+		taskIDs[i] = s.TaskID
+	}
+	d.Stacks = sample.StitchAsyncStacks(d.Stacks, stackIDs, taskIDs, d.AsyncTaskMetadata, len(d.Frames))
+	for i := range d.Samples {
+		d.Samples[i].StackID = stackIDs[i]
+	}
+}
+
 func (c SampleChunk) DurationMS() uint64 {
 	return uint64(math.Round((c.EndTimestamp() - c.StartTimestamp()) * 1e3))
 }
 
+// MainThreadID returns the ID of the thread named as the main thread
+// by the SDK, the lowest one if several are, or an empty string if
+// there's none.
+func (c SampleChunk) MainThreadID() string {
+	var mainThreadID string
+	for tid, m := range c.Profile.ThreadMetadata {
+		if _, exists := mainThreadNames[m.Name]; !exists {
+			continue
+		}
+		if mainThreadID == "" || lessThreadID(tid, mainThreadID) {
+			mainThreadID = tid
+		}
+	}
+	return mainThreadID
+}
+
+// lessThreadID compares thread IDs numerically when they're numbers.
+func lessThreadID(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+func (c SampleChunk) ThreadName(threadID string) string {
+	return c.Profile.ThreadMetadata[threadID].Name
+}
+
 func (c SampleChunk) SDKName() string {
 	return c.ClientSDK.Name
 }
@@ -206,6 +332,14 @@ func (c SampleChunk) EndTimestamp() float64 {
 	return c.Profile.Samples[count-1].Timestamp
 }
 
+func (c SampleChunk) GetDebugMeta() debugmeta.DebugMeta {
+	return c.DebugMeta
+}
+
+func (c SampleChunk) GetDist() string {
+	return c.Dist
+}
+
 func (c SampleChunk) GetEnvironment() string {
 	return c.Environment
 }
@@ -214,6 +348,10 @@ func (c SampleChunk) GetID() string {
 	return c.ID
 }
 
+func (c SampleChunk) GetMeasurements() (map[string]measurements.MeasurementV2, error) {
+	return unmarshalMeasurements(c.Measurements)
+}
+
 func (c SampleChunk) GetPlatform() platform.Platform {
 	return c.Platform
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/sample"
 	"github.com/getsentry/vroom/internal/testutil"
 	"github.com/getsentry/vroom/internal/utils"
 )
@@ -265,10 +266,147 @@ func TestTrimPythonStacks(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			if diff := testutil.Diff(test.input, test.output); diff != "" {
 				t.Fatalf("Result mismatch: got - want +\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestStitchAsyncStacks(t *testing.T) {
+	d := SampleData{
+		AsyncTaskMetadata: map[string]sample.AsyncTaskMetadata{
+			"fetch": {
+				EntryFrame:     2,
+				AwaitedBy:      "handler",
+				AwaitingFrames: []int{4},
+			},
+			"handler": {
+				EntryFrame: 4,
+			},
+		},
+		Frames: []frame.Frame{
+			{Function: "run_forever"},
+			{Function: "_run"},
+			{Function: "fetch"},
+			{Function: "query"},
+			{Function: "handle_request"},
+		},
+		Samples: []Sample{
+			{StackID: 0, TaskID: "fetch", ThreadID: "1", Timestamp: 1},
+			{StackID: 0, TaskID: "fetch", ThreadID: "1", Timestamp: 2},
+			{StackID: 1, TaskID: "handler", ThreadID: "1", Timestamp: 3},
+			{StackID: 0, ThreadID: "2", Timestamp: 4},
+		},
+		Stacks: [][]int{
+			{3, 2, 1, 0},
+			{4, 1, 0},
+		},
+	}
+	d.stitchAsyncStacks()
+
+	wantSamples := []Sample{
+		{StackID: 2, TaskID: "fetch", ThreadID: "1", Timestamp: 1},
+		{StackID: 2, TaskID: "fetch", ThreadID: "1", Timestamp: 2},
+		{StackID: 1, TaskID: "handler", ThreadID: "1", Timestamp: 3},
+		{StackID: 0, ThreadID: "2", Timestamp: 4},
+	}
+	if diff := testutil.Diff(d.Samples, wantSamples); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	wantStacks := [][]int{
+		{3, 2, 1, 0},
+		{4, 1, 0},
+		{3, 2, 4, 1, 0},
+	}
+	if diff := testutil.Diff(d.Stacks, wantStacks); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestNormalizeStitchesAsyncStacksIfEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		options utils.Options
+		want    []int
+	}{
+		{
+			name: "disabled",
+			want: []int{1, 0},
+		},
+		{
+			name:    "enabled",
+			options: utils.Options{StitchAsyncStacks: true},
+			want:    []int{1, 2, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := SampleChunk{
+				Options:  test.options,
+				Platform: platform.Python,
+				Profile: SampleData{
+					AsyncTaskMetadata: map[string]sample.AsyncTaskMetadata{
+						"fetch": {EntryFrame: 1, AwaitedBy: "handler", AwaitingFrames: []int{2}},
+					},
+					Frames: []frame.Frame{
+						{Function: "run_forever", Module: "asyncio.base_events"},
+						{Function: "fetch", Module: "app"},
+						{Function: "handle_request", Module: "app"},
+					},
+					Samples: []Sample{{StackID: 0, TaskID: "fetch", ThreadID: "1"}},
+					Stacks:  [][]int{{1, 0}},
+				},
+			}
+			c.Normalize(nil)
+			got := c.Profile.Stacks[c.Profile.Samples[0].StackID]
+			if diff := testutil.Diff(got, test.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMainThreadID(t *testing.T) {
+	tests := []struct {
+		name           string
+		threadMetadata map[string]sample.ThreadMetadata
+		want           string
+	}{
+		{
+			name: "single main thread",
+			threadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "worker"},
+				"2": {Name: "MainThread"},
+			},
+			want: "2",
+		},
+		{
+			name: "several main threads",
+			threadMetadata: map[string]sample.ThreadMetadata{
+				"10": {Name: "main"},
+				"9":  {Name: "main"},
+				"11": {Name: "MainThread"},
+			},
+			want: "9",
+		},
+		{
+			name: "no main thread",
+			threadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "worker"},
+			},
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := SampleChunk{Profile: SampleData{ThreadMetadata: test.threadMetadata}}
+			if got := c.MainThreadID(); got != test.want {
+				t.Fatalf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package debugmeta
 
+import (
+	"strconv"
+	"strings"
+)
+
 type (
 	Features struct {
 		HasDebugInfo  bool `json:"has_debug_info"`
@@ -25,3 +30,44 @@ type (
 		Images []Image `json:"images,omitempty"`
 	}
 )
+
+// ImageContainingAddress returns the image whose address range contains the
+// instruction address, if any.
+func (d DebugMeta) ImageContainingAddress(addr uint64) (Image, bool) {
+	for _, image := range d.Images {
+		if image.ImageSize == 0 {
+			continue
+		}
+		start, err := ParseAddress(image.ImageAddr)
+		if err != nil {
+			continue
+		}
+		if addr >= start && addr < start+image.ImageSize {
+			return image, true
+		}
+	}
+	return Image{}, false
+}
+
+// ProguardDebugID returns the debug ID of the first ProGuard mapping file
+// image, if any.
+func (d DebugMeta) ProguardDebugID() string {
+	for _, image := range d.Images {
+		if image.Type != "proguard" {
+			continue
+		}
+		if image.UUID != "" {
+			return image.UUID
+		}
+		if image.DebugID != "" {
+			return image.DebugID
+		}
+	}
+	return ""
+}
+
+// ParseAddress parses an hexadecimal address, with or without a 0x prefix.
+func ParseAddress(addr string) (uint64, error) {
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X")
+	return strconv.ParseUint(addr, 16, 64)
+}
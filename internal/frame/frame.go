@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"hash"
 	"hash/fnv"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/getsentry/vroom/internal/packageutil"
@@ -23,6 +25,28 @@ var (
 		"hermes": {},
 	}
 
+	// goModuleCachePathRegexp matches files of dependencies, in the module
+	// cache or recorded as module@version when built with -trimpath.
+	goModuleCachePathRegexp = regexp.MustCompile(`/pkg/mod/|@v\d|(^|/)vendor/`)
+	// rubySystemPathRegexp matches files of gems, of the standard library and
+	// of methods implemented by the interpreter.
+	rubySystemPathRegexp = regexp.MustCompile(`/gems/|/lib/ruby/|/vendor/bundle/|/rubygems/|^<internal:|^<cfunc>`)
+	// rubyGemPathRegexp captures the name of the gem of a file, followed by
+	// its version or, for gems installed from git, its revision.
+	rubyGemPathRegexp = regexp.MustCompile(`/gems/([^/]+?)-(?:\d[^/]*|[0-9a-f]{6,})/`)
+	// rubyStandardLibraryPathRegexp captures the name of the library of a
+	// file of the standard library.
+	rubyStandardLibraryPathRegexp = regexp.MustCompile(`/lib/ruby/\d+\.\d+\.\d+/([^/]+?)(?:\.rb)?(?:/|$)`)
+	// csharpAsyncStateMachineRegexp matches the state machines generated by
+	// the compiler for async methods, MyApp.Service+<ProcessAsync>d__12.
+	csharpAsyncStateMachineRegexp = regexp.MustCompile(`^(.*)[+.]<([^>]+)>d__\d+$`)
+	// pythonSystemPathRegexp matches files of installed packages and of the
+	// standard library of interpreters, installed system-wide or by pyenv or
+	// conda, once backslashes are replaced by slashes.
+	pythonSystemPathRegexp = regexp.MustCompile(
+		`(?i)/(site|dist)-packages/|/lib/python\d+(\.\d+)?t?/|/python\d+/lib/|/\.pyenv/versions/|/(ana|mini)conda\d*/|^/opt/conda/`,
+	)
+
 	ErrFrameNotFound = errors.New("Unable to find matching frame")
 )
 
@@ -54,6 +78,18 @@ type (
 		DeobfuscationStatus string `json:"deobfuscation_status,omitempty"`
 		SymbolicatorStatus  string `json:"symbolicator_status,omitempty"`
 		JsSymbolicated      *bool  `json:"symbolicated,omitempty"`
+		// ExcludeFromGroup is set by stack trace rules to exclude a frame
+		// from function aggregation.
+		ExcludeFromGroup bool `json:"exclude_from_group,omitempty"`
+	}
+
+	// Runtime is what is known about the runtime the frames ran on, used to
+	// tell application frames from system ones.
+	Runtime struct {
+		// Version is the version of the runtime, like 3.11.4 for Python.
+		Version string
+		// GoMainModule is the import path of the main module of a Go program.
+		GoMainModule string
 	}
 )
 
@@ -200,12 +236,54 @@ func (f Frame) IsRustApplicationFrame() bool {
 	return packageutil.IsRustApplicationPackage(f.Package)
 }
 
+type (
+	// pythonVersions holds the minor versions of Python 3 having a module
+	// of the standard library, from added to the one before removed, 0
+	// meaning the range is unbounded.
+	pythonVersions struct {
+		added   int
+		removed int
+	}
+)
+
+// PythonMinorVersion returns the minor version of a Python 3 runtime
+// version, 11 for 3.11.4, or 0 if it can't be parsed.
+func PythonMinorVersion(version string) int {
+	major, rest, found := strings.Cut(strings.TrimSpace(version), ".")
+	if !found || major != "3" {
+		return 0
+	}
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	minor, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0
+	}
+	return minor
+}
+
+// includes returns true if the minor version has the module, any version
+// having it when the version is unknown.
+func (v pythonVersions) includes(minorVersion int) bool {
+	if minorVersion == 0 {
+		return true
+	}
+	return (v.added == 0 || minorVersion >= v.added) &&
+		(v.removed == 0 || minorVersion < v.removed)
+}
+
 func (f Frame) IsPythonApplicationFrame() bool {
-	if strings.Contains(f.Path, "/site-packages/") ||
-		strings.Contains(f.Path, "/dist-packages/") ||
-		strings.Contains(f.Path, "\\site-packages\\") ||
-		strings.Contains(f.Path, "\\dist-packages\\") ||
-		strings.HasPrefix(f.Path, "/usr/local/") {
+	return f.IsPythonApplicationFrameForVersion(0)
+}
+
+// IsPythonApplicationFrameForVersion determines whether the frame belongs
+// to the application, using the standard library of a minor version of
+// Python 3, or of any version if 0.
+func (f Frame) IsPythonApplicationFrameForVersion(minorVersion int) bool {
+	if strings.HasPrefix(f.Path, "/usr/local/") ||
+		pythonSystemPathRegexp.MatchString(strings.ReplaceAll(f.Path, "\\", "/")) {
 		return false
 	}
 
@@ -219,14 +297,177 @@ func (f Frame) IsPythonApplicationFrame() bool {
 		return false
 	}
 
-	_, ok := pythonStdlib[module[0]]
-	return !ok
+	versions, ok := pythonStdlib[module[0]]
+	return !ok || !versions.includes(minorVersion)
 }
 
 func (f Frame) IsPHPApplicationFrame() bool {
 	return !strings.Contains(f.Path, "/vendor/")
 }
 
+// IsRubyApplicationFrame determines whether the frame belongs to the
+// application, frames of gems and of the standard library being system
+// frames. Methods implemented in C don't have a path.
+func (f Frame) IsRubyApplicationFrame() bool {
+	if f.Path == "" {
+		return false
+	}
+	return !rubySystemPathRegexp.MatchString(strings.ReplaceAll(f.Path, "\\", "/"))
+}
+
+// SetRubyModule splits the method name like the Ruby SDK does, setting the
+// module to "ActiveRecord" and the function to "Relation#load" for
+// "ActiveRecord::Relation#load".
+func (f *Frame) SetRubyModule() {
+	if f.Module != "" {
+		return
+	}
+	i := strings.LastIndex(f.Function, "::")
+	if i == -1 {
+		return
+	}
+	f.Module = f.Function[:i]
+	f.Function = f.Function[i+2:]
+}
+
+// SetRubyPackage sets the package to the gem, or the library of the
+// standard library, the file of the frame belongs to, "json" for
+// ".../gems/json-2.7.1/lib/json/common.rb".
+func (f *Frame) SetRubyPackage() {
+	if f.Package != "" {
+		return
+	}
+	p := strings.ReplaceAll(f.Path, "\\", "/")
+	if m := rubyGemPathRegexp.FindStringSubmatch(p); m != nil {
+		f.Package = m[1]
+	} else if m := rubyStandardLibraryPathRegexp.FindStringSubmatch(p); m != nil {
+		f.Package = m[1]
+	}
+}
+
+// IsCSharpApplicationFrame determines whether the frame belongs to the
+// application with the namespace of its type.
+func (f Frame) IsCSharpApplicationFrame() bool {
+	if f.Module == "" {
+		return packageutil.IsDotNetApplicationNamespace(f.Function)
+	}
+	return packageutil.IsDotNetApplicationNamespace(f.Module)
+}
+
+// SetCSharpModule sets the module to the type of the method if the function
+// is fully qualified, dropping the parameters like the .NET SDK does, and
+// replaces the state machines of async methods by the methods themselves,
+// MyApp.Service and ProcessAsync for MyApp.Service+<ProcessAsync>d__12 and
+// MoveNext.
+func (f *Frame) SetCSharpModule() {
+	if f.Module == "" {
+		name, _, _ := strings.Cut(f.Function, "(")
+		if i := lastTopLevelDot(name); i != -1 {
+			// constructors are named .ctor and .cctor
+			if i > 0 && name[i-1] == '.' {
+				i--
+			}
+			f.Module = name[:i]
+			f.Function = name[i+1:]
+		}
+	}
+	if f.Function != "MoveNext" && f.Function != "MoveNext()" {
+		return
+	}
+	if m := csharpAsyncStateMachineRegexp.FindStringSubmatch(f.Module); m != nil {
+		f.Module = m[1]
+		f.Function = m[2]
+	}
+}
+
+// lastTopLevelDot returns the index of the last dot not in the type
+// arguments of a name, or -1 if there's none.
+func lastTopLevelDot(name string) int {
+	depth := 0
+	for i := len(name) - 1; i >= 0; i-- {
+		switch name[i] {
+		case ']':
+			depth++
+		case '[':
+			depth--
+		case '.':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// IsGoApplicationFrame determines whether the frame belongs to the main
+// module, like IsGoApplicationFrameForModule, when the main module isn't
+// known.
+func (f Frame) IsGoApplicationFrame() bool {
+	return f.IsGoApplicationFrameForModule("")
+}
+
+// IsGoApplicationFrameForModule determines whether the frame belongs to the
+// main module, whose import path is mainModule. Frames of the standard library
+// and of dependencies are system frames.
+//
+// When the main module isn't known, it's guessed from the path of the file:
+// the standard library is found in GOROOT and dependencies in the module
+// cache or vendored. Frames without a path, apart from the main package,
+// can't be told apart and are system frames.
+func (f Frame) IsGoApplicationFrameForModule(mainModule string) bool {
+	if f.Module == "main" {
+		return true
+	}
+	if mainModule != "" && f.Module != "" {
+		return f.Module == mainModule || strings.HasPrefix(f.Module, mainModule+"/")
+	}
+	p := strings.ReplaceAll(f.Path, "\\", "/")
+	if p == "" || strings.HasPrefix(p, "$GOROOT/") || goModuleCachePathRegexp.MatchString(p) {
+		return false
+	}
+	if !isGoStandardLibraryPackage(f.Module) {
+		return true
+	}
+	// A file of the standard library is in the src directory of GOROOT, or
+	// recorded with its import path when built with -trimpath, while main
+	// modules without a dot in their path can be anywhere.
+	dir := path.Dir(p)
+	return dir != f.Module && !strings.HasSuffix(dir, "/src/"+f.Module)
+}
+
+// isGoStandardLibraryPackage returns true if the import path looks like one
+// of a package of the standard library, its first element not having a dot.
+func isGoStandardLibraryPackage(importPath string) bool {
+	if importPath == "" || importPath == "main" {
+		return false
+	}
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// SetGoModule sets the module to the import path of the package of a Go
+// function, "github.com/org/repo/pkg" for "github.com/org/repo/pkg.(*T).Method",
+// keeping only "(*T).Method" as the function.
+func (f *Frame) SetGoModule() {
+	if f.Module != "" {
+		return
+	}
+	// type arguments of generic functions can contain import paths
+	end := strings.IndexByte(f.Function, '[')
+	if end == -1 {
+		end = len(f.Function)
+	}
+	start := strings.LastIndexByte(f.Function[:end], '/') + 1
+	i := strings.IndexByte(f.Function[start:end], '.')
+	if i == -1 {
+		return
+	}
+	i += start
+	// dots in the last element of an import path are escaped in symbol names
+	f.Module = strings.ReplaceAll(f.Function[:i], "%2e", ".")
+	f.Function = f.Function[i+1:]
+}
+
 func (f Frame) Fingerprint() uint32 {
 	h := fnv.New64()
 	h.Write([]byte(f.ModuleOrPackage()))
@@ -244,6 +485,15 @@ func defaultFormatter(f Frame) string {
 	return f.Function
 }
 
+// goFormatter qualifies the function with the import path of its package,
+// like Go symbol names.
+func goFormatter(f Frame) string {
+	if f.Module == "" {
+		return f.Function
+	}
+	return f.Module + "." + f.Function
+}
+
 func makeJoinedNameFormatter(separator string) func(f Frame) string {
 	return func(f Frame) string {
 		// These platforms can additionally use the module/package name to fully
@@ -273,6 +523,11 @@ var fullyQualifiedNameFormatters = map[platform.Platform]func(f Frame) string{
 	// module/package name, and concatenate it with the function name.
 	platform.Python: makeJoinedNameFormatter("."),
 	platform.Node:   makeJoinedNameFormatter("."),
+	platform.Ruby:   makeJoinedNameFormatter("::"),
+	platform.CSharp: makeJoinedNameFormatter("."),
+
+	// Functions are qualified by the import path of their package.
+	platform.Go: goFormatter,
 }
 
 func (f Frame) FullyQualifiedName(p platform.Platform) string {
@@ -284,6 +539,12 @@ func (f Frame) FullyQualifiedName(p platform.Platform) string {
 }
 
 func (f *Frame) SetInApp(p platform.Platform) {
+	f.SetInAppForRuntime(p, Runtime{})
+}
+
+// SetInAppForRuntime sets if the frame belongs to the application, like
+// SetInApp, taking what is known about the runtime into account.
+func (f *Frame) SetInAppForRuntime(p platform.Platform, r Runtime) {
 	// for react-native the in_app field seems to be messed up most of the times,
 	// with system libraries and other frames that are clearly system frames
 	// labelled as `in_app`.
@@ -306,9 +567,15 @@ func (f *Frame) SetInApp(p platform.Platform) {
 	case platform.Rust:
 		isApplication = f.IsRustApplicationFrame()
 	case platform.Python:
-		isApplication = f.IsPythonApplicationFrame()
+		isApplication = f.IsPythonApplicationFrameForVersion(PythonMinorVersion(r.Version))
 	case platform.PHP:
 		isApplication = f.IsPHPApplicationFrame()
+	case platform.Go:
+		isApplication = f.IsGoApplicationFrameForModule(r.GoMainModule)
+	case platform.Ruby:
+		isApplication = f.IsRubyApplicationFrame()
+	case platform.CSharp:
+		isApplication = f.IsCSharpApplicationFrame()
 	}
 	f.InApp = &isApplication
 }
@@ -333,8 +600,23 @@ func (f *Frame) SetStatus() {
 }
 
 func (f *Frame) Normalize(p platform.Platform) {
+	f.NormalizeForRuntime(p, Runtime{})
+}
+
+// NormalizeForRuntime normalizes the frame like Normalize, taking what is
+// known about the runtime into account.
+func (f *Frame) NormalizeForRuntime(p platform.Platform, r Runtime) {
 	// Call order is important since SetInApp uses Status and Platform
 	f.SetStatus()
 	f.SetPlatform(p)
-	f.SetInApp(p)
+	switch f.Platform {
+	case platform.Go:
+		f.SetGoModule()
+	case platform.Ruby:
+		f.SetRubyModule()
+		f.SetRubyPackage()
+	case platform.CSharp:
+		f.SetCSharpModule()
+	}
+	f.SetInAppForRuntime(p, r)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
 )
 
 func frameType(isApplication bool) string {
@@ -74,6 +75,7 @@ func TestIsPythonApplicationFrame(t *testing.T) {
 	tests := []struct {
 		name          string
 		frame         Frame
+		minorVersion  int
 		isApplication bool
 	}{
 		{
@@ -141,11 +143,88 @@ func TestIsPythonApplicationFrame(t *testing.T) {
 			},
 			isApplication: false,
 		},
+		{
+			name: "stdlib module added later",
+			frame: Frame{
+				Module: "tomllib",
+			},
+			minorVersion:  11,
+			isApplication: false,
+		},
+		{
+			name: "stdlib module not added yet",
+			frame: Frame{
+				Module: "tomllib",
+				Path:   "/home/user/app/tomllib/__init__.py",
+			},
+			minorVersion:  10,
+			isApplication: true,
+		},
+		{
+			name: "stdlib module removed",
+			frame: Frame{
+				Module: "telnetlib",
+				Path:   "/home/user/app/telnetlib.py",
+			},
+			minorVersion:  13,
+			isApplication: true,
+		},
+		{
+			name: "stdlib module before removal",
+			frame: Frame{
+				Module: "telnetlib",
+			},
+			minorVersion:  12,
+			isApplication: false,
+		},
+		{
+			name: "virtualenv",
+			frame: Frame{
+				Path: "/home/user/app/.venv/lib/python3.12/site-packages/flask/app.py",
+			},
+			isApplication: false,
+		},
+		{
+			name: "pyenv stdlib",
+			frame: Frame{
+				Path: "/home/user/.pyenv/versions/3.11.4/lib/python3.11/asyncio/events.py",
+			},
+			isApplication: false,
+		},
+		{
+			name: "conda stdlib",
+			frame: Frame{
+				Path: "/home/user/miniconda3/envs/app/lib/python3.10/threading.py",
+			},
+			isApplication: false,
+		},
+		{
+			name: "docker image stdlib",
+			frame: Frame{
+				Path: "/opt/conda/lib/python3.11/concurrent/futures/thread.py",
+			},
+			isApplication: false,
+		},
+		{
+			name: "windows stdlib",
+			frame: Frame{
+				Path: "C:\\Users\\user\\AppData\\Local\\Programs\\Python\\Python311\\Lib\\asyncio\\events.py",
+			},
+			isApplication: false,
+		},
+		{
+			name: "app in a python directory",
+			frame: Frame{
+				Module: "handlers",
+				Path:   "/srv/python/app/handlers.py",
+			},
+			isApplication: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if isApplication := tt.frame.IsPythonApplicationFrame(); isApplication != tt.isApplication {
+			if isApplication := tt.frame.IsPythonApplicationFrameForVersion(tt.minorVersion); isApplication != tt.isApplication {
 				t.Fatalf(
 					"Expected %s frame but got %s frame",
 					frameType(tt.isApplication),
@@ -156,6 +235,28 @@ func TestIsPythonApplicationFrame(t *testing.T) {
 	}
 }
 
+func TestPythonMinorVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{version: "3.11.4", want: 11},
+		{version: "3.13.0rc1", want: 13},
+		{version: "3.9", want: 9},
+		{version: "2.7.18", want: 0},
+		{version: "", want: 0},
+		{version: "3", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := PythonMinorVersion(tt.version); got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestIsNodeApplicationFrame(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -328,6 +429,397 @@ func TestIsPHPApplicationFrame(t *testing.T) {
 	}
 }
 
+func TestIsRubyApplicationFrame(t *testing.T) {
+	tests := []struct {
+		name          string
+		frame         Frame
+		isApplication bool
+	}{
+		{
+			name: "app",
+			frame: Frame{
+				Function: "PostsController#index",
+				Path:     "/app/app/controllers/posts_controller.rb",
+			},
+			isApplication: true,
+		},
+		{
+			name: "gem",
+			frame: Frame{
+				Function: "Relation#load",
+				Module:   "ActiveRecord",
+				Path:     "/usr/local/bundle/gems/activerecord-7.1.3/lib/active_record/relation.rb",
+			},
+			isApplication: false,
+		},
+		{
+			name: "vendored bundle",
+			frame: Frame{
+				Function: "Relation#load",
+				Module:   "ActiveRecord",
+				Path:     "/app/vendor/bundle/ruby/3.3.0/bundler/gems/rails-1a2b3c/activerecord/lib/active_record/relation.rb",
+			},
+			isApplication: false,
+		},
+		{
+			name: "standard library",
+			frame: Frame{
+				Function: "JSON.parse",
+				Path:     "/usr/local/lib/ruby/3.3.0/json/common.rb",
+			},
+			isApplication: false,
+		},
+		{
+			name: "internal",
+			frame: Frame{
+				Function: "Kernel#tap",
+				Path:     "<internal:kernel>",
+			},
+			isApplication: false,
+		},
+		{
+			name:          "c method",
+			frame:         Frame{Function: "Array#each"},
+			isApplication: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isApplication := tt.frame.IsRubyApplicationFrame(); isApplication != tt.isApplication {
+				t.Fatalf(
+					"Expected %s frame but got %s frame",
+					frameType(tt.isApplication),
+					frameType(isApplication),
+				)
+			}
+		})
+	}
+}
+
+func TestSetRubyModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    Frame
+		expected Frame
+	}{
+		{
+			name:     "instance method",
+			frame:    Frame{Function: "ActiveRecord::Relation#load"},
+			expected: Frame{Function: "Relation#load", Module: "ActiveRecord"},
+		},
+		{
+			name:     "nested module",
+			frame:    Frame{Function: "ActiveRecord::Associations::Association#load_target"},
+			expected: Frame{Function: "Association#load_target", Module: "ActiveRecord::Associations"},
+		},
+		{
+			name:     "top level class",
+			frame:    Frame{Function: "JSON.parse"},
+			expected: Frame{Function: "JSON.parse"},
+		},
+		{
+			name:     "module already set",
+			frame:    Frame{Function: "Relation#load", Module: "ActiveRecord"},
+			expected: Frame{Function: "Relation#load", Module: "ActiveRecord"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frame.SetRubyModule()
+			if diff := testutil.Diff(tt.frame, tt.expected); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsCSharpApplicationFrame(t *testing.T) {
+	tests := []struct {
+		name          string
+		frame         Frame
+		isApplication bool
+	}{
+		{
+			name:          "application",
+			frame:         Frame{Function: "ProcessAsync", Module: "MyApp.Services.OrderService"},
+			isApplication: true,
+		},
+		{
+			name:          "system",
+			frame:         Frame{Function: "ReadAllText", Module: "System.IO.File"},
+			isApplication: false,
+		},
+		{
+			name:          "microsoft",
+			frame:         Frame{Function: "ExecuteReader", Module: "Microsoft.Data.SqlClient.SqlCommand"},
+			isApplication: false,
+		},
+		{
+			name:          "namespace with a system prefix",
+			frame:         Frame{Function: "Run", Module: "SystemMonitor.Worker"},
+			isApplication: true,
+		},
+		{
+			name:          "no module",
+			frame:         Frame{Function: "System.Threading.Thread.Sleep"},
+			isApplication: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isApplication := tt.frame.IsCSharpApplicationFrame(); isApplication != tt.isApplication {
+				t.Fatalf(
+					"Expected %s frame but got %s frame",
+					frameType(tt.isApplication),
+					frameType(isApplication),
+				)
+			}
+		})
+	}
+}
+
+func TestSetCSharpModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    Frame
+		expected Frame
+	}{
+		{
+			name:     "fully qualified method",
+			frame:    Frame{Function: "System.IO.File.ReadAllText(string)"},
+			expected: Frame{Function: "ReadAllText", Module: "System.IO.File"},
+		},
+		{
+			name:     "generic type",
+			frame:    Frame{Function: "System.Collections.Generic.List`1[System.Int32].Add(!0)"},
+			expected: Frame{Function: "Add", Module: "System.Collections.Generic.List`1[System.Int32]"},
+		},
+		{
+			name:     "constructor",
+			frame:    Frame{Function: "MyApp.OrderService..ctor()"},
+			expected: Frame{Function: ".ctor", Module: "MyApp.OrderService"},
+		},
+		{
+			name:     "async state machine",
+			frame:    Frame{Function: "MoveNext", Module: "MyApp.OrderService+<ProcessAsync>d__12"},
+			expected: Frame{Function: "ProcessAsync", Module: "MyApp.OrderService"},
+		},
+		{
+			name:     "fully qualified async state machine",
+			frame:    Frame{Function: "MyApp.OrderService.<ProcessAsync>d__12.MoveNext()"},
+			expected: Frame{Function: "ProcessAsync", Module: "MyApp.OrderService"},
+		},
+		{
+			name:     "module already set",
+			frame:    Frame{Function: "Run", Module: "MyApp.Worker"},
+			expected: Frame{Function: "Run", Module: "MyApp.Worker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frame.SetCSharpModule()
+			if diff := testutil.Diff(tt.frame, tt.expected); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIsGoApplicationFrame(t *testing.T) {
+	tests := []struct {
+		name          string
+		frame         Frame
+		mainModule    string
+		isApplication bool
+	}{
+		{
+			name:          "main package",
+			frame:         Frame{Function: "main", Module: "main"},
+			isApplication: true,
+		},
+		{
+			name: "main module",
+			frame: Frame{
+				Function: "(*Server).handle",
+				Module:   "github.com/getsentry/app/internal/server",
+				Path:     "/src/app/internal/server/server.go",
+			},
+			isApplication: true,
+		},
+		{
+			name: "main module without a dot",
+			frame: Frame{
+				Function: "Run",
+				Module:   "app/internal/worker",
+				Path:     "/home/user/app/internal/worker/worker.go",
+			},
+			isApplication: true,
+		},
+		{
+			name: "module cache",
+			frame: Frame{
+				Function: "(*Context).Next",
+				Module:   "github.com/gin-gonic/gin",
+				Path:     "/root/go/pkg/mod/github.com/gin-gonic/gin@v1.9.1/context.go",
+			},
+			isApplication: false,
+		},
+		{
+			name: "dependency built with trimpath",
+			frame: Frame{
+				Function: "(*Context).Next",
+				Module:   "github.com/gin-gonic/gin",
+				Path:     "github.com/gin-gonic/gin@v1.9.1/context.go",
+			},
+			isApplication: false,
+		},
+		{
+			name: "vendored dependency",
+			frame: Frame{
+				Function: "(*Context).Next",
+				Module:   "github.com/gin-gonic/gin",
+				Path:     "/src/app/vendor/github.com/gin-gonic/gin/context.go",
+			},
+			isApplication: false,
+		},
+		{
+			name: "goroot",
+			frame: Frame{
+				Function: "(*conn).serve",
+				Module:   "net/http",
+				Path:     "/usr/local/go/src/net/http/server.go",
+			},
+			isApplication: false,
+		},
+		{
+			name: "standard library built with trimpath",
+			frame: Frame{
+				Function: "(*conn).serve",
+				Module:   "net/http",
+				Path:     "net/http/server.go",
+			},
+			isApplication: false,
+		},
+		{
+			name:          "standard library without a path",
+			frame:         Frame{Function: "goexit", Module: "runtime"},
+			isApplication: false,
+		},
+		{
+			name:          "dependency without a path",
+			frame:         Frame{Function: "(*conn).query", Module: "github.com/lib/pq"},
+			isApplication: false,
+		},
+		{
+			name:          "main module without a path",
+			frame:         Frame{Function: "(*Server).handle", Module: "github.com/getsentry/app/internal/server"},
+			mainModule:    "github.com/getsentry/app",
+			isApplication: true,
+		},
+		{
+			name:          "main module root package",
+			frame:         Frame{Function: "Run", Module: "github.com/getsentry/app"},
+			mainModule:    "github.com/getsentry/app",
+			isApplication: true,
+		},
+		{
+			name:          "dependency with a main module",
+			frame:         Frame{Function: "(*conn).query", Module: "github.com/lib/pq"},
+			mainModule:    "github.com/getsentry/app",
+			isApplication: false,
+		},
+		{
+			name: "module sharing a prefix with the main module",
+			frame: Frame{
+				Function: "Run",
+				Module:   "github.com/getsentry/apptools",
+				Path:     "/src/apptools/run.go",
+			},
+			mainModule:    "github.com/getsentry/app",
+			isApplication: false,
+		},
+		{
+			name: "standard library with a main module",
+			frame: Frame{
+				Function: "(*conn).serve",
+				Module:   "net/http",
+				Path:     "/usr/local/go/src/net/http/server.go",
+			},
+			mainModule:    "app",
+			isApplication: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if isApplication := tt.frame.IsGoApplicationFrameForModule(tt.mainModule); isApplication != tt.isApplication {
+				t.Fatalf(
+					"Expected %s frame but got %s frame",
+					frameType(tt.isApplication),
+					frameType(isApplication),
+				)
+			}
+		})
+	}
+}
+
+func TestSetGoModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    Frame
+		expected Frame
+	}{
+		{
+			name:     "function",
+			frame:    Frame{Function: "main.main"},
+			expected: Frame{Function: "main", Module: "main"},
+		},
+		{
+			name:     "method",
+			frame:    Frame{Function: "github.com/org/repo/pkg.(*T).Method"},
+			expected: Frame{Function: "(*T).Method", Module: "github.com/org/repo/pkg"},
+		},
+		{
+			name:     "closure",
+			frame:    Frame{Function: "net/http.(*Server).Serve.func1"},
+			expected: Frame{Function: "(*Server).Serve.func1", Module: "net/http"},
+		},
+		{
+			name:     "escaped dot",
+			frame:    Frame{Function: "gopkg.in/yaml%2ev3.(*decoder).unmarshal"},
+			expected: Frame{Function: "(*decoder).unmarshal", Module: "gopkg.in/yaml.v3"},
+		},
+		{
+			name:     "generic function",
+			frame:    Frame{Function: "slices.SortFunc[go.shape.[]github.com/org/repo.T]"},
+			expected: Frame{Function: "SortFunc[go.shape.[]github.com/org/repo.T]", Module: "slices"},
+		},
+		{
+			name:     "module already set",
+			frame:    Frame{Function: "Unmarshal", Module: "encoding/json"},
+			expected: Frame{Function: "Unmarshal", Module: "encoding/json"},
+		},
+		{
+			name:     "no package",
+			frame:    Frame{Function: "runtime_main"},
+			expected: Frame{Function: "runtime_main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frame.SetGoModule()
+			if diff := testutil.Diff(tt.frame, tt.expected); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestWriteToHash(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -510,6 +1002,33 @@ func TestFullyQualifiedName(t *testing.T) {
 			},
 			expected: "threading.Condition.wait",
 		},
+		{
+			name:     "ruby",
+			platform: platform.Ruby,
+			frame: Frame{
+				Module:   "ActiveRecord",
+				Function: "Relation#load",
+			},
+			expected: "ActiveRecord::Relation#load",
+		},
+		{
+			name:     "csharp",
+			platform: platform.CSharp,
+			frame: Frame{
+				Module:   "MyApp.Services.OrderService",
+				Function: "ProcessAsync",
+			},
+			expected: "MyApp.Services.OrderService.ProcessAsync",
+		},
+		{
+			name:     "go",
+			platform: platform.Go,
+			frame: Frame{
+				Module:   "github.com/org/repo/pkg",
+				Function: "(*T).Method",
+			},
+			expected: "github.com/org/repo/pkg.(*T).Method",
+		},
 	}
 
 	for _, tt := range tests {
@@ -521,3 +1040,60 @@ func TestFullyQualifiedName(t *testing.T) {
 		})
 	}
 }
+
+func TestSetRubyPackage(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    Frame
+		expected string
+	}{
+		{
+			name:     "gem",
+			frame:    Frame{Function: "JSON.parse", Path: "/usr/local/bundle/gems/json-2.7.1/lib/json/common.rb"},
+			expected: "json",
+		},
+		{
+			name:     "gem with a dash and a platform",
+			frame:    Frame{Function: "Document.parse", Path: "/app/vendor/bundle/ruby/3.3.0/gems/nokogiri-html5-1.16.0-x86_64-linux/lib/nokogiri.rb"},
+			expected: "nokogiri-html5",
+		},
+		{
+			name:     "gem with a dash",
+			frame:    Frame{Function: "Attack#call", Module: "Rack", Path: "/usr/local/bundle/gems/rack-attack-6.7.0/lib/rack/attack.rb"},
+			expected: "rack-attack",
+		},
+		{
+			name:     "git gem",
+			frame:    Frame{Function: "Relation#load", Path: "/app/vendor/bundle/ruby/3.3.0/bundler/gems/rails-1a2b3c/activerecord/lib/active_record/relation.rb"},
+			expected: "rails",
+		},
+		{
+			name:     "standard library",
+			frame:    Frame{Function: "JSON.parse", Path: "/usr/local/lib/ruby/3.3.0/json/common.rb"},
+			expected: "json",
+		},
+		{
+			name:     "standard library file",
+			frame:    Frame{Function: "Set#add", Path: "/usr/local/lib/ruby/3.3.0/set.rb"},
+			expected: "set",
+		},
+		{
+			name:  "app",
+			frame: Frame{Function: "PostsController#index", Path: "/app/app/controllers/posts_controller.rb"},
+		},
+		{
+			name:     "package already set",
+			frame:    Frame{Function: "JSON.parse", Package: "oj", Path: "/usr/local/bundle/gems/json-2.7.1/lib/json/common.rb"},
+			expected: "oj",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.frame.SetRubyPackage()
+			if tt.frame.Package != tt.expected {
+				t.Fatalf("expected package %q, got %q", tt.expected, tt.frame.Package)
+			}
+		})
+	}
+}
@@ -4,52 +4,61 @@
 package frame
 
 var (
-	pythonStdlib = map[string]struct{}{
+	// pythonStdlib maps the modules of the standard library to the minor
+	// versions of Python 3 having them.
+	pythonStdlib = map[string]pythonVersions{
 		"__future__":       {},
 		"__hello__":        {},
-		"__phello__":       {},
-		"__phello__.foo":   {},
-		"_aix_support":     {},
+		"__phello__":       {added: 11},
+		"__phello__.foo":   {added: 11},
+		"_aix_support":     {added: 9},
+		"_android_support": {added: 13},
 		"_ast":             {},
-		"_bootlocale":      {},
-		"_bootsubprocess":  {},
-		"_collections_abc": {},
+		"_bootlocale":      {added: 5, removed: 10},
+		"_bootsubprocess":  {added: 9},
+		"_collections_abc": {added: 4},
+		"_colorize":        {added: 13},
 		"_compat_pickle":   {},
-		"_compression":     {},
-		"_dummy_thread":    {},
+		"_compression":     {added: 5},
+		"_dummy_thread":    {removed: 9},
+		"_ios_support":     {added: 13},
 		"_markupbase":      {},
+		"_opcode_metadata": {added: 13},
 		"_osx_support":     {},
-		"_py_abc":          {},
-		"_pydecimal":       {},
+		"_py_abc":          {added: 7},
+		"_pydatetime":      {added: 12},
+		"_pydecimal":       {added: 5},
 		"_pyio":            {},
-		"_sitebuiltins":    {},
+		"_pylong":          {added: 12},
+		"_pyrepl":          {added: 13},
+		"_sitebuiltins":    {added: 4},
 		"_strptime":        {},
 		"_thread":          {},
 		"_threading_local": {},
 		"_weakrefset":      {},
 		"abc":              {},
-		"aifc":             {},
+		"aifc":             {removed: 13},
 		"antigravity":      {},
 		"argparse":         {},
 		"array":            {},
 		"ast":              {},
-		"asynchat":         {},
-		"asyncio":          {},
-		"asyncore":         {},
+		"asynchat":         {removed: 12},
+		"asyncio":          {added: 4},
+		"asyncore":         {removed: 12},
 		"atexit":           {},
-		"audioop":          {},
+		"audioop":          {removed: 13},
 		"base64":           {},
 		"bdb":              {},
 		"binascii":         {},
-		"binhex":           {},
+		"binhex":           {removed: 11},
 		"bisect":           {},
 		"builtins":         {},
 		"bz2":              {},
 		"cProfile":         {},
 		"calendar":         {},
-		"cgi":              {},
-		"cgitb":            {},
-		"chunk":            {},
+		"cgi":              {removed: 13},
+		"cgitb":            {removed: 13},
+		"chunk":            {removed: 13},
 		"cmath":            {},
 		"cmd":              {},
 		"code":             {},
@@ -61,34 +70,34 @@ var (
 		"concurrent":       {},
 		"configparser":     {},
 		"contextlib":       {},
-		"contextvars":      {},
+		"contextvars":      {added: 7},
 		"copy":             {},
 		"copyreg":          {},
-		"crypt":            {},
+		"crypt":            {removed: 13},
 		"csv":              {},
 		"ctypes":           {},
 		"curses":           {},
-		"dataclasses":      {},
+		"dataclasses":      {added: 7},
 		"datetime":         {},
 		"dbm":              {},
 		"decimal":          {},
 		"difflib":          {},
 		"dis":              {},
-		"distutils":        {},
+		"distutils":        {removed: 12},
 		"doctest":          {},
-		"dummy_threading":  {},
+		"dummy_threading":  {removed: 9},
 		"email":            {},
 		"encodings":        {},
-		"ensurepip":        {},
-		"enum":             {},
+		"ensurepip":        {added: 4},
+		"enum":             {added: 4},
 		"errno":            {},
 		"faulthandler":     {},
 		"fcntl":            {},
 		"filecmp":          {},
 		"fileinput":        {},
 		"fnmatch":          {},
-		"formatter":        {},
-		"fpectl":           {},
+		"formatter":        {removed: 10},
+		"fpectl":           {removed: 7},
 		"fractions":        {},
 		"ftplib":           {},
 		"functools":        {},
@@ -98,7 +107,7 @@ var (
 		"getpass":          {},
 		"gettext":          {},
 		"glob":             {},
-		"graphlib":         {},
+		"graphlib":         {added: 9},
 		"grp":              {},
 		"gzip":             {},
 		"hashlib":          {},
@@ -108,8 +117,8 @@ var (
 		"http":             {},
 		"idlelib":          {},
 		"imaplib":          {},
-		"imghdr":           {},
-		"imp":              {},
+		"imghdr":           {removed: 13},
+		"imp":              {removed: 12},
 		"importlib":        {},
 		"inspect":          {},
 		"io":               {},
@@ -117,26 +126,26 @@ var (
 		"itertools":        {},
 		"json":             {},
 		"keyword":          {},
-		"lib2to3":          {},
+		"lib2to3":          {removed: 13},
 		"linecache":        {},
 		"locale":           {},
 		"logging":          {},
 		"lzma":             {},
-		"macpath":          {},
-		"macurl2path":      {},
+		"macpath":          {removed: 8},
+		"macurl2path":      {removed: 7},
 		"mailbox":          {},
-		"mailcap":          {},
+		"mailcap":          {removed: 13},
 		"marshal":          {},
 		"math":             {},
 		"mimetypes":        {},
 		"mmap":             {},
 		"modulefinder":     {},
-		"msilib":           {},
+		"msilib":           {removed: 13},
 		"msvcrt":           {},
 		"multiprocessing":  {},
 		"netrc":            {},
-		"nis":              {},
-		"nntplib":          {},
+		"nis":              {removed: 13},
+		"nntplib":          {removed: 13},
 		"ntpath":           {},
 		"nturl2path":       {},
 		"numbers":          {},
@@ -144,14 +153,14 @@ var (
 		"operator":         {},
 		"optparse":         {},
 		"os":               {},
-		"os2emxpath":       {},
-		"ossaudiodev":      {},
-		"parser":           {},
-		"pathlib":          {},
+		"os2emxpath":       {removed: 4},
+		"ossaudiodev":      {removed: 13},
+		"parser":           {removed: 10},
+		"pathlib":          {added: 4},
 		"pdb":              {},
 		"pickle":           {},
 		"pickletools":      {},
-		"pipes":            {},
+		"pipes":            {removed: 13},
 		"pkgutil":          {},
 		"platform":         {},
 		"plistlib":         {},
@@ -177,20 +186,20 @@ var (
 		"rlcompleter":      {},
 		"runpy":            {},
 		"sched":            {},
-		"secrets":          {},
+		"secrets":          {added: 6},
 		"select":           {},
-		"selectors":        {},
+		"selectors":        {added: 4},
 		"shelve":           {},
 		"shlex":            {},
 		"shutil":           {},
 		"signal":           {},
 		"site":             {},
-		"smtpd":            {},
+		"smtpd":            {removed: 12},
 		"smtplib":          {},
-		"sndhdr":           {},
+		"sndhdr":           {removed: 13},
 		"socket":           {},
 		"socketserver":     {},
-		"spwd":             {},
+		"spwd":             {removed: 13},
 		"sqlite3":          {},
 		"sre":              {},
 		"sre_compile":      {},
@@ -198,20 +207,20 @@ var (
 		"sre_parse":        {},
 		"ssl":              {},
 		"stat":             {},
-		"statistics":       {},
+		"statistics":       {added: 4},
 		"string":           {},
 		"stringprep":       {},
 		"struct":           {},
 		"subprocess":       {},
-		"sunau":            {},
-		"symbol":           {},
+		"sunau":            {removed: 13},
+		"symbol":           {removed: 10},
 		"symtable":         {},
 		"sys":              {},
 		"sysconfig":        {},
 		"syslog":           {},
 		"tabnanny":         {},
 		"tarfile":          {},
-		"telnetlib":        {},
+		"telnetlib":        {removed: 13},
 		"tempfile":         {},
 		"termios":          {},
 		"test":             {},
@@ -223,19 +232,19 @@ var (
 		"tkinter":          {},
 		"token":            {},
 		"tokenize":         {},
-		"tomllib":          {},
+		"tomllib":          {added: 11},
 		"trace":            {},
 		"traceback":        {},
-		"tracemalloc":      {},
+		"tracemalloc":      {added: 4},
 		"tty":              {},
 		"turtle":           {},
 		"turtledemo":       {},
 		"types":            {},
-		"typing":           {},
+		"typing":           {added: 5},
 		"unicodedata":      {},
 		"unittest":         {},
 		"urllib":           {},
-		"uu":               {},
+		"uu":               {removed: 13},
 		"uuid":             {},
 		"venv":             {},
 		"warnings":         {},
@@ -245,13 +254,13 @@ var (
 		"winreg":           {},
 		"winsound":         {},
 		"wsgiref":          {},
-		"xdrlib":           {},
+		"xdrlib":           {removed: 13},
 		"xml":              {},
 		"xmlrpc":           {},
-		"zipapp":           {},
+		"zipapp":           {added: 5},
 		"zipfile":          {},
 		"zipimport":        {},
 		"zlib":             {},
-		"zoneinfo":         {},
+		"zoneinfo":         {added: 9},
 	}
 )
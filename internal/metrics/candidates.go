@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/utils"
+	"gocloud.dev/blob"
+)
+
+// ReadCandidates reads the profiles and chunks of the candidates with the
+// read jobs, calling addProfile or addChunk with the call trees of each one.
+// Objects not found are skipped and other errors reported to Sentry, only a
+// deadline exceeded stopping the reads.
+func ReadCandidates(
+	ctx context.Context,
+	storage *blob.Bucket,
+	organizationID uint64,
+	transactionProfileCandidates []utils.TransactionProfileCandidate,
+	continuousProfileCandidates []utils.ContinuousProfileCandidate,
+	jobs chan storageutil.ReadJob,
+	addProfile func(result profile.ReadJobResult, callTrees map[uint64][]*nodetree.Node),
+	addChunk func(result chunk.ReadJobResult, callTrees map[string][]*nodetree.Node),
+) error {
+	hub := sentry.GetHubFromContext(ctx)
+	captureException := func(err error) {
+		if hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	numCandidates := len(transactionProfileCandidates) + len(continuousProfileCandidates)
+
+	results := make(chan storageutil.ReadJobResult, numCandidates)
+	defer close(results)
+
+	for _, candidate := range transactionProfileCandidates {
+		jobs <- profile.ReadJob{
+			Ctx:            ctx,
+			OrganizationID: organizationID,
+			ProjectID:      candidate.ProjectID,
+			ProfileID:      candidate.ProfileID,
+			Storage:        storage,
+			Result:         results,
+		}
+	}
+
+	for _, candidate := range continuousProfileCandidates {
+		jobs <- chunk.ReadJob{
+			Ctx:            ctx,
+			OrganizationID: organizationID,
+			ProjectID:      candidate.ProjectID,
+			ProfilerID:     candidate.ProfilerID,
+			ChunkID:        candidate.ChunkID,
+			TransactionID:  candidate.TransactionID,
+			ThreadID:       candidate.ThreadID,
+			Start:          candidate.Start,
+			End:            candidate.End,
+			Storage:        storage,
+			Result:         results,
+		}
+	}
+
+	for i := 0; i < numCandidates; i++ {
+		res := <-results
+
+		err := res.Error()
+		if err != nil {
+			if errors.Is(err, storageutil.ErrObjectNotFound) {
+				continue
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			captureException(err)
+			continue
+		}
+
+		if result, ok := res.(profile.ReadJobResult); ok {
+			profileCallTrees, err := result.Profile.CallTrees()
+			if err != nil {
+				captureException(err)
+				continue
+			}
+			addProfile(result, profileCallTrees)
+		} else if result, ok := res.(chunk.ReadJobResult); ok {
+			chunkCallTrees, err := result.Chunk.CallTrees(result.ThreadID)
+			if err != nil {
+				captureException(err)
+				continue
+			}
+			addChunk(result, chunkCallTrees)
+		} else {
+			// this should never happen
+			return errors.New("unexpected result from storage")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"gocloud.dev/blob/memblob"
+
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+func TestReadCandidatesSkipsInvalidChunks(t *testing.T) {
+	// no hub in the context, errors can't be reported
+	ctx := context.Background()
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	chunks := map[string][]int{
+		"valid":   {0},
+		"invalid": {1},
+	}
+	for id, stack := range chunks {
+		c := chunk.New(&chunk.SampleChunk{
+			ID:             id,
+			OrganizationID: 1,
+			ProjectID:      2,
+			ProfilerID:     "profiler",
+			Platform:       platform.Python,
+			Version:        "2",
+			Profile: chunk.SampleData{
+				Frames:  []frame.Frame{{Function: "main"}},
+				Samples: []chunk.Sample{{StackID: 0, ThreadID: "1", Timestamp: 1}, {StackID: 0, ThreadID: "1", Timestamp: 2}},
+				Stacks:  [][]int{stack},
+			},
+		})
+		if err := storageutil.CompressedWrite(ctx, bucket, c.StoragePath(), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs := make(chan storageutil.ReadJob)
+	defer close(jobs)
+	go storageutil.ReadWorker(jobs)
+
+	var read []string
+	err := ReadCandidates(
+		ctx,
+		bucket,
+		1,
+		nil,
+		[]utils.ContinuousProfileCandidate{
+			{ProjectID: 2, ProfilerID: "profiler", ChunkID: "valid"},
+			{ProjectID: 2, ProfilerID: "profiler", ChunkID: "invalid"},
+			{ProjectID: 2, ProfilerID: "profiler", ChunkID: "missing"},
+		},
+		jobs,
+		func(profile.ReadJobResult, map[uint64][]*nodetree.Node) {
+			t.Fatal("unexpected profile")
+		},
+		func(result chunk.ReadJobResult, _ map[string][]*nodetree.Node) {
+			read = append(read, result.Chunk.GetID())
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(read) != 1 || read[0] != "valid" {
+		t.Fatalf("expected only the valid chunk to be read, got %v", read)
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+type (
+	exampleWithValue struct {
+		Example    utils.ExampleMetadata
+		SelfTimeNS uint64
+	}
+
+	// examplesHeap is a min-heap of examples ordered by self time, used to
+	// keep the K slowest examples seen for a function.
+	examplesHeap []exampleWithValue
+)
+
+func (h examplesHeap) Len() int {
+	return len(h)
+}
+
+func (h examplesHeap) Less(i, j int) bool {
+	return h[i].SelfTimeNS < h[j].SelfTimeNS
+}
+
+func (h examplesHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *examplesHeap) Push(item any) {
+	*h = append(*h, item.(exampleWithValue))
+}
+
+func (h *examplesHeap) Pop() any {
+	old := *h
+	n := len(old) - 1
+	item := old[n]
+	*h = old[:n]
+	return item
+}
+
+// add keeps the example if it's one of the k slowest seen so far.
+func (h *examplesHeap) add(example exampleWithValue, k int) {
+	if h.Len() < k {
+		heap.Push(h, example)
+		return
+	}
+	if k == 0 || (*h)[0].SelfTimeNS >= example.SelfTimeNS {
+		return
+	}
+	(*h)[0] = example
+	heap.Fix(h, 0)
+}
+
+// sorted returns the examples, slowest first.
+func (h examplesHeap) sorted() []utils.ExampleMetadata {
+	examples := make([]exampleWithValue, len(h))
+	copy(examples, h)
+	sort.SliceStable(examples, func(i, j int) bool {
+		return examples[i].SelfTimeNS > examples[j].SelfTimeNS
+	})
+	sorted := make([]utils.ExampleMetadata, 0, len(examples))
+	for _, e := range examples {
+		sorted = append(sorted, e.Example)
+	}
+	return sorted
+}
+
+// addExample records an example for a function, keeping both the K slowest
+// examples and a uniform sample of K examples (reservoir sampling).
+func (fm *FunctionsMetadata) addExample(example utils.ExampleMetadata, selfTimeNS uint64, k uint) {
+	if selfTimeNS > fm.MaxVal || fm.ExamplesSeen == 0 {
+		fm.MaxVal = selfTimeNS
+		fm.Worst = example
+	}
+	fm.WorstExamples.add(exampleWithValue{Example: example, SelfTimeNS: selfTimeNS}, int(k))
+	fm.ExamplesSeen++
+	if len(fm.Examples) < int(k) {
+		fm.Examples = append(fm.Examples, example)
+		return
+	}
+	if i := rand.Int63n(int64(fm.ExamplesSeen)); i < int64(k) {
+		fm.Examples[i] = example
+	}
+}
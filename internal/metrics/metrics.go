@@ -7,7 +7,6 @@ import (
 	"sort"
 	"strconv"
 
-	"github.com/getsentry/sentry-go"
 	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/profile"
@@ -18,9 +17,13 @@ import (
 
 type (
 	FunctionsMetadata struct {
-		MaxVal   uint64
-		Worst    utils.ExampleMetadata
+		MaxVal uint64
+		Worst  utils.ExampleMetadata
+		// Examples is a uniform sample of the examples seen.
 		Examples []utils.ExampleMetadata
+		// WorstExamples holds the slowest examples seen, by self time.
+		WorstExamples examplesHeap
+		ExamplesSeen  uint64
 	}
 
 	Aggregator struct {
@@ -50,24 +53,13 @@ func (ma *Aggregator) AddFunctions(functions []nodetree.CallTreeFunction, result
 			fn.SampleCount += f.SampleCount
 			fn.SelfTimesNS = append(fn.SelfTimesNS, f.SelfTimesNS...)
 			fn.SumSelfTimeNS += f.SumSelfTimeNS
-			funcMetadata := ma.FunctionsMetadata[f.Fingerprint]
-			if f.SumSelfTimeNS > funcMetadata.MaxVal {
-				funcMetadata.MaxVal = f.SumSelfTimeNS
-				funcMetadata.Worst = resultMetadata
-			}
-			if len(funcMetadata.Examples) < int(ma.MaxNumOfExamples) {
-				funcMetadata.Examples = append(funcMetadata.Examples, resultMetadata)
-			}
-			ma.FunctionsMetadata[f.Fingerprint] = funcMetadata
 			ma.CallTreeFunctions[f.Fingerprint] = fn
 		} else {
 			ma.CallTreeFunctions[f.Fingerprint] = f
-			ma.FunctionsMetadata[f.Fingerprint] = FunctionsMetadata{
-				MaxVal:   f.SumSelfTimeNS,
-				Worst:    resultMetadata,
-				Examples: []utils.ExampleMetadata{resultMetadata},
-			}
 		}
+		funcMetadata := ma.FunctionsMetadata[f.Fingerprint]
+		funcMetadata.addExample(resultMetadata, f.SumSelfTimeNS, ma.MaxNumOfExamples)
+		ma.FunctionsMetadata[f.Fingerprint] = funcMetadata
 	}
 }
 
@@ -78,22 +70,25 @@ func (ma *Aggregator) ToMetrics() []utils.FunctionMetrics {
 		sort.Slice(f.SelfTimesNS, func(i, j int) bool {
 			return f.SelfTimesNS[i] < f.SelfTimesNS[j]
 		})
+		p50, _ := quantile(f.SelfTimesNS, 0.50)
 		p75, _ := quantile(f.SelfTimesNS, 0.75)
 		p95, _ := quantile(f.SelfTimesNS, 0.95)
 		p99, _ := quantile(f.SelfTimesNS, 0.99)
 		metrics = append(metrics, utils.FunctionMetrics{
-			Name:        f.Function,
-			Package:     f.Package,
-			Fingerprint: uint64(f.Fingerprint),
-			InApp:       f.InApp,
-			P75:         p75,
-			P95:         p95,
-			P99:         p99,
-			Avg:         float64(f.SumSelfTimeNS) / float64(len(f.SelfTimesNS)),
-			Sum:         f.SumSelfTimeNS,
-			Count:       uint64(f.SampleCount),
-			Worst:       ma.FunctionsMetadata[f.Fingerprint].Worst,
-			Examples:    ma.FunctionsMetadata[f.Fingerprint].Examples,
+			Name:          f.Function,
+			Package:       f.Package,
+			Fingerprint:   uint64(f.Fingerprint),
+			InApp:         f.InApp,
+			P50:           p50,
+			P75:           p75,
+			P95:           p95,
+			P99:           p99,
+			Avg:           float64(f.SumSelfTimeNS) / float64(len(f.SelfTimesNS)),
+			Sum:           f.SumSelfTimeNS,
+			Count:         uint64(f.SampleCount),
+			Worst:         ma.FunctionsMetadata[f.Fingerprint].Worst,
+			Examples:      ma.FunctionsMetadata[f.Fingerprint].Examples,
+			WorstExamples: ma.FunctionsMetadata[f.Fingerprint].WorstExamples.sorted(),
 		})
 	}
 	sort.Slice(metrics, func(i, j int) bool {
@@ -198,75 +193,20 @@ func (ma *Aggregator) GetMetricsFromCandidates(
 	continuousProfileCandidates []utils.ContinuousProfileCandidate,
 	jobs chan storageutil.ReadJob,
 ) ([]utils.FunctionMetrics, error) {
-	hub := sentry.GetHubFromContext(ctx)
-
-	numCandidates := len(transactionProfileCandidates) + len(continuousProfileCandidates)
-
-	results := make(chan storageutil.ReadJobResult, numCandidates)
-	defer close(results)
-
-	for _, candidate := range transactionProfileCandidates {
-		jobs <- profile.ReadJob{
-			Ctx:            ctx,
-			OrganizationID: organizationID,
-			ProjectID:      candidate.ProjectID,
-			ProfileID:      candidate.ProfileID,
-			Storage:        storage,
-			Result:         results,
-		}
-	}
-
-	for _, candidate := range continuousProfileCandidates {
-		jobs <- chunk.ReadJob{
-			Ctx:            ctx,
-			OrganizationID: organizationID,
-			ProjectID:      candidate.ProjectID,
-			ProfilerID:     candidate.ProfilerID,
-			ChunkID:        candidate.ChunkID,
-			TransactionID:  candidate.TransactionID,
-			ThreadID:       candidate.ThreadID,
-			Start:          candidate.Start,
-			End:            candidate.End,
-			Storage:        storage,
-			Result:         results,
-		}
-	}
-
-	for i := 0; i < numCandidates; i++ {
-		res := <-results
-
-		err := res.Error()
-		if err != nil {
-			if errors.Is(err, storageutil.ErrObjectNotFound) {
-				continue
-			}
-			if errors.Is(err, context.DeadlineExceeded) {
-				return nil, err
-			}
-			if hub != nil {
-				hub.CaptureException(err)
-			}
-			continue
-		}
-
-		var resultMetadata utils.ExampleMetadata
-		if result, ok := res.(profile.ReadJobResult); ok {
-			profileCallTrees, err := result.Profile.CallTrees()
-			if err != nil {
-				hub.CaptureException(err)
-				continue
-			}
-			resultMetadata = utils.NewExampleFromProfileID(result.Profile.ProjectID(), result.Profile.ID())
+	err := ReadCandidates(
+		ctx,
+		storage,
+		organizationID,
+		transactionProfileCandidates,
+		continuousProfileCandidates,
+		jobs,
+		func(result profile.ReadJobResult, profileCallTrees map[uint64][]*nodetree.Node) {
+			resultMetadata := utils.NewExampleFromProfileID(result.Profile.ProjectID(), result.Profile.ID())
 			functions := CapAndFilterFunctions(ExtractFunctionsFromCallTrees(profileCallTrees, ma.MinDepth), int(ma.MaxUniqueFunctions), true)
 			ma.AddFunctions(functions, resultMetadata)
-		} else if result, ok := res.(chunk.ReadJobResult); ok {
-			chunkCallTrees, err := result.Chunk.CallTrees(result.ThreadID)
-			if err != nil {
-				hub.CaptureException(err)
-				continue
-			}
-
-			resultMetadata = utils.NewExampleFromProfilerChunk(
+		},
+		func(result chunk.ReadJobResult, chunkCallTrees map[string][]*nodetree.Node) {
+			resultMetadata := utils.NewExampleFromProfilerChunk(
 				result.Chunk.GetProjectID(),
 				result.Chunk.GetProfilerID(),
 				result.Chunk.GetID(),
@@ -277,11 +217,10 @@ func (ma *Aggregator) GetMetricsFromCandidates(
 			)
 			functions := CapAndFilterFunctions(ExtractFunctionsFromCallTrees(chunkCallTrees, ma.MinDepth), int(ma.MaxUniqueFunctions), true)
 			ma.AddFunctions(functions, resultMetadata)
-		} else {
-			// this should never happen
-			return nil, errors.New("unexpected result from storage")
-		}
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
-
 	return ma.ToMetrics(), nil
 }
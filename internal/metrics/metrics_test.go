@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/getsentry/vroom/internal/nodetree"
@@ -53,11 +54,21 @@ func TestAggregatorAddFunctions(t *testing.T) {
 						MaxVal:   40,
 						Worst:    utils.ExampleMetadata{ProfileID: "1"},
 						Examples: []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "2"}},
+						WorstExamples: examplesHeap{
+							{Example: utils.ExampleMetadata{ProfileID: "1"}, SelfTimeNS: 40},
+							{Example: utils.ExampleMetadata{ProfileID: "2"}, SelfTimeNS: 40},
+						},
+						ExamplesSeen: 2,
 					},
 					1: {
 						MaxVal:   105,
 						Worst:    utils.ExampleMetadata{ProfileID: "1"},
 						Examples: []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "2"}},
+						WorstExamples: examplesHeap{
+							{Example: utils.ExampleMetadata{ProfileID: "1"}, SelfTimeNS: 105},
+							{Example: utils.ExampleMetadata{ProfileID: "2"}, SelfTimeNS: 105},
+						},
+						ExamplesSeen: 2,
 					},
 				}, // end want
 			},
@@ -107,11 +118,18 @@ func TestAggregatorToMetrics(t *testing.T) {
 						MaxVal:   66,
 						Worst:    utils.ExampleMetadata{ProfileID: "1"},
 						Examples: []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "2"}},
+						WorstExamples: examplesHeap{
+							{Example: utils.ExampleMetadata{ProfileID: "2"}, SelfTimeNS: 20},
+							{Example: utils.ExampleMetadata{ProfileID: "1"}, SelfTimeNS: 66},
+						},
 					},
 					1: {
 						MaxVal:   66,
 						Worst:    utils.ExampleMetadata{ProfileID: "3"},
 						Examples: []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "3"}},
+						WorstExamples: examplesHeap{
+							{Example: utils.ExampleMetadata{ProfileID: "3"}, SelfTimeNS: 66},
+						},
 					},
 				}, //end functionsMetadata
 			}, //end Aggregator
@@ -119,6 +137,7 @@ func TestAggregatorToMetrics(t *testing.T) {
 				{
 					Name:        "a",
 					Fingerprint: 0,
+					P50:         7,
 					P75:         10,
 					P95:         20,
 					P99:         20,
@@ -127,10 +146,15 @@ func TestAggregatorToMetrics(t *testing.T) {
 					Avg:         float64(66) / float64(9),
 					Worst:       utils.ExampleMetadata{ProfileID: "1"},
 					Examples:    []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "2"}},
+					WorstExamples: []utils.ExampleMetadata{
+						{ProfileID: "1"},
+						{ProfileID: "2"},
+					},
 				},
 				{
 					Name:        "b",
 					Fingerprint: 1,
+					P50:         7,
 					P75:         10,
 					P95:         20,
 					P99:         20,
@@ -139,6 +163,9 @@ func TestAggregatorToMetrics(t *testing.T) {
 					Avg:         float64(66) / float64(9),
 					Worst:       utils.ExampleMetadata{ProfileID: "3"},
 					Examples:    []utils.ExampleMetadata{{ProfileID: "1"}, {ProfileID: "3"}},
+					WorstExamples: []utils.ExampleMetadata{
+						{ProfileID: "3"},
+					},
 				},
 			}, //want
 		},
@@ -154,3 +181,38 @@ func TestAggregatorToMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestAggregatorKeepsWorstExamples(t *testing.T) {
+	ma := NewAggregator(100, 2, 0)
+	for i, selfTimeNS := range []uint64{30, 10, 50, 20, 40} {
+		ma.AddFunctions(
+			[]nodetree.CallTreeFunction{
+				{
+					Function:      "a",
+					Fingerprint:   0,
+					SelfTimesNS:   []uint64{selfTimeNS},
+					SumSelfTimeNS: selfTimeNS,
+				},
+			},
+			utils.ExampleMetadata{ProfileID: strconv.Itoa(i)},
+		)
+	}
+
+	metrics := ma.ToMetrics()
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 function, got %d", len(metrics))
+	}
+	want := []utils.ExampleMetadata{{ProfileID: "2"}, {ProfileID: "4"}}
+	if diff := testutil.Diff(metrics[0].WorstExamples, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if diff := testutil.Diff(metrics[0].Worst, want[0]); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if len(metrics[0].Examples) != 2 {
+		t.Fatalf("expected 2 typical examples, got %d", len(metrics[0].Examples))
+	}
+	if ma.FunctionsMetadata[0].ExamplesSeen != 5 {
+		t.Fatalf("expected 5 examples seen, got %d", ma.FunctionsMetadata[0].ExamplesSeen)
+	}
+}
@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/getsentry/vroom/internal/chunk"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/profile"
+	"github.com/getsentry/vroom/internal/storageutil"
+	"github.com/getsentry/vroom/internal/utils"
+	"gocloud.dev/blob"
+)
+
+type (
+	TimeSeriesBucket struct {
+		// Timestamp is the start of the bucket, in seconds since epoch.
+		Timestamp uint64                 `json:"timestamp"`
+		Metrics   *utils.FunctionMetrics `json:"metrics"`
+	}
+
+	FunctionTimeSeries struct {
+		Fingerprint uint64             `json:"fingerprint"`
+		Name        string             `json:"name"`
+		Package     string             `json:"package"`
+		Buckets     []TimeSeriesBucket `json:"buckets"`
+	}
+
+	// TimeSeriesAggregator aggregates functions per time bucket
+	// for a fixed set of fingerprints.
+	TimeSeriesAggregator struct {
+		// Interval is the width of a bucket, in seconds.
+		Interval         uint64
+		MinDepth         uint
+		MaxNumOfExamples uint
+		Fingerprints     map[uint32]struct{}
+		Buckets          map[uint64]*Aggregator
+	}
+)
+
+// MaxTimeSeriesBuckets is the maximum number of buckets of a time series
+// filled in over a range.
+const MaxTimeSeriesBuckets = 10000
+
+// MaxTimeSeriesInterval is the widest bucket of a time series, in seconds,
+// keeping bucket boundaries in nanoseconds far from overflowing.
+const MaxTimeSeriesInterval = 366 * 24 * 60 * 60
+
+var ErrInvalidTimeSeriesRange = errors.New("invalid time series range")
+
+// CheckTimeSeriesRange returns an error if the interval is 0 or wider than
+// MaxTimeSeriesInterval or, when a range (in seconds) is set, if it's empty
+// or would be split in more than MaxTimeSeriesBuckets buckets of the
+// interval.
+func CheckTimeSeriesRange(start, end, interval uint64) error {
+	if interval == 0 || interval > MaxTimeSeriesInterval {
+		return fmt.Errorf("%w: invalid interval", ErrInvalidTimeSeriesRange)
+	}
+	if start == 0 && end == 0 {
+		return nil
+	}
+	if end <= start {
+		return ErrInvalidTimeSeriesRange
+	}
+	if (end-start)/interval >= MaxTimeSeriesBuckets {
+		return fmt.Errorf("%w: more than %d buckets", ErrInvalidTimeSeriesRange, MaxTimeSeriesBuckets)
+	}
+	return nil
+}
+
+func NewTimeSeriesAggregator(
+	fingerprints []uint32,
+	interval uint64,
+	maxNumOfExamples uint,
+	minDepth uint,
+) TimeSeriesAggregator {
+	ta := TimeSeriesAggregator{
+		Interval:         interval,
+		MinDepth:         minDepth,
+		MaxNumOfExamples: maxNumOfExamples,
+		Fingerprints:     make(map[uint32]struct{}, len(fingerprints)),
+		Buckets:          make(map[uint64]*Aggregator),
+	}
+	for _, fingerprint := range fingerprints {
+		ta.Fingerprints[fingerprint] = struct{}{}
+	}
+	return ta
+}
+
+// bucket returns the start of the bucket containing the timestamp,
+// timestamp being expressed in seconds.
+func (ta *TimeSeriesAggregator) bucket(timestamp uint64) uint64 {
+	return timestamp - timestamp%ta.Interval
+}
+
+// AddFunctions adds functions matching the requested fingerprints
+// to the bucket containing the timestamp (in seconds).
+func (ta *TimeSeriesAggregator) AddFunctions(
+	timestamp uint64,
+	functions []nodetree.CallTreeFunction,
+	resultMetadata utils.ExampleMetadata,
+) {
+	filtered := make([]nodetree.CallTreeFunction, 0, len(ta.Fingerprints))
+	for _, f := range functions {
+		if _, ok := ta.Fingerprints[f.Fingerprint]; ok {
+			filtered = append(filtered, f)
+		}
+	}
+	if len(filtered) == 0 {
+		return
+	}
+	b := ta.bucket(timestamp)
+	ma, ok := ta.Buckets[b]
+	if !ok {
+		a := NewAggregator(uint(len(ta.Fingerprints)), ta.MaxNumOfExamples, ta.MinDepth)
+		ma = &a
+		ta.Buckets[b] = ma
+	}
+	ma.AddFunctions(filtered, resultMetadata)
+}
+
+// AddChunkCallTrees splits call trees of a continuous profile chunk on
+// bucket boundaries and adds the functions found in each slice to the
+// matching bucket. Timestamps are expressed in nanoseconds.
+func (ta *TimeSeriesAggregator) AddChunkCallTrees(
+	callTrees map[string][]*nodetree.Node,
+	startNS, endNS uint64,
+	resultMetadata utils.ExampleMetadata,
+) {
+	if ta.Interval == 0 || ta.Interval > MaxTimeSeriesInterval {
+		return
+	}
+	intervalNS := ta.Interval * uint64(time.Second)
+	for bucketStartNS := startNS - startNS%intervalNS; bucketStartNS < endNS; bucketStartNS += intervalNS {
+		sliceStart := max(bucketStartNS, startNS)
+		sliceEnd := min(bucketStartNS+intervalNS, endNS)
+		slicedCallTrees := make(map[string][]*nodetree.Node, len(callTrees))
+		for tid, callTreesForThread := range callTrees {
+			if sliced := sliceNodes(callTreesForThread, sliceStart, sliceEnd); len(sliced) > 0 {
+				slicedCallTrees[tid] = sliced
+			}
+		}
+		if len(slicedCallTrees) == 0 {
+			continue
+		}
+		ta.AddFunctions(
+			bucketStartNS/uint64(time.Second),
+			ExtractFunctionsFromCallTrees(slicedCallTrees, ta.MinDepth),
+			resultMetadata,
+		)
+	}
+}
+
+// sliceNodes returns a copy of the nodes, and their children, restricted
+// to the [start, end) interval. Nodes passed as arguments are not modified.
+func sliceNodes(nodes []*nodetree.Node, start, end uint64) []*nodetree.Node {
+	var sliced []*nodetree.Node
+	for _, n := range nodes {
+		if n.EndNS <= start || n.StartNS >= end {
+			continue
+		}
+		c := *n
+		c.StartNS = max(n.StartNS, start)
+		c.EndNS = min(n.EndNS, end)
+		c.DurationNS = c.EndNS - c.StartNS
+		if c.DurationNS != n.DurationNS {
+			// estimate the number of samples in the slice from the share of
+			// the node it covers, whatever the sampling frequency was
+			sampleCount := int(math.Ceil(float64(n.SampleCount) * float64(c.DurationNS) / float64(n.DurationNS)))
+			c.SampleCount = min(sampleCount, n.SampleCount)
+		}
+		c.Children = sliceNodes(n.Children, start, end)
+		sliced = append(sliced, &c)
+	}
+	return sliced
+}
+
+// ToTimeSeries returns one time series per fingerprint found, sorted by
+// fingerprint. If start and end (in seconds) are set and form a valid
+// range, buckets outside of the range are dropped and empty buckets are
+// filled in.
+func (ta *TimeSeriesAggregator) ToTimeSeries(start, end uint64) []FunctionTimeSeries {
+	timestamps := make([]uint64, 0, len(ta.Buckets))
+	if start > 0 && CheckTimeSeriesRange(start, end, ta.Interval) == nil {
+		for b := ta.bucket(start); b < end; b += ta.Interval {
+			timestamps = append(timestamps, b)
+		}
+	} else {
+		for b := range ta.Buckets {
+			timestamps = append(timestamps, b)
+		}
+		sort.Slice(timestamps, func(i, j int) bool {
+			return timestamps[i] < timestamps[j]
+		})
+	}
+
+	metricsByBucket := make(map[uint64]map[uint64]utils.FunctionMetrics, len(ta.Buckets))
+	series := make(map[uint64]*FunctionTimeSeries)
+	for _, b := range timestamps {
+		ma, ok := ta.Buckets[b]
+		if !ok {
+			continue
+		}
+		metricsByBucket[b] = make(map[uint64]utils.FunctionMetrics)
+		for _, m := range ma.ToMetrics() {
+			metricsByBucket[b][m.Fingerprint] = m
+			if _, ok := series[m.Fingerprint]; !ok {
+				series[m.Fingerprint] = &FunctionTimeSeries{
+					Fingerprint: m.Fingerprint,
+					Name:        m.Name,
+					Package:     m.Package,
+				}
+			}
+		}
+	}
+
+	timeSeries := make([]FunctionTimeSeries, 0, len(series))
+	for fingerprint, s := range series {
+		s.Buckets = make([]TimeSeriesBucket, 0, len(timestamps))
+		for _, b := range timestamps {
+			bucket := TimeSeriesBucket{Timestamp: b}
+			if m, ok := metricsByBucket[b][fingerprint]; ok {
+				bucket.Metrics = &m
+			}
+			s.Buckets = append(s.Buckets, bucket)
+		}
+		timeSeries = append(timeSeries, *s)
+	}
+	sort.Slice(timeSeries, func(i, j int) bool {
+		return timeSeries[i].Fingerprint < timeSeries[j].Fingerprint
+	})
+	return timeSeries
+}
+
+func (ta *TimeSeriesAggregator) GetTimeSeriesFromCandidates(
+	ctx context.Context,
+	storage *blob.Bucket,
+	organizationID uint64,
+	transactionProfileCandidates []utils.TransactionProfileCandidate,
+	continuousProfileCandidates []utils.ContinuousProfileCandidate,
+	jobs chan storageutil.ReadJob,
+) error {
+	return ReadCandidates(
+		ctx,
+		storage,
+		organizationID,
+		transactionProfileCandidates,
+		continuousProfileCandidates,
+		jobs,
+		func(result profile.ReadJobResult, profileCallTrees map[uint64][]*nodetree.Node) {
+			ta.AddFunctions(
+				uint64(result.Profile.Timestamp().Unix()),
+				ExtractFunctionsFromCallTrees(profileCallTrees, ta.MinDepth),
+				utils.NewExampleFromProfileID(result.Profile.ProjectID(), result.Profile.ID()),
+			)
+		},
+		func(result chunk.ReadJobResult, chunkCallTrees map[string][]*nodetree.Node) {
+			startNS := uint64(result.Chunk.StartTimestamp() * 1e9)
+			endNS := uint64(result.Chunk.EndTimestamp() * 1e9)
+			if result.Start > 0 && result.End > 0 {
+				startNS = max(startNS, result.Start)
+				endNS = min(endNS, result.End)
+			}
+			ta.AddChunkCallTrees(
+				chunkCallTrees,
+				startNS,
+				endNS,
+				utils.NewExampleFromProfilerChunk(
+					result.Chunk.GetProjectID(),
+					result.Chunk.GetProfilerID(),
+					result.Chunk.GetID(),
+					result.TransactionID,
+					result.ThreadID,
+					result.Start,
+					result.End,
+				),
+			)
+		},
+	)
+}
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/testutil"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+func TestTimeSeriesAggregatorToTimeSeries(t *testing.T) {
+	functions := []nodetree.CallTreeFunction{
+		{
+			Function:      "a",
+			Fingerprint:   1,
+			SelfTimesNS:   []uint64{10},
+			SumSelfTimeNS: 10,
+			SampleCount:   2,
+		},
+		{
+			Function:      "b",
+			Fingerprint:   2,
+			SelfTimesNS:   []uint64{20},
+			SumSelfTimeNS: 20,
+			SampleCount:   2,
+		},
+	}
+
+	tests := []struct {
+		name  string
+		start uint64
+		end   uint64
+		want  []FunctionTimeSeries
+	}{
+		{
+			name: "only non empty buckets",
+			want: []FunctionTimeSeries{
+				{
+					Fingerprint: 1,
+					Name:        "a",
+					Buckets: []TimeSeriesBucket{
+						{
+							Timestamp: 60,
+							Metrics: &utils.FunctionMetrics{
+								Name:        "a",
+								Fingerprint: 1,
+								P50:         10,
+								P75:         10,
+								P95:         10,
+								P99:         10,
+								Avg:         10,
+								Sum:         10,
+								Count:       2,
+								Worst:       utils.ExampleMetadata{ProfileID: "1"},
+								Examples:    []utils.ExampleMetadata{{ProfileID: "1"}},
+								WorstExamples: []utils.ExampleMetadata{
+									{ProfileID: "1"},
+								},
+							},
+						},
+						{
+							Timestamp: 120,
+							Metrics: &utils.FunctionMetrics{
+								Name:        "a",
+								Fingerprint: 1,
+								P50:         10,
+								P75:         10,
+								P95:         10,
+								P99:         10,
+								Avg:         10,
+								Sum:         10,
+								Count:       2,
+								Worst:       utils.ExampleMetadata{ProfileID: "2"},
+								Examples:    []utils.ExampleMetadata{{ProfileID: "2"}},
+								WorstExamples: []utils.ExampleMetadata{
+									{ProfileID: "2"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "fill empty buckets",
+			start: 30,
+			end:   180,
+			want: []FunctionTimeSeries{
+				{
+					Fingerprint: 1,
+					Name:        "a",
+					Buckets: []TimeSeriesBucket{
+						{Timestamp: 0},
+						{
+							Timestamp: 60,
+							Metrics: &utils.FunctionMetrics{
+								Name:        "a",
+								Fingerprint: 1,
+								P50:         10,
+								P75:         10,
+								P95:         10,
+								P99:         10,
+								Avg:         10,
+								Sum:         10,
+								Count:       2,
+								Worst:       utils.ExampleMetadata{ProfileID: "1"},
+								Examples:    []utils.ExampleMetadata{{ProfileID: "1"}},
+								WorstExamples: []utils.ExampleMetadata{
+									{ProfileID: "1"},
+								},
+							},
+						},
+						{
+							Timestamp: 120,
+							Metrics: &utils.FunctionMetrics{
+								Name:        "a",
+								Fingerprint: 1,
+								P50:         10,
+								P75:         10,
+								P95:         10,
+								P99:         10,
+								Avg:         10,
+								Sum:         10,
+								Count:       2,
+								Worst:       utils.ExampleMetadata{ProfileID: "2"},
+								Examples:    []utils.ExampleMetadata{{ProfileID: "2"}},
+								WorstExamples: []utils.ExampleMetadata{
+									{ProfileID: "2"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := NewTimeSeriesAggregator([]uint32{1}, 60, 5, 0)
+			ta.AddFunctions(65, functions, utils.ExampleMetadata{ProfileID: "1"})
+			ta.AddFunctions(179, functions, utils.ExampleMetadata{ProfileID: "2"})
+			if diff := testutil.Diff(ta.ToTimeSeries(tt.start, tt.end), tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckTimeSeriesRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    uint64
+		end      uint64
+		interval uint64
+		valid    bool
+	}{
+		{name: "valid range", start: 60, end: 180, interval: 60, valid: true},
+		{name: "end before start", start: 180, end: 60, interval: 60},
+		{name: "empty range", start: 60, end: 60, interval: 60},
+		{name: "too many buckets", start: 1, end: 1 << 40, interval: 1},
+		{name: "no range", interval: 60, valid: true},
+		{name: "no interval", start: 60, end: 180},
+		{name: "interval overflowing in nanoseconds", interval: 1 << 55},
+		{name: "widest interval", interval: MaxTimeSeriesInterval, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTimeSeriesRange(tt.start, tt.end, tt.interval)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesAggregatorAddChunkCallTrees(t *testing.T) {
+	second := uint64(time.Second)
+	fn := func(name string, start, end uint64, children ...*nodetree.Node) *nodetree.Node {
+		n := nodetree.NodeFromFrame(frame.Frame{Function: name, InApp: &testutil.True}, start, end, 0)
+		n.SampleCount = int((end - start) / uint64(10*time.Millisecond))
+		n.Children = children
+		return n
+	}
+	callTrees := map[string][]*nodetree.Node{
+		"1": {
+			fn("root", 50*second, 70*second,
+				fn("a", 50*second, 70*second),
+			),
+		},
+	}
+	fingerprint := frame.Frame{Function: "a"}.Fingerprint()
+
+	ta := NewTimeSeriesAggregator([]uint32{fingerprint}, 60, 5, 1)
+	ta.AddChunkCallTrees(callTrees, 50*second, 70*second, utils.ExampleMetadata{ProfileID: "1"})
+
+	want := map[uint64]uint64{
+		0:  10 * second,
+		60: 10 * second,
+	}
+	got := make(map[uint64]uint64)
+	for b, ma := range ta.Buckets {
+		got[b] = ma.CallTreeFunctions[fingerprint].SumSelfTimeNS
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if callTrees["1"][0].DurationNS != 20*second {
+		t.Fatalf("call trees should not be modified")
+	}
+}
+
+func TestSliceNodesSampleCount(t *testing.T) {
+	// sampled every 20ms
+	n := nodetree.NodeFromFrame(frame.Frame{Function: "a"}, 0, uint64(100*time.Millisecond), 0)
+	n.SampleCount = 5
+
+	tests := []struct {
+		name  string
+		start uint64
+		end   uint64
+		want  int
+	}{
+		{name: "whole node", start: 0, end: uint64(time.Second), want: 5},
+		{name: "first samples", start: 0, end: uint64(40 * time.Millisecond), want: 2},
+		{name: "partial sample", start: uint64(50 * time.Millisecond), end: uint64(time.Second), want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sliced := sliceNodes([]*nodetree.Node{n}, tt.start, tt.end)
+			if len(sliced) != 1 || sliced[0].SampleCount != tt.want {
+				t.Fatalf("expected %d samples, got %+v", tt.want, sliced)
+			}
+		})
+	}
+}
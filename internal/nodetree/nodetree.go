@@ -221,6 +221,11 @@ func shouldAggregateFrame(frame frame.Frame) bool {
 		return false
 	}
 
+	// frames excluded by a stack trace rule
+	if frame.Data.ExcludeFromGroup {
+		return false
+	}
+
 	// hard coded list of functions that we should not aggregate by
 	if functionDenyList, exists := functionDenyListByPlatform[frame.Platform]; exists {
 		if _, exists = functionDenyList[frameFunction]; exists {
@@ -1,6 +1,8 @@
 package occurrence
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +40,78 @@ type (
 		SampleThreshold int
 	}
 
+	// DetectJavaFrameOptions matches JVM frames on their fully qualified
+	// method name, whether the class is part of the function name, as
+	// for Android, or set as the module of the frame.
+	DetectJavaFrameOptions struct {
+		ActiveThreadOnly   bool
+		DurationThreshold  time.Duration
+		FunctionsByPackage map[string]map[string]Category
+
+		// SampleThreshold is the minimum number of samples in which we need to
+		// detect the frame in order to create an occurrence.
+		SampleThreshold int
+	}
+
+	// DetectInAppFrameOptions matches any application frame spending
+	// too much time in its own code, regardless of its name.
+	DetectInAppFrameOptions struct {
+		ActiveThreadOnly bool
+		// Category is the category of the occurrences, SlowFunction by default.
+		Category Category
+		// SelfTimeThreshold is the minimum self time of the frame.
+		SelfTimeThreshold time.Duration
+		// MinProfileRatio is the minimum ratio of the profile duration
+		// spent in the frame itself, between 0 and 1.
+		MinProfileRatio float64
+		// MaxOccurrences caps the number of occurrences created per
+		// profile, keeping the slowest frames. No cap if 0.
+		MaxOccurrences int
+
+		// SampleThreshold is the minimum number of samples in which we need to
+		// detect the frame in order to create an occurrence.
+		SampleThreshold int
+	}
+
+	// DetectRepeatedFrameOptions matches frames called several times by
+	// the same caller, the closest application frame above them, like
+	// records loaded one at a time in a loop.
+	DetectRepeatedFrameOptions struct {
+		ActiveThreadOnly bool
+		// CallThreshold is the minimum number of calls under the same
+		// caller.
+		CallThreshold int
+		// DurationThreshold is the minimum duration of all the calls.
+		DurationThreshold  time.Duration
+		FunctionsByPackage map[string]map[string]Category
+	}
+
+	// inAppFrameDetector checks nodes against DetectInAppFrameOptions
+	// for a given profile.
+	inAppFrameDetector struct {
+		options           DetectInAppFrameOptions
+		profileDurationNS uint64
+	}
+
+	// profileBoundOptions are options needing information about the
+	// profile or chunk to check nodes.
+	profileBoundOptions interface {
+		forSource(src source) DetectFrameOptions
+	}
+
+	// limitedOptions are options capping the number of occurrences
+	// created per profile.
+	limitedOptions interface {
+		maxOccurrences() int
+	}
+
+	// exhaustiveOptions are options checking all the children of a node
+	// instead of stopping at the first one matching, for several siblings
+	// to be reported.
+	exhaustiveOptions interface {
+		checkAllChildren() bool
+	}
+
 	nodeKey struct {
 		Package  string
 		Function string
@@ -47,10 +121,32 @@ type (
 		Category   Category
 		Node       nodetree.Node
 		StackTrace []frame.Frame
+
+		// GroupByFrameFingerprint groups occurrences with the fingerprint
+		// of the frame instead of its package and function name.
+		GroupByFrameFingerprint bool
+		// SelfTimeNS is the time spent in the frame itself, only set by the
+		// in-app detector.
+		SelfTimeNS uint64
+
+		// ThreadID and ThreadName identify the thread the node was found in.
+		ThreadID   string
+		ThreadName string
+		// Ranges are the time ranges during which the function was running,
+		// in the same time base as the call trees.
+		Ranges []timeRange
+		// Subtree is the aggregated call tree beneath the node.
+		Subtree []*evidenceNode
+
+		// CallCount and Caller are only set for repeated calls, Caller being
+		// the name of the frame calling the function.
+		CallCount int
+		Caller    string
 	}
 )
 
 const (
+	AssociationLoad  Category = "association_load"
 	Base64Decode     Category = "base64_decode"
 	Base64Encode     Category = "base64_encode"
 	Compression      Category = "compression"
@@ -69,6 +165,7 @@ const (
 	MLModelInference Category = "ml_model_inference"
 	MLModelLoad      Category = "ml_model_load"
 	Regex            Category = "regex"
+	SlowFunction     Category = "slow_function"
 	SQL              Category = "sql"
 	SourceContext    Category = "source_context"
 	ThreadWait       Category = "thread_wait"
@@ -77,6 +174,21 @@ const (
 	ViewRender       Category = "view_render"
 	ViewUpdate       Category = "view_update"
 	XPC              Category = "xpc"
+
+	// Categories of the work blocking the request threads or event loops of
+	// backend services, titled apart from the mobile main thread ones.
+	ServerBase64Decode  Category = "server_base64_decode"
+	ServerBase64Encode  Category = "server_base64_encode"
+	ServerCompression   Category = "server_compression"
+	ServerDecompression Category = "server_decompression"
+	ServerFileRead      Category = "server_file_read"
+	ServerFileWrite     Category = "server_file_write"
+	ServerHTTP          Category = "server_http"
+	ServerJSONDecode    Category = "server_json_decode"
+	ServerJSONEncode    Category = "server_json_encode"
+	ServerRegex         Category = "server_regex"
+	ServerSQL           Category = "server_sql"
+	ServerThreadWait    Category = "server_thread_wait"
 )
 
 func (options DetectExactFrameOptions) onlyCheckActiveThread() bool {
@@ -129,11 +241,7 @@ func (options DetectAndroidFrameOptions) checkNode(n *nodetree.Node) *nodeInfo {
 	// Android frame names contain the deobfuscated signature.
 	// Here we strip away the argument and return types to only
 	// match on the the package + function name.
-	name := n.Name
-	parts := strings.SplitN(name, "(", 2)
-	if len(parts) > 0 {
-		name = parts[0]
-	}
+	name := stripJavaSignature(n.Name)
 
 	// Check if we need to detect that function.
 	category, exists := functions[name]
@@ -162,6 +270,236 @@ func (options DetectAndroidFrameOptions) checkNode(n *nodetree.Node) *nodeInfo {
 	return &ni
 }
 
+func (options DetectJavaFrameOptions) onlyCheckActiveThread() bool {
+	return options.ActiveThreadOnly
+}
+
+func (options DetectJavaFrameOptions) checkNode(n *nodetree.Node) *nodeInfo {
+	name := stripJavaSignature(n.Name)
+	if n.Package != "" && !strings.HasPrefix(name, n.Package+".") {
+		name = n.Package + "." + name
+	}
+
+	// Check if we have a list of functions associated to the package.
+	functions, exists := options.FunctionsByPackage[javaPackageName(name)]
+	if !exists {
+		return nil
+	}
+
+	// Check if we need to detect that function.
+	category, exists := functions[name]
+	if !exists {
+		return nil
+	}
+
+	// Check if it's above the duration threshold.
+	if n.DurationNS < uint64(options.DurationThreshold) {
+		return nil
+	}
+
+	// Check if it's above the sample threshold.
+	if n.SampleCount < options.SampleThreshold {
+		return nil
+	}
+
+	ni := nodeInfo{
+		Category: category,
+		Node:     *n,
+	}
+	ni.Node.Children = nil
+	return &ni
+}
+
+func (options DetectInAppFrameOptions) onlyCheckActiveThread() bool {
+	return options.ActiveThreadOnly
+}
+
+func (options DetectInAppFrameOptions) checkNode(n *nodetree.Node) *nodeInfo {
+	return inAppFrameDetector{options: options}.checkNode(n)
+}
+
+func (options DetectInAppFrameOptions) forSource(src source) DetectFrameOptions {
+	return inAppFrameDetector{
+		options:           options,
+		profileDurationNS: src.DurationNS,
+	}
+}
+
+func (options DetectInAppFrameOptions) maxOccurrences() int {
+	return options.MaxOccurrences
+}
+
+func (options DetectInAppFrameOptions) checkAllChildren() bool {
+	return true
+}
+
+func (d inAppFrameDetector) onlyCheckActiveThread() bool {
+	return d.options.ActiveThreadOnly
+}
+
+func (d inAppFrameDetector) maxOccurrences() int {
+	return d.options.MaxOccurrences
+}
+
+// checkAllChildren returns true since any application frame can be slow,
+// not only the first one found under a parent.
+func (d inAppFrameDetector) checkAllChildren() bool {
+	return true
+}
+
+func (d inAppFrameDetector) checkNode(n *nodetree.Node) *nodeInfo {
+	if !n.IsApplication {
+		return nil
+	}
+
+	var childrenDurationNS uint64
+	for _, c := range n.Children {
+		childrenDurationNS += c.DurationNS
+	}
+	if childrenDurationNS >= n.DurationNS {
+		return nil
+	}
+	selfTimeNS := n.DurationNS - childrenDurationNS
+
+	// Check if it's above the self time threshold.
+	if selfTimeNS < uint64(d.options.SelfTimeThreshold) {
+		return nil
+	}
+
+	// Check if it's above the share of the profile.
+	if d.options.MinProfileRatio > 0 && d.profileDurationNS > 0 &&
+		float64(selfTimeNS)/float64(d.profileDurationNS) < d.options.MinProfileRatio {
+		return nil
+	}
+
+	// Check if it's above the sample threshold.
+	if n.SampleCount < d.options.SampleThreshold {
+		return nil
+	}
+
+	category := d.options.Category
+	if category == "" {
+		category = SlowFunction
+	}
+	ni := nodeInfo{
+		Category:                category,
+		Node:                    *n,
+		GroupByFrameFingerprint: true,
+		SelfTimeNS:              selfTimeNS,
+	}
+	ni.Node.Children = nil
+	return &ni
+}
+
+func (options DetectRepeatedFrameOptions) onlyCheckActiveThread() bool {
+	return options.ActiveThreadOnly
+}
+
+// checkNode checks an application frame, counting the calls to each
+// function it makes through system frames. The stack trace of the node
+// returned goes from the caller to its first call.
+func (options DetectRepeatedFrameOptions) checkNode(n *nodetree.Node) *nodeInfo {
+	if !n.IsApplication {
+		return nil
+	}
+
+	calls := make(map[nodeKey][]*nodetree.Node)
+	var keys []nodeKey
+	for _, c := range n.Children {
+		options.collectCalls(c, calls, &keys)
+	}
+
+	for _, k := range keys {
+		matches := calls[k]
+		// Check if it's above the call threshold.
+		if len(matches) < options.CallThreshold {
+			continue
+		}
+
+		var durationNS uint64
+		var sampleCount int
+		for _, m := range matches {
+			durationNS += m.DurationNS
+			sampleCount += m.SampleCount
+		}
+		// Check if it's above the duration threshold.
+		if durationNS < uint64(options.DurationThreshold) {
+			continue
+		}
+
+		ni := nodeInfo{
+			Category:   options.FunctionsByPackage[k.Package][k.Function],
+			Node:       *matches[0],
+			StackTrace: callPath(n, matches[0]),
+			CallCount:  len(matches),
+			Caller:     n.Name,
+		}
+		ni.Node.Children = nil
+		ni.Node.DurationNS = durationNS
+		ni.Node.SampleCount = sampleCount
+		return &ni
+	}
+	return nil
+}
+
+// collectCalls collects the outermost nodes matching the options beneath a
+// node, without going through application frames, since they are the
+// callers of their own calls.
+func (options DetectRepeatedFrameOptions) collectCalls(
+	n *nodetree.Node,
+	calls map[nodeKey][]*nodetree.Node,
+	keys *[]nodeKey,
+) {
+	if _, exists := options.FunctionsByPackage[n.Package][n.Name]; exists {
+		k := nodeKey{Package: n.Package, Function: n.Name}
+		if _, exists := calls[k]; !exists {
+			*keys = append(*keys, k)
+		}
+		calls[k] = append(calls[k], n)
+		return
+	}
+	if n.IsApplication {
+		return
+	}
+	for _, c := range n.Children {
+		options.collectCalls(c, calls, keys)
+	}
+}
+
+// callPath returns the frames beneath a node leading to one of its
+// descendants, the descendant included.
+func callPath(n, descendant *nodetree.Node) []frame.Frame {
+	for _, c := range n.Children {
+		if c == descendant {
+			return []frame.Frame{c.ToFrame()}
+		}
+		if path := callPath(c, descendant); path != nil {
+			return append([]frame.Frame{c.ToFrame()}, path...)
+		}
+	}
+	return nil
+}
+
+// stripJavaSignature removes the argument and return types from a method name.
+func stripJavaSignature(name string) string {
+	name, _, _ = strings.Cut(name, "(")
+	return name
+}
+
+// javaPackageName returns the package of a fully qualified method name
+// (com.example.Class.method returns com.example).
+func javaPackageName(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i == -1 {
+		return ""
+	}
+	i = strings.LastIndex(name[:i], ".")
+	if i == -1 {
+		return ""
+	}
+	return name[:i]
+}
+
 var detectFrameJobs = map[platform.Platform][]DetectFrameOptions{
 	platform.Node: {
 		DetectExactFrameOptions{
@@ -464,6 +802,331 @@ var detectFrameJobs = map[platform.Platform][]DetectFrameOptions{
 			},
 		},
 	},
+	platform.Python: {
+		DetectExactFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 100 * time.Millisecond,
+			SampleThreshold:   5,
+			FunctionsByPackage: map[string]map[string]Category{
+				"django.db.backends.utils": {
+					"CursorDebugWrapper.execute":           ServerSQL,
+					"CursorWrapper.execute":                ServerSQL,
+					"CursorWrapper.executemany":            ServerSQL,
+					"CursorWrapper._execute_with_wrappers": ServerSQL,
+				},
+				"django.db.models.sql.compiler": {
+					"SQLCompiler.execute_sql": ServerSQL,
+					"execute_sql":             ServerSQL,
+				},
+				"http.client": {
+					"HTTPConnection.getresponse": ServerHTTP,
+				},
+				"requests.api": {
+					"delete":  ServerHTTP,
+					"get":     ServerHTTP,
+					"head":    ServerHTTP,
+					"options": ServerHTTP,
+					"patch":   ServerHTTP,
+					"post":    ServerHTTP,
+					"put":     ServerHTTP,
+					"request": ServerHTTP,
+				},
+				"requests.sessions": {
+					"Session.request": ServerHTTP,
+					"Session.send":    ServerHTTP,
+				},
+				"sqlalchemy.engine.base": {
+					"Connection._execute_context": ServerSQL,
+					"Connection.exec_driver_sql":  ServerSQL,
+					"Connection.execute":          ServerSQL,
+				},
+				"sqlalchemy.orm.session": {
+					"Session.execute": ServerSQL,
+				},
+				"time": {
+					"sleep": ServerThreadWait,
+				},
+				"urllib.request": {
+					"urlopen": ServerHTTP,
+				},
+				"urllib3.connectionpool": {
+					"HTTPConnectionPool.urlopen": ServerHTTP,
+				},
+				"urllib3.poolmanager": {
+					"PoolManager.urlopen": ServerHTTP,
+				},
+			},
+		},
+		DetectExactFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 40 * time.Millisecond,
+			SampleThreshold:   3,
+			FunctionsByPackage: map[string]map[string]Category{
+				"json": {
+					"load":  ServerJSONDecode,
+					"loads": ServerJSONDecode,
+				},
+				"json.decoder": {
+					"JSONDecoder.decode":     ServerJSONDecode,
+					"JSONDecoder.raw_decode": ServerJSONDecode,
+				},
+				"orjson": {
+					"loads": ServerJSONDecode,
+				},
+				"re": {
+					"_compile": ServerRegex,
+					"compile":  ServerRegex,
+				},
+				"ujson": {
+					"loads": ServerJSONDecode,
+				},
+			},
+		},
+	},
+	platform.Go: {
+		DetectExactFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 100 * time.Millisecond,
+			SampleThreshold:   5,
+			FunctionsByPackage: map[string]map[string]Category{
+				"database/sql": {
+					"(*DB).ExecContext":       ServerSQL,
+					"(*DB).QueryContext":      ServerSQL,
+					"(*DB).QueryRowContext":   ServerSQL,
+					"(*Stmt).ExecContext":     ServerSQL,
+					"(*Stmt).QueryContext":    ServerSQL,
+					"(*Tx).ExecContext":       ServerSQL,
+					"(*Tx).QueryContext":      ServerSQL,
+					"(*Tx).QueryRowContext":   ServerSQL,
+					"(*Conn).ExecContext":     ServerSQL,
+					"(*Conn).QueryContext":    ServerSQL,
+					"(*Conn).QueryRowContext": ServerSQL,
+				},
+				"net/http": {
+					"(*Client).Do": ServerHTTP,
+				},
+				"os": {
+					"ReadFile":  ServerFileRead,
+					"WriteFile": ServerFileWrite,
+				},
+				"time": {
+					"Sleep": ServerThreadWait,
+				},
+			},
+		},
+		DetectExactFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 40 * time.Millisecond,
+			SampleThreshold:   3,
+			FunctionsByPackage: map[string]map[string]Category{
+				"compress/gzip": {
+					"(*Reader).Read":  ServerDecompression,
+					"(*Writer).Write": ServerCompression,
+				},
+				"encoding/base64": {
+					"(*Encoding).DecodeString":   ServerBase64Decode,
+					"(*Encoding).EncodeToString": ServerBase64Encode,
+				},
+				"encoding/json": {
+					"(*Decoder).Decode": ServerJSONDecode,
+					"(*Encoder).Encode": ServerJSONEncode,
+					"Marshal":           ServerJSONEncode,
+					"MarshalIndent":     ServerJSONEncode,
+					"Unmarshal":         ServerJSONDecode,
+				},
+				"regexp": {
+					"Compile":     ServerRegex,
+					"MustCompile": ServerRegex,
+					"MatchString": ServerRegex,
+				},
+			},
+		},
+	},
+	// .NET frames have the type as module, async methods being named after
+	// themselves instead of their state machine.
+	platform.CSharp: {
+		DetectExactFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 16 * time.Millisecond,
+			SampleThreshold:   1,
+			FunctionsByPackage: map[string]map[string]Category{
+				"System.IO.File": {
+					"AppendAllText": ServerFileWrite,
+					"ReadAllBytes":  ServerFileRead,
+					"ReadAllLines":  ServerFileRead,
+					"ReadAllText":   ServerFileRead,
+					"WriteAllBytes": ServerFileWrite,
+					"WriteAllLines": ServerFileWrite,
+					"WriteAllText":  ServerFileWrite,
+				},
+				"System.IO.FileStream": {
+					"Flush": ServerFileWrite,
+					"Read":  ServerFileRead,
+					"Write": ServerFileWrite,
+				},
+				"System.IO.StreamReader": {
+					"ReadLine":  ServerFileRead,
+					"ReadToEnd": ServerFileRead,
+				},
+				"System.IO.StreamWriter": {
+					"Flush": ServerFileWrite,
+					"Write": ServerFileWrite,
+				},
+				"System.Net.HttpWebRequest": {
+					"GetResponse": ServerHTTP,
+				},
+				"System.Net.Http.HttpClient": {
+					"Send": ServerHTTP,
+				},
+				"System.Net.WebClient": {
+					"DownloadData":   ServerHTTP,
+					"DownloadFile":   ServerHTTP,
+					"DownloadString": ServerHTTP,
+					"UploadData":     ServerHTTP,
+					"UploadString":   ServerHTTP,
+				},
+				"Microsoft.Data.SqlClient.SqlCommand": {
+					"ExecuteNonQuery": ServerSQL,
+					"ExecuteReader":   ServerSQL,
+					"ExecuteScalar":   ServerSQL,
+				},
+				"Microsoft.Data.Sqlite.SqliteCommand": {
+					"ExecuteNonQuery": ServerSQL,
+					"ExecuteReader":   ServerSQL,
+					"ExecuteScalar":   ServerSQL,
+				},
+				"System.Data.SqlClient.SqlCommand": {
+					"ExecuteNonQuery": ServerSQL,
+					"ExecuteReader":   ServerSQL,
+					"ExecuteScalar":   ServerSQL,
+				},
+				// blocking on a task instead of awaiting it
+				"System.Threading.Tasks.Task": {
+					"Wait": ServerThreadWait,
+				},
+				"System.Threading.Tasks.Task`1": {
+					"get_Result": ServerThreadWait,
+				},
+				"System.Threading.Thread": {
+					"Sleep": ServerThreadWait,
+				},
+			},
+		},
+	},
+	// Ruby frames are split like the SDK does, "ActiveRecord::Associations"
+	// and "Association#load_target" for
+	// "ActiveRecord::Associations::Association#load_target", the package
+	// being the gem for methods of top level classes. Requests are served by
+	// worker threads, so all threads are checked.
+	platform.Ruby: {
+		// associations loaded lazily, one query per record when done in a
+		// loop
+		DetectRepeatedFrameOptions{
+			CallThreshold:     5,
+			DurationThreshold: 50 * time.Millisecond,
+			FunctionsByPackage: map[string]map[string]Category{
+				"ActiveRecord::Associations": {
+					"Association#load_target":           AssociationLoad,
+					"CollectionAssociation#load_target": AssociationLoad,
+				},
+			},
+		},
+		DetectExactFrameOptions{
+			DurationThreshold: 40 * time.Millisecond,
+			SampleThreshold:   3,
+			FunctionsByPackage: map[string]map[string]Category{
+				"ActiveSupport": {
+					"JSON.decode": ServerJSONDecode,
+				},
+				"JSON::Ext": {
+					"Parser#parse": ServerJSONDecode,
+					"Parser.parse": ServerJSONDecode,
+				},
+				"json": {
+					"JSON.load":  ServerJSONDecode,
+					"JSON.parse": ServerJSONDecode,
+				},
+				"oj": {
+					"Oj.load": ServerJSONDecode,
+				},
+			},
+		},
+	},
+	// Backend JVM profiles have the request thread as active thread.
+	platform.Java: {
+		DetectJavaFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 100 * time.Millisecond,
+			SampleThreshold:   5,
+			FunctionsByPackage: map[string]map[string]Category{
+				"com.mysql.cj.jdbc": {
+					"com.mysql.cj.jdbc.ClientPreparedStatement.execute":       ServerSQL,
+					"com.mysql.cj.jdbc.ClientPreparedStatement.executeQuery":  ServerSQL,
+					"com.mysql.cj.jdbc.ClientPreparedStatement.executeUpdate": ServerSQL,
+					"com.mysql.cj.jdbc.StatementImpl.execute":                 ServerSQL,
+					"com.mysql.cj.jdbc.StatementImpl.executeQuery":            ServerSQL,
+					"com.mysql.cj.jdbc.StatementImpl.executeUpdate":           ServerSQL,
+				},
+				"java.lang": {
+					"java.lang.Thread.sleep": ServerThreadWait,
+				},
+				"java.net": {
+					"java.net.HttpURLConnection.getResponseCode": ServerHTTP,
+				},
+				"java.net.http": {
+					"java.net.http.HttpClient.send": ServerHTTP,
+				},
+				"jdk.internal.net.http": {
+					"jdk.internal.net.http.HttpClientImpl.send": ServerHTTP,
+				},
+				"okhttp3": {
+					"okhttp3.RealCall.execute": ServerHTTP,
+				},
+				"okhttp3.internal.connection": {
+					"okhttp3.internal.connection.RealCall.execute": ServerHTTP,
+				},
+				"org.apache.http.impl.client": {
+					"org.apache.http.impl.client.CloseableHttpClient.execute": ServerHTTP,
+				},
+				"org.apache.hc.client5.http.impl.classic": {
+					"org.apache.hc.client5.http.impl.classic.CloseableHttpClient.execute": ServerHTTP,
+				},
+				"org.postgresql.jdbc": {
+					"org.postgresql.jdbc.PgPreparedStatement.execute":       ServerSQL,
+					"org.postgresql.jdbc.PgPreparedStatement.executeQuery":  ServerSQL,
+					"org.postgresql.jdbc.PgPreparedStatement.executeUpdate": ServerSQL,
+					"org.postgresql.jdbc.PgStatement.execute":               ServerSQL,
+					"org.postgresql.jdbc.PgStatement.executeQuery":          ServerSQL,
+					"org.postgresql.jdbc.PgStatement.executeUpdate":         ServerSQL,
+				},
+				"org.springframework.web.client": {
+					"org.springframework.web.client.RestTemplate.doExecute": ServerHTTP,
+				},
+				"sun.net.www.protocol.http": {
+					"sun.net.www.protocol.http.HttpURLConnection.getInputStream": ServerHTTP,
+				},
+			},
+		},
+		DetectJavaFrameOptions{
+			ActiveThreadOnly:  true,
+			DurationThreshold: 40 * time.Millisecond,
+			SampleThreshold:   3,
+			FunctionsByPackage: map[string]map[string]Category{
+				"com.fasterxml.jackson.databind": {
+					"com.fasterxml.jackson.databind.ObjectMapper.readTree":  ServerJSONDecode,
+					"com.fasterxml.jackson.databind.ObjectMapper.readValue": ServerJSONDecode,
+					"com.fasterxml.jackson.databind.ObjectReader.readValue": ServerJSONDecode,
+				},
+				"com.google.gson": {
+					"com.google.gson.Gson.fromJson": ServerJSONDecode,
+				},
+				"java.util.regex": {
+					"java.util.regex.Pattern.compile": ServerRegex,
+					"java.util.regex.Pattern.matches": ServerRegex,
+				},
+			},
+		},
+	},
 }
 
 // DetectFrames detects occurrence of an issue based by matching frames of the profile on a list of frames.
@@ -473,32 +1136,93 @@ func detectFrame(
 	options DetectFrameOptions,
 	occurrences *[]*Occurrence,
 ) {
-	// List nodes matching criteria
-	nodes := make(map[nodeKey]nodeInfo)
+	callTrees := make(map[string][]*nodetree.Node, len(callTreesPerThreadID))
+	for tid, trees := range callTreesPerThreadID {
+		callTrees[strconv.FormatUint(tid, 10)] = trees
+	}
+	threadName := func(tid string) string {
+		id, _ := strconv.ParseUint(tid, 10, 64)
+		return p.ThreadName(id)
+	}
+	detectFrameInThreads(sourceFromProfile(p), callTrees, threadName, options, occurrences)
+}
+
+// detectFrameInThreads looks for nodes matching the options in the call
+// trees of each thread, or only in the ones of the main thread of the source
+// if the options ask for it, and creates an occurrence for each of them.
+func detectFrameInThreads(
+	src source,
+	callTreesPerThreadID map[string][]*nodetree.Node,
+	threadName func(threadID string) string,
+	options DetectFrameOptions,
+	occurrences *[]*Occurrence,
+) {
+	if o, ok := options.(profileBoundOptions); ok {
+		options = o.forSource(src)
+	}
+
+	// List nodes matching criteria, per thread since a function found on
+	// several threads is described for each of them.
+	var matches []nodeInfo
 	if options.onlyCheckActiveThread() {
-		callTrees, exists := callTreesPerThreadID[p.Transaction().ActiveThreadID]
+		callTrees, exists := callTreesPerThreadID[src.MainThreadID]
 		if !exists {
 			slog.Debug(
 				"call tree for active thread ID doesn't exist",
-				slog.Uint64("active_thread_id", p.Transaction().ActiveThreadID),
+				slog.String("active_thread_id", src.MainThreadID),
 			)
 			return
 		}
-		for _, root := range callTrees {
-			detectFrameInCallTree(root, options, nodes)
-		}
+		matches = detectFrameInThread(callTrees, src.MainThreadID, src.MainThreadName, options, matches)
 	} else {
-		for _, callTrees := range callTreesPerThreadID {
-			for _, root := range callTrees {
-				detectFrameInCallTree(root, options, nodes)
-			}
+		for tid, callTrees := range callTreesPerThreadID {
+			matches = detectFrameInThread(callTrees, tid, threadName(tid), options, matches)
 		}
 	}
 
 	// Create occurrences.
+	for _, n := range limitNodes(matches, options) {
+		*occurrences = append(*occurrences, newOccurrence(src, n))
+	}
+}
+
+// detectFrameInThread appends the nodes matching the options in the call
+// trees of a thread to matches.
+func detectFrameInThread(
+	callTrees []*nodetree.Node,
+	threadID string,
+	threadName string,
+	options DetectFrameOptions,
+	matches []nodeInfo,
+) []nodeInfo {
+	nodes := make(map[nodeKey]nodeInfo)
+	for _, root := range callTrees {
+		detectFrameInCallTree(root, options, nodes)
+	}
+	describeNodes(nodes, callTrees, threadID, threadName)
 	for _, n := range nodes {
-		*occurrences = append(*occurrences, NewOccurrence(p, n))
+		matches = append(matches, n)
+	}
+	return matches
+}
+
+func limitNodes(matches []nodeInfo, options DetectFrameOptions) []nodeInfo {
+	o, ok := options.(limitedOptions)
+	if !ok || o.maxOccurrences() <= 0 || len(matches) <= o.maxOccurrences() {
+		return matches
 	}
+	// Keep the frames spending the most time in their own code, since it's
+	// what they were detected on.
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].SelfTimeNS != matches[j].SelfTimeNS {
+			return matches[i].SelfTimeNS > matches[j].SelfTimeNS
+		}
+		if matches[i].Node.Fingerprint != matches[j].Node.Fingerprint {
+			return matches[i].Node.Fingerprint < matches[j].Node.Fingerprint
+		}
+		return matches[i].ThreadID < matches[j].ThreadID
+	})
+	return matches[:o.maxOccurrences()]
 }
 
 func detectFrameInCallTree(
@@ -520,17 +1244,32 @@ func detectFrameInNode(
 	defer func() {
 		*st = (*st)[:len(*st)-1]
 	}()
-	for _, c := range n.Children {
-		if ni := detectFrameInNode(c, options, nodes, st); ni != nil {
-			return ni
+	if o, ok := options.(exhaustiveOptions); ok && o.checkAllChildren() {
+		// Check all children but don't check the parent if one of them matched.
+		var childInfo *nodeInfo
+		for _, c := range n.Children {
+			if ni := detectFrameInNode(c, options, nodes, st); ni != nil && childInfo == nil {
+				childInfo = ni
+			}
+		}
+		if childInfo != nil {
+			return childInfo
+		}
+	} else {
+		for _, c := range n.Children {
+			if ni := detectFrameInNode(c, options, nodes, st); ni != nil {
+				return ni
+			}
 		}
 	}
 	ni := options.checkNode(n)
 	if ni != nil {
 		nk := nodeKey{Package: ni.Node.Package, Function: ni.Node.Name}
 		if _, exists := nodes[nk]; !exists {
-			ni.StackTrace = make([]frame.Frame, len(*st))
-			copy(ni.StackTrace, *st)
+			// Frames beneath the node are appended to the stack trace.
+			stackTrace := make([]frame.Frame, len(*st), len(*st)+len(ni.StackTrace))
+			copy(stackTrace, *st)
+			ni.StackTrace = append(stackTrace, ni.StackTrace...)
 			nodes[nk] = *ni
 		}
 	}
@@ -1,11 +1,13 @@
 package occurrence
 
 import (
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/testutil"
 )
 
@@ -810,3 +812,410 @@ func TestDetectFrameInCallTree(t *testing.T) {
 		})
 	}
 }
+
+func TestBuiltinDetectFrameJobs(t *testing.T) {
+	tests := []struct {
+		name         string
+		platform     platform.Platform
+		frame        frame.Frame
+		duration     time.Duration
+		sampleCount  int
+		wantCategory Category
+	}{
+		{
+			name:         "python requests call",
+			platform:     platform.Python,
+			frame:        frame.Frame{Function: "Session.request", Module: "requests.sessions"},
+			duration:     200 * time.Millisecond,
+			sampleCount:  20,
+			wantCategory: ServerHTTP,
+		},
+		{
+			name:         "python django query",
+			platform:     platform.Python,
+			frame:        frame.Frame{Function: "SQLCompiler.execute_sql", Module: "django.db.models.sql.compiler"},
+			duration:     150 * time.Millisecond,
+			sampleCount:  15,
+			wantCategory: ServerSQL,
+		},
+		{
+			name:         "python json decode",
+			platform:     platform.Python,
+			frame:        frame.Frame{Function: "loads", Module: "json"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerJSONDecode,
+		},
+		{
+			name:         "python regex compile",
+			platform:     platform.Python,
+			frame:        frame.Frame{Function: "compile", Module: "re"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerRegex,
+		},
+		{
+			name:         "java jackson with class as module",
+			platform:     platform.Java,
+			frame:        frame.Frame{Function: "readValue", Module: "com.fasterxml.jackson.databind.ObjectMapper"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerJSONDecode,
+		},
+		{
+			name:         "java regex with signature",
+			platform:     platform.Java,
+			frame:        frame.Frame{Function: "java.util.regex.Pattern.compile(java.lang.String): java.util.regex.Pattern", Package: "java.util.regex"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerRegex,
+		},
+		{
+			name:         "java jdbc query",
+			platform:     platform.Java,
+			frame:        frame.Frame{Function: "executeQuery", Module: "org.postgresql.jdbc.PgPreparedStatement"},
+			duration:     200 * time.Millisecond,
+			sampleCount:  20,
+			wantCategory: ServerSQL,
+		},
+		{
+			name:         "java thread sleep",
+			platform:     platform.Java,
+			frame:        frame.Frame{Function: "sleep", Module: "java.lang.Thread"},
+			duration:     200 * time.Millisecond,
+			sampleCount:  20,
+			wantCategory: ServerThreadWait,
+		},
+		{
+			name:         "go json decode",
+			platform:     platform.Go,
+			frame:        frame.Frame{Function: "Unmarshal", Module: "encoding/json"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerJSONDecode,
+		},
+		{
+			name:         "go regex compile",
+			platform:     platform.Go,
+			frame:        frame.Frame{Function: "MustCompile", Module: "regexp"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerRegex,
+		},
+		{
+			name:         "go sql query",
+			platform:     platform.Go,
+			frame:        frame.Frame{Function: "(*DB).QueryContext", Module: "database/sql"},
+			duration:     200 * time.Millisecond,
+			sampleCount:  20,
+			wantCategory: ServerSQL,
+		},
+		{
+			name:        "ruby single association load",
+			platform:    platform.Ruby,
+			frame:       frame.Frame{Function: "CollectionAssociation#load_target", Module: "ActiveRecord::Associations"},
+			duration:    200 * time.Millisecond,
+			sampleCount: 20,
+		},
+		{
+			name:         "ruby json parse",
+			platform:     platform.Ruby,
+			frame:        frame.Frame{Function: "JSON.parse", Package: "json"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerJSONDecode,
+		},
+		{
+			name:         "csharp file read",
+			platform:     platform.CSharp,
+			frame:        frame.Frame{Function: "ReadAllText", Module: "System.IO.File"},
+			duration:     20 * time.Millisecond,
+			sampleCount:  2,
+			wantCategory: ServerFileRead,
+		},
+		{
+			name:         "csharp blocking on a task",
+			platform:     platform.CSharp,
+			frame:        frame.Frame{Function: "get_Result", Module: "System.Threading.Tasks.Task`1"},
+			duration:     50 * time.Millisecond,
+			sampleCount:  5,
+			wantCategory: ServerThreadWait,
+		},
+		{
+			name:        "android slow in-app method without an in_app rule",
+			platform:    platform.Android,
+			frame:       frame.Frame{Function: "com.example.Adapter.bind()", Package: "com.example", InApp: &testutil.True},
+			duration:    60 * time.Millisecond,
+			sampleCount: 6,
+		},
+		{
+			name:        "python fast json decode",
+			platform:    platform.Python,
+			frame:       frame.Frame{Function: "loads", Module: "json"},
+			duration:    10 * time.Millisecond,
+			sampleCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := uint64(tt.duration)
+			root := nodetree.NodeFromFrame(frame.Frame{Function: "handler", Module: "app", InApp: &testutil.True}, 0, end, 0)
+			child := nodetree.NodeFromFrame(tt.frame, 0, end, 0)
+			child.SampleCount = tt.sampleCount
+			root.Children = []*nodetree.Node{child}
+			root.SampleCount = tt.sampleCount
+
+			nodes := make(map[nodeKey]nodeInfo)
+			for _, job := range detectFrameJobs[tt.platform] {
+				detectFrameInCallTree(root, job, nodes)
+			}
+
+			var got Category
+			for _, ni := range nodes {
+				got = ni.Category
+			}
+			if len(nodes) > 1 {
+				t.Fatalf("expected at most 1 node, got %d", len(nodes))
+			}
+			if got != tt.wantCategory {
+				t.Fatalf("expected category %q, got %q", tt.wantCategory, got)
+			}
+		})
+	}
+}
+
+func TestDetectFrameStopsAtFirstMatchingChild(t *testing.T) {
+	fn := func(name string, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		f := frame.Frame{Function: name, Package: "CoreFoundation", InApp: &testutil.False}
+		n := nodetree.NodeFromFrame(f, uint64(start), uint64(end), uint64(f.Fingerprint()))
+		n.SampleCount = int((end - start) / (10 * time.Millisecond))
+		n.Children = children
+		return n
+	}
+	root := fn("main", 0, 100*time.Millisecond,
+		fn("CFReadStreamRead", 0, 50*time.Millisecond),
+		fn("CFURLConnectionSendSynchronousRequest", 50*time.Millisecond, 100*time.Millisecond),
+	)
+	job := DetectExactFrameOptions{
+		DurationThreshold: 16 * time.Millisecond,
+		FunctionsByPackage: map[string]map[string]Category{
+			"CoreFoundation": {
+				"CFReadStreamRead":                      FileRead,
+				"CFURLConnectionSendSynchronousRequest": HTTP,
+			},
+		},
+	}
+
+	nodes := make(map[nodeKey]nodeInfo)
+	detectFrameInCallTree(root, job, nodes)
+	got := []string{}
+	for nk := range nodes {
+		got = append(got, nk.Function)
+	}
+	if diff := testutil.Diff(got, []string{"CFReadStreamRead"}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestDetectRepeatedFrame(t *testing.T) {
+	node := func(f frame.Frame, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		n := nodetree.NodeFromFrame(f, uint64(start), uint64(end), uint64(f.Fingerprint()))
+		n.SampleCount = int((end - start) / (10 * time.Millisecond))
+		n.Children = children
+		return n
+	}
+	app := func(name string, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		return node(frame.Frame{Function: name, InApp: &testutil.True}, start, end, children...)
+	}
+	load := func(start time.Duration) *nodetree.Node {
+		return node(
+			frame.Frame{Function: "CollectionProxy#records", Module: "ActiveRecord::Associations", InApp: &testutil.False},
+			start,
+			start+20*time.Millisecond,
+			node(
+				frame.Frame{Function: "CollectionAssociation#load_target", Module: "ActiveRecord::Associations", InApp: &testutil.False},
+				start,
+				start+20*time.Millisecond,
+			),
+		)
+	}
+	job := detectFrameJobs[platform.Ruby][0]
+
+	tests := []struct {
+		name          string
+		root          *nodetree.Node
+		wantCallCount int
+		wantCaller    string
+	}{
+		{
+			name: "loads in a loop",
+			root: app("PostsController#index", 0, 200*time.Millisecond,
+				load(0), load(40*time.Millisecond), load(80*time.Millisecond),
+				load(120*time.Millisecond), load(160*time.Millisecond),
+			),
+			wantCallCount: 5,
+			wantCaller:    "PostsController#index",
+		},
+		{
+			name: "loads from different callers",
+			root: app("PostsController#index", 0, 200*time.Millisecond,
+				app("Post#comments", 0, 40*time.Millisecond, load(0)),
+				app("Post#comments", 40*time.Millisecond, 80*time.Millisecond, load(40*time.Millisecond)),
+				app("Post#comments", 80*time.Millisecond, 120*time.Millisecond, load(80*time.Millisecond)),
+				app("Post#tags", 120*time.Millisecond, 160*time.Millisecond, load(120*time.Millisecond)),
+				app("Post#tags", 160*time.Millisecond, 200*time.Millisecond, load(160*time.Millisecond)),
+			),
+		},
+		{
+			name: "too few loads",
+			root: app("PostsController#show", 0, 200*time.Millisecond,
+				load(0), load(40*time.Millisecond),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make(map[nodeKey]nodeInfo)
+			detectFrameInCallTree(tt.root, job, nodes)
+			if tt.wantCallCount == 0 {
+				if len(nodes) != 0 {
+					t.Fatalf("expected no node, got %d", len(nodes))
+				}
+				return
+			}
+			if len(nodes) != 1 {
+				t.Fatalf("expected 1 node, got %d", len(nodes))
+			}
+			for _, ni := range nodes {
+				if ni.Category != AssociationLoad || ni.CallCount != tt.wantCallCount || ni.Caller != tt.wantCaller {
+					t.Fatalf("unexpected node: %s, %d calls from %s", ni.Category, ni.CallCount, ni.Caller)
+				}
+				if ni.Node.DurationNS != uint64(tt.wantCallCount)*uint64(20*time.Millisecond) {
+					t.Fatalf("unexpected duration: %d", ni.Node.DurationNS)
+				}
+				if len(ni.StackTrace) != 3 || ni.StackTrace[2].Function != "CollectionAssociation#load_target" {
+					t.Fatalf("unexpected stack trace: %v", ni.StackTrace)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectInAppFrame(t *testing.T) {
+	fn := func(name string, inApp bool, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		f := frame.Frame{Function: name, Package: "app", InApp: &inApp}
+		n := nodetree.NodeFromFrame(f, uint64(start), uint64(end), uint64(f.Fingerprint()))
+		n.SampleCount = int((end - start) / (10 * time.Millisecond))
+		n.Children = children
+		return n
+	}
+	root := fn("main", true, 0, 450*time.Millisecond,
+		fn("parse", true, 0, 120*time.Millisecond),
+		fn("render", true, 120*time.Millisecond, 200*time.Millisecond,
+			fn("layout", true, 120*time.Millisecond, 190*time.Millisecond),
+		),
+		fn("read", false, 200*time.Millisecond, 300*time.Millisecond,
+			fn("callback", true, 200*time.Millisecond, 260*time.Millisecond),
+		),
+		// slower than parse but spending less time in its own code
+		fn("load", true, 300*time.Millisecond, 450*time.Millisecond,
+			fn("decode", true, 300*time.Millisecond, 340*time.Millisecond),
+		),
+	)
+
+	tests := []struct {
+		name string
+		job  DetectInAppFrameOptions
+		want []string
+	}{
+		{
+			name: "all slow in-app frames",
+			job: DetectInAppFrameOptions{
+				SelfTimeThreshold: 50 * time.Millisecond,
+			},
+			want: []string{"callback", "layout", "load", "parse"},
+		},
+		{
+			name: "above sample threshold",
+			job: DetectInAppFrameOptions{
+				SelfTimeThreshold: 50 * time.Millisecond,
+				SampleThreshold:   7,
+			},
+			want: []string{"layout", "load", "parse"},
+		},
+		{
+			name: "capped to the slowest frames",
+			job: DetectInAppFrameOptions{
+				SelfTimeThreshold: 50 * time.Millisecond,
+				MaxOccurrences:    2,
+			},
+			want: []string{"load", "parse"},
+		},
+		{
+			name: "capped on self time",
+			job: DetectInAppFrameOptions{
+				SelfTimeThreshold: 50 * time.Millisecond,
+				MaxOccurrences:    1,
+			},
+			want: []string{"parse"},
+		},
+		{
+			name: "above share of the profile",
+			job: DetectInAppFrameOptions{
+				SelfTimeThreshold: 50 * time.Millisecond,
+				MinProfileRatio:   0.25,
+			},
+			want: []string{"parse"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DetectFrameOptions(inAppFrameDetector{
+				options:           tt.job,
+				profileDurationNS: uint64(450 * time.Millisecond),
+			})
+			matches := detectFrameInThread([]*nodetree.Node{root}, "1", "main", options, nil)
+			got := []string{}
+			for _, ni := range limitNodes(matches, options) {
+				if ni.Category != SlowFunction || !ni.GroupByFrameFingerprint {
+					t.Fatalf("unexpected node info for %s: %+v", ni.Node.Name, ni)
+				}
+				got = append(got, ni.Node.Name)
+			}
+			sort.Strings(got)
+			if diff := testutil.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectFrameInThreadsDescribesEachThread(t *testing.T) {
+	parse := func(start, end time.Duration) *nodetree.Node {
+		n := nodetree.NodeFromFrame(frame.Frame{Function: "JSON.parse", Package: "json", InApp: &testutil.False}, uint64(start), uint64(end), 0)
+		n.SampleCount = 5
+		return n
+	}
+	callTrees := map[string][]*nodetree.Node{
+		"1": {parse(0, 50*time.Millisecond)},
+		"2": {parse(100*time.Millisecond, 150*time.Millisecond), parse(200*time.Millisecond, 250*time.Millisecond)},
+	}
+	src := source{Platform: platform.Ruby, DurationNS: uint64(300 * time.Millisecond)}
+	threadName := func(tid string) string { return "puma srv tp " + tid }
+
+	var occurrences []*Occurrence
+	detectFrameInThreads(src, callTrees, threadName, detectFrameJobs[platform.Ruby][1], &occurrences)
+	if len(occurrences) != 2 {
+		t.Fatalf("expected 2 occurrences, got %d", len(occurrences))
+	}
+	ranges := make(map[interface{}]int)
+	for _, o := range occurrences {
+		ranges[o.EvidenceData["thread_id"]] = len(o.EvidenceData["sample_ranges"].([]timeRange))
+	}
+	if diff := testutil.Diff(ranges, map[interface{}]int{"1": 1, "2": 2}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
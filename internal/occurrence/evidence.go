@@ -0,0 +1,125 @@
+package occurrence
+
+import (
+	"sort"
+
+	"github.com/getsentry/vroom/internal/nodetree"
+)
+
+type (
+	// timeRange is a time range, in nanoseconds.
+	timeRange struct {
+		StartNS uint64 `json:"start_ns"`
+		EndNS   uint64 `json:"end_ns"`
+	}
+
+	// evidenceNode is a node of the call tree beneath the culprit, nodes
+	// for the same function being merged together.
+	evidenceNode struct {
+		Name          string          `json:"name"`
+		Package       string          `json:"package"`
+		IsApplication bool            `json:"is_application"`
+		DurationNS    uint64          `json:"duration_ns"`
+		SampleCount   int             `json:"sample_count"`
+		Children      []*evidenceNode `json:"children,omitempty"`
+	}
+)
+
+const (
+	// Limits on the size of the evidence data.
+	maxEvidenceDepth    int = 8
+	maxEvidenceChildren int = 5
+	maxEvidenceRanges   int = 50
+)
+
+// describeNodes sets the thread, the time ranges and the aggregated call
+// tree of the nodes found in the call trees of a thread.
+func describeNodes(
+	nodes map[nodeKey]nodeInfo,
+	callTrees []*nodetree.Node,
+	threadID string,
+	threadName string,
+) {
+	for k, ni := range nodes {
+		ni.ThreadID = threadID
+		ni.ThreadName = threadName
+		var matches []*nodetree.Node
+		for _, root := range callTrees {
+			collectNodes(root, k, &matches)
+		}
+		var children []*nodetree.Node
+		for _, n := range matches {
+			ni.Ranges = append(ni.Ranges, timeRange{StartNS: n.StartNS, EndNS: n.EndNS})
+			children = append(children, n.Children...)
+		}
+		ni.Subtree = aggregateNodes(children, 0)
+		nodes[k] = ni
+	}
+}
+
+// collectNodes collects the outermost nodes for a function.
+func collectNodes(n *nodetree.Node, k nodeKey, matches *[]*nodetree.Node) {
+	if n.Package == k.Package && n.Name == k.Function {
+		*matches = append(*matches, n)
+		return
+	}
+	for _, c := range n.Children {
+		collectNodes(c, k, matches)
+	}
+}
+
+// aggregateNodes merges nodes for the same function, keeping the
+// slowest ones up to a maximum depth.
+func aggregateNodes(nodes []*nodetree.Node, depth int) []*evidenceNode {
+	if len(nodes) == 0 || depth >= maxEvidenceDepth {
+		return nil
+	}
+	aggregated := make(map[nodeKey]*evidenceNode)
+	children := make(map[*evidenceNode][]*nodetree.Node)
+	evidenceNodes := make([]*evidenceNode, 0, len(nodes))
+	for _, n := range nodes {
+		k := nodeKey{Package: n.Package, Function: n.Name}
+		en, exists := aggregated[k]
+		if !exists {
+			en = &evidenceNode{
+				Name:          n.Name,
+				Package:       n.Package,
+				IsApplication: n.IsApplication,
+			}
+			aggregated[k] = en
+			evidenceNodes = append(evidenceNodes, en)
+		}
+		en.DurationNS += n.DurationNS
+		en.SampleCount += n.SampleCount
+		children[en] = append(children[en], n.Children...)
+	}
+	sort.SliceStable(evidenceNodes, func(i, j int) bool {
+		return evidenceNodes[i].DurationNS > evidenceNodes[j].DurationNS
+	})
+	if len(evidenceNodes) > maxEvidenceChildren {
+		evidenceNodes = evidenceNodes[:maxEvidenceChildren]
+	}
+	for _, en := range evidenceNodes {
+		en.Children = aggregateNodes(children[en], depth+1)
+	}
+	return evidenceNodes
+}
+
+// relativeRanges returns the ranges relative to a start timestamp
+// and the range containing all of them.
+func relativeRanges(ranges []timeRange, startNS uint64) ([]timeRange, timeRange) {
+	relative := make([]timeRange, 0, min(len(ranges), maxEvidenceRanges))
+	slice := ranges[0]
+	for i, r := range ranges {
+		slice.StartNS = min(slice.StartNS, r.StartNS)
+		slice.EndNS = max(slice.EndNS, r.EndNS)
+		if i >= maxEvidenceRanges {
+			continue
+		}
+		relative = append(relative, timeRange{
+			StartNS: r.StartNS - min(r.StartNS, startNS),
+			EndNS:   r.EndNS - min(r.EndNS, startNS),
+		})
+	}
+	return relative, slice
+}
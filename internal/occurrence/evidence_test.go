@@ -0,0 +1,129 @@
+package occurrence
+
+import (
+	"testing"
+
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestDescribeNodes(t *testing.T) {
+	leaf := func(name string, startNS, endNS uint64) *nodetree.Node {
+		return &nodetree.Node{
+			Name:        name,
+			Package:     "package",
+			StartNS:     startNS,
+			EndNS:       endNS,
+			DurationNS:  endNS - startNS,
+			SampleCount: int((endNS - startNS) / 10),
+		}
+	}
+	node := func(name string, startNS, endNS uint64, children ...*nodetree.Node) *nodetree.Node {
+		n := leaf(name, startNS, endNS)
+		n.Children = children
+		return n
+	}
+
+	tests := []struct {
+		name      string
+		callTrees []*nodetree.Node
+		want      nodeInfo
+	}{
+		{
+			name: "merge calls to the same function",
+			callTrees: []*nodetree.Node{
+				node("root", 0, 100,
+					node("culprit", 0, 40,
+						leaf("read", 0, 10),
+						leaf("parse", 10, 40),
+					),
+					node("other", 40, 60),
+					node("culprit", 60, 100,
+						leaf("read", 60, 90),
+					),
+				),
+			},
+			want: nodeInfo{
+				ThreadID:   "1",
+				ThreadName: "main",
+				Ranges:     []timeRange{{StartNS: 0, EndNS: 40}, {StartNS: 60, EndNS: 100}},
+				Subtree: []*evidenceNode{
+					{Name: "read", Package: "package", DurationNS: 40, SampleCount: 4},
+					{Name: "parse", Package: "package", DurationNS: 30, SampleCount: 3},
+				},
+			},
+		},
+		{
+			name: "don't describe recursive calls twice",
+			callTrees: []*nodetree.Node{
+				node("culprit", 0, 100,
+					node("culprit", 0, 50),
+				),
+			},
+			want: nodeInfo{
+				ThreadID:   "1",
+				ThreadName: "main",
+				Ranges:     []timeRange{{StartNS: 0, EndNS: 100}},
+				Subtree: []*evidenceNode{
+					{Name: "culprit", Package: "package", DurationNS: 50, SampleCount: 5},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := nodeKey{Package: "package", Function: "culprit"}
+			nodes := map[nodeKey]nodeInfo{k: {}}
+			describeNodes(nodes, tt.callTrees, "1", "main")
+			if diff := testutil.Diff(nodes[k], tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAggregateNodesLimits(t *testing.T) {
+	var nodes []*nodetree.Node
+	for i := 0; i < maxEvidenceChildren+2; i++ {
+		nodes = append(nodes, &nodetree.Node{
+			Name:       string(rune('a' + i)),
+			DurationNS: uint64(i),
+		})
+	}
+	aggregated := aggregateNodes(nodes, 0)
+	if len(aggregated) != maxEvidenceChildren {
+		t.Fatalf("expected %d nodes, got %d", maxEvidenceChildren, len(aggregated))
+	}
+	if aggregated[0].Name != string(rune('a'+maxEvidenceChildren+1)) {
+		t.Fatalf("expected the slowest node first, got %s", aggregated[0].Name)
+	}
+
+	deep := &nodetree.Node{Name: "0"}
+	n := deep
+	for i := 1; i < maxEvidenceDepth*2; i++ {
+		c := &nodetree.Node{Name: string(rune('0' + i))}
+		n.Children = []*nodetree.Node{c}
+		n = c
+	}
+	var depth int
+	for en := aggregateNodes([]*nodetree.Node{deep}, 0); len(en) > 0; en = en[0].Children {
+		depth++
+	}
+	if depth != maxEvidenceDepth {
+		t.Fatalf("expected a depth of %d, got %d", maxEvidenceDepth, depth)
+	}
+}
+
+func TestRelativeRanges(t *testing.T) {
+	ranges, slice := relativeRanges(
+		[]timeRange{{StartNS: 1_500, EndNS: 2_000}, {StartNS: 1_100, EndNS: 1_200}},
+		1_000,
+	)
+	if diff := testutil.Diff(ranges, []timeRange{{StartNS: 500, EndNS: 1_000}, {StartNS: 100, EndNS: 200}}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if diff := testutil.Diff(slice, timeRange{StartNS: 1_100, EndNS: 2_000}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
@@ -1,17 +1,64 @@
 package occurrence
 
 import (
+	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/profile"
 )
 
 func Find(p profile.Profile, callTrees map[uint64][]*nodetree.Node) []*Occurrence {
 	var occurrences []*Occurrence
-	if jobs, exists := detectFrameJobs[p.Platform()]; exists {
-		for _, metadata := range jobs {
+	rs := rules()
+	if jobs, exists := rs.Jobs[p.Platform()]; exists {
+		for i, metadata := range jobs {
+			n := len(occurrences)
 			detectFrame(p, callTrees, metadata, &occurrences)
+			for _, o := range occurrences[n:] {
+				o.rule = rs.jobName(p.Platform(), i)
+			}
 		}
 	}
 	findFrameDropCause(p, callTrees, &occurrences)
+	if activeCallTrees, exists := callTrees[p.Transaction().ActiveThreadID]; exists {
+		findHangs(sourceFromProfile(p), activeCallTrees, uint64(rs.HangThreshold), &occurrences)
+	}
 	return occurrences
 }
+
+// chunkJobPlatforms are the platforms whose detection jobs also run on
+// continuous profile chunks. Ruby chunks, converted from stackprof or vernier
+// profiles, sample the request threads of the server rather than a main
+// thread.
+var chunkJobPlatforms = map[platform.Platform]struct{}{
+	platform.Ruby: {},
+}
+
+// FindInChunk looks for frame drops and hangs on the main thread of a
+// continuous profile chunk and, for some platforms, runs the detection jobs
+// on all its threads.
+func FindInChunk(c chunk.Chunk, callTrees map[string][]*nodetree.Node) ([]*Occurrence, error) {
+	var occurrences []*Occurrence
+	src := sourceFromChunk(c)
+	rs := rules()
+	if _, exists := chunkJobPlatforms[src.Platform]; exists {
+		for i, job := range rs.Jobs[src.Platform] {
+			n := len(occurrences)
+			detectFrameInThreads(src, callTrees, c.ThreadName, job, &occurrences)
+			for _, o := range occurrences[n:] {
+				o.rule = rs.jobName(src.Platform, i)
+			}
+		}
+	}
+	mainCallTrees, exists := callTrees[c.MainThreadID()]
+	if !exists {
+		return occurrences, nil
+	}
+	chunkMeasurements, err := c.GetMeasurements()
+	if err != nil {
+		return nil, err
+	}
+	findChunkFrameDropCause(src, mainCallTrees, chunkMeasurements, &occurrences)
+	findHangs(src, mainCallTrees, uint64(rs.HangThreshold), &occurrences)
+	return occurrences, nil
+}
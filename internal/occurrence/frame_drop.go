@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/measurements"
 	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/profile"
 )
 
@@ -23,6 +25,12 @@ type (
 		startLimitNS  uint64
 		startNS       uint64
 	}
+
+	// frameRender is a frame which took too long to render.
+	frameRender struct {
+		endNS      uint64
+		durationNS float64
+	}
 )
 
 func newFrozenFrameStats(endNS uint64, durationNS float64) frozenFrameStats {
@@ -51,12 +59,37 @@ func (s *frozenFrameStats) IsNodeStackValid(ns *nodeStack) bool {
 }
 
 const (
-	FrameDrop Category = "frame_drop"
+	FrameDrop     Category = "frame_drop"
+	SlowFrameDrop Category = "slow_frame_drop"
+	Hang          Category = "hang"
 
 	marginPercent                    float64 = 0.05
 	minFrameDurationPercent          float64 = 0.5
 	startLimitPercent                float64 = 0.2
 	unknownFramesInTheStackThreshold float64 = 0.8
+
+	hangRule = "hang"
+)
+
+var (
+	// frameRenderMeasurements maps the measurements listing frames taking
+	// too long to render to the category of the occurrences for their cause.
+	// Slow frames are frequent, only one occurrence per function is kept.
+	frameRenderMeasurements = []struct {
+		name           string
+		category       Category
+		onePerFunction bool
+	}{
+		{name: "frozen_frame_renders", category: FrameDrop},
+		{name: "slow_frame_renders", category: SlowFrameDrop, onePerFunction: true},
+	}
+
+	// hangPlatforms are the platforms where the main thread is
+	// responsible for the UI and shouldn't hang.
+	hangPlatforms = map[platform.Platform]struct{}{
+		platform.Android: {},
+		platform.Cocoa:   {},
+	}
 )
 
 func findFrameDropCause(
@@ -64,16 +97,73 @@ func findFrameDropCause(
 	callTreesPerThreadID map[uint64][]*nodetree.Node,
 	occurrences *[]*Occurrence,
 ) {
-	frameDrops, exists := p.Measurements()["frozen_frame_renders"]
-	if !exists {
-		return
-	}
 	callTrees, exists := callTreesPerThreadID[p.Transaction().ActiveThreadID]
 	if !exists {
 		return
 	}
-	for _, mv := range frameDrops.Values {
-		stats := newFrozenFrameStats(mv.ElapsedSinceStartNs, mv.Value)
+	src := sourceFromProfile(p)
+	for _, m := range frameRenderMeasurements {
+		measurement, exists := p.Measurements()[m.name]
+		if !exists {
+			continue
+		}
+		frames := make([]frameRender, 0, len(measurement.Values))
+		for _, mv := range measurement.Values {
+			frames = append(frames, frameRender{
+				endNS:      mv.ElapsedSinceStartNs,
+				durationNS: mv.Value,
+			})
+		}
+		for _, ni := range findFrameRenderCauses(callTrees, frames, m.category, m.onePerFunction) {
+			ni.ThreadID, ni.ThreadName = src.MainThreadID, src.MainThreadName
+			o := newOccurrence(src, ni)
+			o.rule = m.name
+			*occurrences = append(*occurrences, o)
+		}
+	}
+}
+
+// findChunkFrameDropCause does the same as findFrameDropCause for a chunk,
+// its measurements having absolute timestamps like its call trees.
+func findChunkFrameDropCause(
+	src source,
+	callTrees []*nodetree.Node,
+	chunkMeasurements map[string]measurements.MeasurementV2,
+	occurrences *[]*Occurrence,
+) {
+	for _, m := range frameRenderMeasurements {
+		measurement, exists := chunkMeasurements[m.name]
+		if !exists {
+			continue
+		}
+		frames := make([]frameRender, 0, len(measurement.Values))
+		for _, mv := range measurement.Values {
+			frames = append(frames, frameRender{
+				endNS:      uint64(mv.Timestamp * 1e9),
+				durationNS: mv.Value,
+			})
+		}
+		for _, ni := range findFrameRenderCauses(callTrees, frames, m.category, m.onePerFunction) {
+			ni.ThreadID, ni.ThreadName = src.MainThreadID, src.MainThreadName
+			o := newOccurrence(src, ni)
+			o.rule = m.name
+			*occurrences = append(*occurrences, o)
+		}
+	}
+}
+
+// findFrameRenderCauses returns the most likely cause of each frame,
+// keeping only one occurrence per function if onePerFunction is set.
+func findFrameRenderCauses(
+	callTrees []*nodetree.Node,
+	frames []frameRender,
+	category Category,
+	onePerFunction bool,
+) []nodeInfo {
+	var causes []nodeInfo
+	seen := make(map[nodeKey]struct{})
+	for _, fr := range frames {
+		stats := newFrozenFrameStats(fr.endNS, fr.durationNS)
 		for _, root := range callTrees {
 			st := make([]*nodetree.Node, 0, profile.MaxStackDepth)
 			cause := findFrameDropCauseFrame(
@@ -85,7 +175,7 @@ func findFrameDropCause(
 			if cause == nil {
 				continue
 			}
-			// We found a potential stacktrace responsible for this frozen frame
+			// We found a potential stacktrace responsible for this frame
 			stackTrace := make([]frame.Frame, 0, len(cause.st))
 			var unknownFramesCount float64
 			for _, f := range cause.st {
@@ -99,17 +189,106 @@ func findFrameDropCause(
 			if unknownFramesCount >= float64(len(stackTrace))*unknownFramesInTheStackThreshold {
 				continue
 			}
-			*occurrences = append(
-				*occurrences,
-				NewOccurrence(p, nodeInfo{
-					Category:   FrameDrop,
-					Node:       *cause.n,
-					StackTrace: stackTrace,
-				}),
-			)
+			nk := nodeKey{Package: cause.n.Package, Function: cause.n.Name}
+			if _, exists := seen[nk]; exists && onePerFunction {
+				break
+			}
+			seen[nk] = struct{}{}
+			causes = append(causes, nodeInfo{
+				Category:   category,
+				Node:       *cause.n,
+				StackTrace: stackTrace,
+				Ranges:     []timeRange{{StartNS: cause.n.StartNS, EndNS: cause.n.EndNS}},
+				Subtree:    aggregateNodes(cause.n.Children, 0),
+			})
 			break
 		}
 	}
+	return causes
+}
+
+// findHangs looks for periods longer than the threshold during which the
+// stack of the main thread didn't change and blames the deepest
+// application frame of that stack.
+func findHangs(
+	src source,
+	callTrees []*nodetree.Node,
+	thresholdNS uint64,
+	occurrences *[]*Occurrence,
+) {
+	if _, exists := hangPlatforms[src.Platform]; !exists || thresholdNS == 0 {
+		return
+	}
+	nodes := make(map[nodeKey]nodeInfo)
+	for _, root := range callTrees {
+		st := make([]*nodetree.Node, 0, profile.MaxStackDepth)
+		findHangsInNode(root, thresholdNS, nodes, &st)
+	}
+	for _, ni := range nodes {
+		ni.ThreadID, ni.ThreadName = src.MainThreadID, src.MainThreadName
+		o := newOccurrence(src, ni)
+		o.rule = hangRule
+		*occurrences = append(*occurrences, o)
+	}
+}
+
+func findHangsInNode(
+	n *nodetree.Node,
+	thresholdNS uint64,
+	nodes map[nodeKey]nodeInfo,
+	st *[]*nodetree.Node,
+) {
+	*st = append(*st, n)
+	defer func() {
+		*st = (*st)[:len(*st)-1]
+	}()
+	for _, c := range n.Children {
+		findHangsInNode(c, thresholdNS, nodes, st)
+	}
+
+	// Find the longest period the node was on top of the stack.
+	var startNS, endNS uint64
+	lastEndNS := n.StartNS
+	for i := 0; i <= len(n.Children); i++ {
+		nextStartNS, nextEndNS := n.EndNS, n.EndNS
+		if i < len(n.Children) {
+			nextStartNS, nextEndNS = n.Children[i].StartNS, n.Children[i].EndNS
+		}
+		if nextStartNS > lastEndNS && nextStartNS-lastEndNS > endNS-startNS {
+			startNS, endNS = lastEndNS, nextStartNS
+		}
+		lastEndNS = max(lastEndNS, nextEndNS)
+	}
+	if endNS-startNS < thresholdNS {
+		return
+	}
+
+	// Blame the deepest application frame.
+	for i := len(*st) - 1; i >= 0; i-- {
+		culprit := (*st)[i]
+		if !culprit.IsApplication || culprit.Frame.Function == "" {
+			continue
+		}
+		nk := nodeKey{Package: culprit.Package, Function: culprit.Name}
+		if ni, exists := nodes[nk]; exists && ni.Node.DurationNS >= endNS-startNS {
+			return
+		}
+		stackTrace := make([]frame.Frame, 0, len(*st))
+		for _, f := range *st {
+			stackTrace = append(stackTrace, f.ToFrame())
+		}
+		ni := nodeInfo{
+			Category:   Hang,
+			Node:       *culprit,
+			StackTrace: stackTrace,
+			Ranges:     []timeRange{{StartNS: startNS, EndNS: endNS}},
+		}
+		// Report the duration of the hang instead of the one of the frame.
+		ni.Node.StartNS, ni.Node.EndNS, ni.Node.DurationNS = startNS, endNS, endNS-startNS
+		ni.Node.Children = nil
+		nodes[nk] = ni
+		return
+	}
 }
 
 func findFrameDropCauseFrame(
@@ -1,12 +1,14 @@
 package occurrence
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
+	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/measurements"
 	"github.com/getsentry/vroom/internal/nodetree"
@@ -156,10 +158,18 @@ func TestFindFrameDrop(t *testing.T) {
 						"transaction_id":      "1234",
 						"transaction_name":    "some",
 						"profile_duration_ns": uint64(500000000),
+						"sample_ranges":       []timeRange{{StartNS: 200000000, EndNS: 300000000}},
+						"slice_start_ns":      uint64(200000000),
+						"slice_end_ns":        uint64(300000000),
+						"subtree": []*evidenceNode{
+							{Name: "child2-1", Package: "package", IsApplication: true, DurationNS: 50000000},
+						},
+						"thread_id": "1",
 					},
 					EvidenceDisplay: []Evidence{
 						{Name: "Suspect function", Value: "child2", Important: true},
 						{Name: "Package", Value: "package"},
+						{Name: "Thread", Value: "1"},
 					},
 					IssueTitle:  issueTitles[FrameDrop].IssueTitle,
 					Level:       "info",
@@ -375,6 +385,20 @@ func TestFindFrameDrop(t *testing.T) {
 						"transaction_id":      "1234",
 						"transaction_name":    "some",
 						"profile_duration_ns": uint64(500000000),
+						"sample_ranges":       []timeRange{{StartNS: 200000000, EndNS: 300000000}},
+						"slice_start_ns":      uint64(200000000),
+						"slice_end_ns":        uint64(300000000),
+						"subtree": []*evidenceNode{
+							{
+								Name:       "child2-1-1-1",
+								Package:    "package",
+								DurationNS: 100000000,
+								Children: []*evidenceNode{
+									{Name: "child2-1-1-1-1", Package: "package", DurationNS: 100000000},
+								},
+							},
+						},
+						"thread_id": "1",
 					},
 					EvidenceDisplay: []Evidence{
 						{
@@ -383,6 +407,7 @@ func TestFindFrameDrop(t *testing.T) {
 							Important: true,
 						},
 						{Name: "Package", Value: "package"},
+						{Name: "Thread", Value: "1"},
 					},
 					IssueTitle:  issueTitles[FrameDrop].IssueTitle,
 					Level:       "info",
@@ -577,6 +602,10 @@ func TestFindFrameDrop(t *testing.T) {
 						"transaction_id":      "1234",
 						"transaction_name":    "some",
 						"profile_duration_ns": uint64(500000000),
+						"sample_ranges":       []timeRange{{StartNS: 100000000, EndNS: 250000000}},
+						"slice_start_ns":      uint64(100000000),
+						"slice_end_ns":        uint64(250000000),
+						"thread_id":           "1",
 					},
 					EvidenceDisplay: []Evidence{
 						{
@@ -585,6 +614,7 @@ func TestFindFrameDrop(t *testing.T) {
 							Important: true,
 						},
 						{Name: "Package", Value: "package"},
+						{Name: "Thread", Value: "1"},
 					},
 					IssueTitle:  issueTitles[FrameDrop].IssueTitle,
 					Level:       "info",
@@ -746,6 +776,10 @@ func TestFindFrameDrop(t *testing.T) {
 						"transaction_id":      "1234",
 						"transaction_name":    "some",
 						"profile_duration_ns": uint64(500000000),
+						"sample_ranges":       []timeRange{{StartNS: 100000000, EndNS: 350000000}},
+						"slice_start_ns":      uint64(100000000),
+						"slice_end_ns":        uint64(350000000),
+						"thread_id":           "1",
 					},
 					EvidenceDisplay: []Evidence{
 						{
@@ -754,6 +788,7 @@ func TestFindFrameDrop(t *testing.T) {
 							Important: true,
 						},
 						{Name: "Package", Value: "package"},
+						{Name: "Thread", Value: "1"},
 					},
 					IssueTitle:  issueTitles[FrameDrop].IssueTitle,
 					Level:       "info",
@@ -819,3 +854,302 @@ func TestIsNodeStackValid(t *testing.T) {
 		})
 	}
 }
+
+func TestFindHangs(t *testing.T) {
+	fn := func(name string, inApp bool, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		n := nodetree.NodeFromFrame(frame.Frame{Function: name, Package: "package", InApp: &inApp}, uint64(start), uint64(end), 0)
+		n.Children = children
+		return n
+	}
+
+	tests := []struct {
+		name      string
+		platform  platform.Platform
+		callTrees []*nodetree.Node
+		want      map[string]uint64
+	}{
+		{
+			name:     "blame the deepest application frame",
+			platform: platform.Cocoa,
+			callTrees: []*nodetree.Node{
+				fn("main", true, 0, 4*time.Second,
+					fn("load", true, 0, 3*time.Second,
+						fn("wait", false, 0, 3*time.Second),
+					),
+				),
+			},
+			want: map[string]uint64{"load": uint64(3 * time.Second)},
+		},
+		{
+			name:     "no progress after a call",
+			platform: platform.Android,
+			callTrees: []*nodetree.Node{
+				fn("main", true, 0, 4*time.Second,
+					fn("load", true, 0, 100*time.Millisecond),
+					fn("parse", true, 200*time.Millisecond, 300*time.Millisecond),
+				),
+			},
+			want: map[string]uint64{"main": uint64(3700 * time.Millisecond)},
+		},
+		{
+			name:     "no application frame",
+			platform: platform.Cocoa,
+			callTrees: []*nodetree.Node{
+				fn("start", false, 0, 3*time.Second,
+					fn("wait", false, 0, 3*time.Second),
+				),
+			},
+			want: map[string]uint64{},
+		},
+		{
+			name:     "stack changing often enough",
+			platform: platform.Cocoa,
+			callTrees: []*nodetree.Node{
+				fn("main", true, 0, 3*time.Second,
+					fn("load", true, 0, time.Second),
+					fn("load", true, time.Second, 2*time.Second),
+					fn("load", true, 2*time.Second, 3*time.Second),
+				),
+			},
+			want: map[string]uint64{},
+		},
+		{
+			name:     "backend platform",
+			platform: platform.Python,
+			callTrees: []*nodetree.Node{
+				fn("handler", true, 0, 3*time.Second),
+			},
+			want: map[string]uint64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var occurrences []*Occurrence
+			findHangs(
+				source{Platform: tt.platform},
+				tt.callTrees,
+				uint64(2*time.Second),
+				&occurrences,
+			)
+			got := make(map[string]uint64)
+			for _, o := range occurrences {
+				if o.Category() != Hang {
+					t.Fatalf("unexpected category %s", o.Category())
+				}
+				got[o.Subtitle] = o.durationNS
+			}
+			if diff := testutil.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindHangsWithConfiguredThreshold(t *testing.T) {
+	rs, err := ParseRules([]byte("hang_threshold: 2s\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetRuleSet(rs)
+	defer SetRuleSet(nil)
+	second := uint64(time.Second)
+	c := chunk.New(&chunk.SampleChunk{
+		ID:         "chunk",
+		ProfilerID: "profiler",
+		Platform:   platform.Cocoa,
+		Profile: chunk.SampleData{
+			Samples: []chunk.Sample{
+				{ThreadID: "1", Timestamp: 1000},
+				{ThreadID: "1", Timestamp: 1004},
+			},
+			ThreadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "main"},
+			},
+		},
+	})
+	load := nodetree.NodeFromFrame(frame.Frame{Function: "load", Package: "package", InApp: &testutil.True}, 1000*second, 1003*second, 0)
+	main := nodetree.NodeFromFrame(frame.Frame{Function: "main", Package: "package", InApp: &testutil.True}, 1000*second, 1004*second, 0)
+	main.Children = []*nodetree.Node{load}
+
+	occurrences, err := FindInChunk(c, map[string][]*nodetree.Node{"1": {main}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(occurrences) != 1 {
+		t.Fatalf("expected 1 occurrence, got %d", len(occurrences))
+	}
+	o := occurrences[0]
+	if o.Category() != Hang || o.Type != HangType || o.Subtitle != "load" {
+		t.Fatalf("unexpected occurrence: %s %s %d", o.Category(), o.Subtitle, o.Type)
+	}
+}
+
+func TestFindInChunk(t *testing.T) {
+	second := uint64(time.Second)
+	c := chunk.New(&chunk.SampleChunk{
+		ID:         "chunk",
+		ProfilerID: "profiler",
+		Platform:   platform.Cocoa,
+		Profile: chunk.SampleData{
+			Samples: []chunk.Sample{
+				{ThreadID: "1", Timestamp: 1000},
+				{ThreadID: "1", Timestamp: 1001},
+			},
+			ThreadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "main"},
+				"2": {Name: "worker"},
+			},
+		},
+		Measurements: json.RawMessage(`{
+			"slow_frame_renders": {
+				"unit": "nanosecond",
+				"values": [{"timestamp": 1000.5, "value": 100000000}]
+			}
+		}`),
+	})
+	app := func(name string, start, end uint64, children ...*nodetree.Node) *nodetree.Node {
+		n := nodetree.NodeFromFrame(frame.Frame{Function: name, Package: "package", InApp: &testutil.True}, start, end, 0)
+		n.Children = children
+		return n
+	}
+	callTrees := map[string][]*nodetree.Node{
+		"1": {
+			app("main", 1000*second, 1001*second,
+				app("layout", 1000*second+400_000_000, 1000*second+490_000_000),
+			),
+		},
+		"2": {
+			app("sync", 1000*second, 1001*second),
+		},
+	}
+
+	occurrences, err := FindInChunk(c, callTrees)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(occurrences) != 1 {
+		t.Fatalf("expected 1 occurrence, got %d", len(occurrences))
+	}
+	o := occurrences[0]
+	if o.Type != SlowFrameDropType || o.IssueTitle != "Slow Frame" || o.Subtitle != "layout" {
+		t.Fatalf("unexpected occurrence: %s %d", o.Subtitle, o.Type)
+	}
+	if o.EvidenceData["chunk_id"] != "chunk" || o.EvidenceData["profiler_id"] != "profiler" {
+		t.Fatalf("unexpected evidence data: %v", o.EvidenceData)
+	}
+}
+
+func TestFindInRubyChunk(t *testing.T) {
+	second := uint64(time.Second)
+	c := chunk.New(&chunk.SampleChunk{
+		ID:         "chunk",
+		ProfilerID: "profiler",
+		Platform:   platform.Ruby,
+		Profile: chunk.SampleData{
+			Samples: []chunk.Sample{
+				{ThreadID: "1", Timestamp: 1000},
+				{ThreadID: "2", Timestamp: 1000},
+			},
+			ThreadMetadata: map[string]sample.ThreadMetadata{
+				"1": {Name: "main"},
+				"2": {Name: "puma srv tp 001"},
+			},
+		},
+	})
+	handler := nodetree.NodeFromFrame(frame.Frame{Function: "UsersController#index", InApp: &testutil.True}, 1000*second, 1001*second, 0)
+	parse := nodetree.NodeFromFrame(frame.Frame{Function: "JSON.parse", Package: "json"}, 1000*second, 1000*second+50_000_000, 0)
+	parse.SampleCount = 5
+	handler.Children = []*nodetree.Node{parse}
+	callTrees := map[string][]*nodetree.Node{
+		"1": {nodetree.NodeFromFrame(frame.Frame{Function: "Thread#join"}, 1000*second, 1001*second, 0)},
+		"2": {handler},
+	}
+
+	occurrences, err := FindInChunk(c, callTrees)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(occurrences) != 1 {
+		t.Fatalf("expected 1 occurrence, got %d", len(occurrences))
+	}
+	o := occurrences[0]
+	if o.Category() != ServerJSONDecode || o.IssueTitle != "JSON Decoding on Request Thread" || o.EvidenceData["thread_id"] != "2" {
+		t.Fatalf("unexpected occurrence: %s on thread %v", o.Category(), o.EvidenceData["thread_id"])
+	}
+}
+
+func TestFindFrameDropAndroidSlowFrames(t *testing.T) {
+	p := profile.New(&profile.LegacyProfile{
+		RawProfile: profile.RawProfile{
+			DurationNS: uint64(600 * time.Millisecond),
+			Platform:   platform.Android,
+			ProfileID:  "1234567890",
+			Measurements: map[string]measurements.Measurement{
+				"slow_frame_renders": {
+					Unit: "nanosecond",
+					Values: []measurements.MeasurementValue{
+						{
+							ElapsedSinceStartNs: uint64(300 * time.Millisecond),
+							Value:               float64(100 * time.Millisecond),
+						},
+						{
+							ElapsedSinceStartNs: uint64(500 * time.Millisecond),
+							Value:               float64(100 * time.Millisecond),
+						},
+					},
+				},
+				"frozen_frame_renders": {
+					Unit: "nanosecond",
+					Values: []measurements.MeasurementValue{
+						{
+							ElapsedSinceStartNs: uint64(300 * time.Millisecond),
+							Value:               float64(100 * time.Millisecond),
+						},
+						{
+							ElapsedSinceStartNs: uint64(500 * time.Millisecond),
+							Value:               float64(100 * time.Millisecond),
+						},
+					},
+				},
+			},
+		},
+		Trace: &profile.Android{
+			Threads: []profile.AndroidThread{{ID: 1, Name: "main"}},
+		},
+	})
+	app := func(name string, start, end time.Duration, children ...*nodetree.Node) *nodetree.Node {
+		n := nodetree.NodeFromFrame(
+			frame.Frame{Function: "com.example." + name + "()", Package: "com.example", InApp: &testutil.True},
+			uint64(start),
+			uint64(end),
+			0,
+		)
+		n.Children = children
+		return n
+	}
+	callTrees := map[uint64][]*nodetree.Node{
+		1: {
+			app("Activity.onCreate", 0, 600*time.Millisecond,
+				app("Adapter.bind", 200*time.Millisecond, 290*time.Millisecond),
+				app("Adapter.bind", 400*time.Millisecond, 490*time.Millisecond),
+			),
+		},
+	}
+
+	var occurrences []*Occurrence
+	findFrameDropCause(p, callTrees, &occurrences)
+	// frozen frames are all kept, slow frames once per function
+	if len(occurrences) != 3 {
+		t.Fatalf("expected 3 occurrences, got %d", len(occurrences))
+	}
+	for _, o := range occurrences[:2] {
+		if o.Type != FrameDropType || o.Subtitle != "Adapter.bind()" {
+			t.Fatalf("unexpected occurrence: %s %d", o.Subtitle, o.Type)
+		}
+	}
+	o := occurrences[2]
+	if o.Type != SlowFrameDropType || o.IssueTitle != "Slow Frame" || o.Subtitle != "Adapter.bind()" || o.Event.Platform != platform.Java {
+		t.Fatalf("unexpected occurrence: %s %d %s", o.Subtitle, o.Type, o.Event.Platform)
+	}
+}
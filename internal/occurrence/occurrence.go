@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/getsentry/vroom/internal/android"
+	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/debugmeta"
 	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/platform"
@@ -67,12 +68,46 @@ type (
 		category    Category
 		durationNS  uint64
 		sampleCount int
+
+		// rule is the name of the rule which detected the occurrence.
+		rule string
 	}
 
 	StackTrace struct {
 		Frames []frame.Frame `json:"frames"`
 	}
 
+	// source holds the metadata of the profile or the chunk an occurrence
+	// was detected in.
+	source struct {
+		DebugMeta      debugmeta.DebugMeta
+		DurationNS     uint64
+		Environment    string
+		OrganizationID uint64
+		Platform       platform.Platform
+		ProjectID      uint64
+		Received       time.Time
+		Release        string
+		Tags           map[string]string
+		Timestamp      time.Time
+		// StartNS is the start of the call trees, 0 for transaction profiles
+		// and the absolute start timestamp for chunks.
+		StartNS uint64
+		// MainThreadID and MainThreadName identify the thread frame drops
+		// and hangs are looked for on.
+		MainThreadID   string
+		MainThreadName string
+
+		// Only set for transaction profiles.
+		ProfileID       string
+		TransactionID   string
+		TransactionName string
+
+		// Only set for continuous profile chunks.
+		ChunkID    string
+		ProfilerID string
+	}
+
 	CategoryMetadata struct {
 		IssueTitle IssueTitle
 		Type       Type
@@ -83,6 +118,7 @@ type (
 
 const (
 	NoneType               Type = 0
+	NPlusOneDBQueriesType  Type = 1006
 	CoreDataType           Type = 2004
 	FileIOType             Type = 2001
 	ImageDecodeType        Type = 2002
@@ -92,13 +128,19 @@ const (
 	FrameDropType          Type = 2009
 	FrameRegressionExpType Type = 2010
 	FrameRegressionType    Type = 2011
+	SlowFrameDropType      Type = 2012
+	HangType               Type = 2013
+	SlowFunctionType       Type = 2014
 
 	EvidenceNameDuration       EvidenceName = "Duration"
 	EvidenceNameFunction       EvidenceName = "Suspect function"
 	EvidenceNamePackage        EvidenceName = "Package"
+	EvidenceNameThread         EvidenceName = "Thread"
 	EvidenceFullyQualifiedName EvidenceName = "Fully qualified name"
 	EvidenceBreakpoint         EvidenceName = "Breakpoint"
 	EvidenceRegression         EvidenceName = "Regression"
+	EvidenceSlowerCallees      EvidenceName = "Slower callees"
+	EvidenceNameCalls          EvidenceName = "Calls"
 
 	ContextTrace Context = "trace"
 
@@ -108,6 +150,7 @@ const (
 )
 
 var issueTitles = map[Category]CategoryMetadata{
+	AssociationLoad:  {IssueTitle: "N+1 ActiveRecord Association Load", Type: NPlusOneDBQueriesType},
 	Base64Decode:     {IssueTitle: "Base64 Decode on Main Thread"},
 	Base64Encode:     {IssueTitle: "Base64 Encode on Main Thread"},
 	Compression:      {IssueTitle: "Compression on Main Thread"},
@@ -119,6 +162,7 @@ var issueTitles = map[Category]CategoryMetadata{
 	FileRead:         {IssueTitle: "File I/O on Main Thread"},
 	FileWrite:        {IssueTitle: "File I/O on Main Thread"},
 	FrameDrop:        {IssueTitle: "Frame Drop", Type: FrameDropType},
+	Hang:             {IssueTitle: "Main Thread Hang", Type: HangType},
 	HTTP:             {IssueTitle: "Network I/O on Main Thread"},
 	ImageDecode:      {IssueTitle: "Image Decoding on Main Thread", Type: ImageDecodeType},
 	ImageEncode:      {IssueTitle: "Image Encoding on Main Thread"},
@@ -127,6 +171,8 @@ var issueTitles = map[Category]CategoryMetadata{
 	MLModelInference: {IssueTitle: "Machine Learning inference on Main Thread"},
 	MLModelLoad:      {IssueTitle: "Machine Learning model load on Main Thread"},
 	Regex:            {IssueTitle: "Regex on Main Thread", Type: RegexType},
+	SlowFrameDrop:    {IssueTitle: "Slow Frame", Type: SlowFrameDropType},
+	SlowFunction:     {IssueTitle: "Slow Function on Main Thread", Type: SlowFunctionType},
 	SQL:              {IssueTitle: "SQL operation on Main Thread"},
 	SourceContext:    {IssueTitle: "Adding Source Context is slow"},
 	ThreadWait:       {IssueTitle: "Thread Wait on Main Thread"},
@@ -135,14 +181,76 @@ var issueTitles = map[Category]CategoryMetadata{
 	ViewRender:       {IssueTitle: "SwiftUI View Render is slow", Type: ViewType},
 	ViewUpdate:       {IssueTitle: "SwiftUI View Update is slow", Type: ViewType},
 	XPC:              {IssueTitle: "XPC operation on Main Thread"},
+
+	ServerBase64Decode:  {IssueTitle: "Base64 Decode on Request Thread"},
+	ServerBase64Encode:  {IssueTitle: "Base64 Encode on Request Thread"},
+	ServerCompression:   {IssueTitle: "Compression on Request Thread"},
+	ServerDecompression: {IssueTitle: "Decompression on Request Thread"},
+	ServerFileRead:      {IssueTitle: "File I/O on Request Thread"},
+	ServerFileWrite:     {IssueTitle: "File I/O on Request Thread"},
+	ServerHTTP:          {IssueTitle: "Network I/O on Request Thread"},
+	ServerJSONDecode:    {IssueTitle: "JSON Decoding on Request Thread", Type: JSONDecodeType},
+	ServerJSONEncode:    {IssueTitle: "JSON Encoding on Request Thread"},
+	ServerRegex:         {IssueTitle: "Regex on Request Thread", Type: RegexType},
+	ServerSQL:           {IssueTitle: "SQL operation on Request Thread"},
+	ServerThreadWait:    {IssueTitle: "Thread Wait on Request Thread"},
 }
 
 // NewOccurrence returns an Occurrence struct populated with info.
 func NewOccurrence(p profile.Profile, ni nodeInfo) *Occurrence {
+	return newOccurrence(sourceFromProfile(p), ni)
+}
+
+// sourceFromProfile returns the metadata of a transaction profile.
+func sourceFromProfile(p profile.Profile) source {
 	t := p.Transaction()
+	return source{
+		DebugMeta:       p.DebugMeta(),
+		DurationNS:      p.DurationNS(),
+		Environment:     p.Environment(),
+		MainThreadID:    strconv.FormatUint(t.ActiveThreadID, 10),
+		MainThreadName:  p.ThreadName(t.ActiveThreadID),
+		OrganizationID:  p.OrganizationID(),
+		Platform:        p.Platform(),
+		ProfileID:       p.ID(),
+		ProjectID:       p.ProjectID(),
+		Received:        p.Received(),
+		Release:         p.Release(),
+		Tags:            p.TransactionTags(),
+		Timestamp:       p.Timestamp(),
+		TransactionID:   t.ID,
+		TransactionName: t.Name,
+	}
+}
+
+// sourceFromChunk returns the metadata of a continuous profile chunk.
+func sourceFromChunk(c chunk.Chunk) source {
+	return source{
+		ChunkID:        c.GetID(),
+		DebugMeta:      c.GetDebugMeta(),
+		DurationNS:     uint64(time.Duration(c.DurationMS()) * time.Millisecond),
+		Environment:    c.GetEnvironment(),
+		MainThreadID:   c.MainThreadID(),
+		MainThreadName: c.ThreadName(c.MainThreadID()),
+		OrganizationID: c.GetOrganizationID(),
+		Platform:       c.GetPlatform(),
+		ProfilerID:     c.GetProfilerID(),
+		ProjectID:      c.GetProjectID(),
+		Received:       timeFromSeconds(c.GetReceived()),
+		Release:        c.GetRelease(),
+		StartNS:        uint64(c.StartTimestamp() * 1e9),
+		Timestamp:      timeFromSeconds(c.StartTimestamp()),
+	}
+}
+
+func timeFromSeconds(ts float64) time.Time {
+	return time.Unix(0, int64(ts*1e9)).UTC()
+}
+
+func newOccurrence(src source, ni nodeInfo) *Occurrence {
 	var title IssueTitle
 	var issueType Type
-	cm, exists := issueTitles[ni.Category]
+	cm, exists := rules().categoryMetadata(ni.Category)
 	if exists {
 		issueType = cm.Type
 		title = cm.IssueTitle
@@ -150,7 +258,7 @@ func NewOccurrence(p profile.Profile, ni nodeInfo) *Occurrence {
 		issueType = NoneType
 		title = IssueTitle(fmt.Sprintf("%v issue detected", ni.Category))
 	}
-	pf := p.Platform()
+	pf := src.Platform
 	switch pf {
 	case platform.Android:
 		pf = platform.Java
@@ -161,40 +269,49 @@ func NewOccurrence(p profile.Profile, ni nodeInfo) *Occurrence {
 		)
 	}
 	h := md5.New()
-	_, _ = io.WriteString(h, strconv.FormatUint(p.ProjectID(), 10))
+	_, _ = io.WriteString(h, strconv.FormatUint(src.ProjectID, 10))
 	_, _ = io.WriteString(h, string(title))
 	_, _ = io.WriteString(h, strconv.Itoa(int(issueType)))
-	_, _ = io.WriteString(h, ni.Node.Frame.ModuleOrPackage())
-	_, _ = io.WriteString(h, ni.Node.Name)
+	if ni.GroupByFrameFingerprint {
+		_, _ = io.WriteString(h, strconv.FormatUint(uint64(ni.Node.Frame.Fingerprint()), 10))
+	} else {
+		_, _ = io.WriteString(h, ni.Node.Frame.ModuleOrPackage())
+		_, _ = io.WriteString(h, ni.Node.Name)
+	}
 	fingerprint := fmt.Sprintf("%x", h.Sum(nil))
-	tags := p.TransactionTags()
+	tags := src.Tags
 	if tags == nil {
 		tags = make(map[string]string)
 	}
+	culprit := src.TransactionName
+	if src.ChunkID != "" {
+		// Chunks are not tied to a transaction.
+		culprit = ni.Node.Name
+	}
 	return &Occurrence{
-		Culprit:       t.Name,
+		Culprit:       culprit,
 		DetectionTime: time.Now().UTC(),
 		Event: Event{
-			DebugMeta:      p.DebugMeta(),
-			Environment:    p.Environment(),
+			DebugMeta:      src.DebugMeta,
+			Environment:    src.Environment,
 			ID:             eventID(),
-			OrganizationID: p.OrganizationID(),
+			OrganizationID: src.OrganizationID,
 			Platform:       pf,
-			ProjectID:      p.ProjectID(),
-			Received:       p.Received(),
-			Release:        p.Release(),
+			ProjectID:      src.ProjectID,
+			Received:       src.Received,
+			Release:        src.Release,
 			StackTrace:     StackTrace{Frames: ni.StackTrace},
 			Tags:           tags,
-			Timestamp:      p.Timestamp(),
+			Timestamp:      src.Timestamp,
 		},
-		EvidenceData:    generateEvidenceData(p, ni),
-		EvidenceDisplay: generateEvidenceDisplay(p, ni),
+		EvidenceData:    generateEvidenceData(src, ni),
+		EvidenceDisplay: generateEvidenceDisplay(src, ni),
 		Fingerprint:     []string{fingerprint},
 		ID:              eventID(),
 		IssueTitle:      title,
 		Level:           "info",
 		PayloadType:     OccurrencePayload,
-		ProjectID:       p.ProjectID(),
+		ProjectID:       src.ProjectID,
 		Subtitle:        ni.Node.Name,
 		Type:            issueType,
 		category:        ni.Category,
@@ -203,6 +320,26 @@ func NewOccurrence(p profile.Profile, ni nodeInfo) *Occurrence {
 	}
 }
 
+// Rule returns the name of the rule which detected the occurrence.
+func (o *Occurrence) Rule() string {
+	return o.rule
+}
+
+// Category returns the category of the detected function.
+func (o *Occurrence) Category() Category {
+	return o.category
+}
+
+// DurationNS returns the duration of the detected function.
+func (o *Occurrence) DurationNS() uint64 {
+	return o.durationNS
+}
+
+// SampleCount returns the number of samples the function was found in.
+func (o *Occurrence) SampleCount() int {
+	return o.sampleCount
+}
+
 func FromRegressedFunction(
 	pf platform.Platform,
 	regressed RegressedFunction,
@@ -302,23 +439,50 @@ func normalizeAndroidStackTrace(st []frame.Frame) {
 	}
 }
 
-func generateEvidenceData(p profile.Profile, ni nodeInfo) map[string]interface{} {
-	t := p.Transaction()
+func generateEvidenceData(src source, ni nodeInfo) map[string]interface{} {
 	evidenceData := map[string]interface{}{
 		"frame_duration_ns":   ni.Node.DurationNS,
 		"frame_module":        ni.Node.Frame.Module,
 		"frame_name":          ni.Node.Name,
 		"frame_package":       ni.Node.Frame.Package,
-		"profile_duration_ns": p.DurationNS(),
+		"profile_duration_ns": src.DurationNS,
 		"template_name":       "profile",
-		"transaction_id":      t.ID,
-		"transaction_name":    t.Name,
-		ProfileID:             p.ID(),
+	}
+	if src.ChunkID != "" {
+		evidenceData["chunk_id"] = src.ChunkID
+		evidenceData["profiler_id"] = src.ProfilerID
+		evidenceData["frame_start_ns"] = ni.Node.StartNS
+		evidenceData["frame_end_ns"] = ni.Node.EndNS
+	} else {
+		evidenceData["transaction_id"] = src.TransactionID
+		evidenceData["transaction_name"] = src.TransactionName
+		evidenceData[ProfileID] = src.ProfileID
+	}
+	if ni.ThreadID != "" {
+		evidenceData["thread_id"] = ni.ThreadID
+		if ni.ThreadName != "" {
+			evidenceData["thread_name"] = ni.ThreadName
+		}
+	}
+	if len(ni.Ranges) > 0 {
+		// Ranges are relative to the start of the profile while the slice
+		// uses the time base of the profile, to zoom the flamegraph on it.
+		ranges, slice := relativeRanges(ni.Ranges, src.StartNS)
+		evidenceData["sample_ranges"] = ranges
+		evidenceData["slice_start_ns"] = slice.StartNS
+		evidenceData["slice_end_ns"] = slice.EndNS
+	}
+	if len(ni.Subtree) > 0 {
+		evidenceData["subtree"] = ni.Subtree
+	}
+	if ni.CallCount > 0 {
+		evidenceData["call_count"] = ni.CallCount
+		evidenceData["caller"] = ni.Caller
 	}
 	switch ni.Category {
-	case FrameDrop:
+	case FrameDrop, SlowFrameDrop:
 	default:
-		switch p.Platform() {
+		switch src.Platform {
 		case platform.Android:
 			evidenceData["sample_count"] = ni.Node.SampleCount
 		}
@@ -326,7 +490,7 @@ func generateEvidenceData(p profile.Profile, ni nodeInfo) map[string]interface{}
 	return evidenceData
 }
 
-func generateEvidenceDisplay(p profile.Profile, ni nodeInfo) []Evidence {
+func generateEvidenceDisplay(src source, ni nodeInfo) []Evidence {
 	evidenceDisplay := []Evidence{
 		{
 			Important: true,
@@ -338,14 +502,32 @@ func generateEvidenceDisplay(p profile.Profile, ni nodeInfo) []Evidence {
 			Value: ni.Node.Package,
 		},
 	}
+	if ni.ThreadID != "" {
+		thread := ni.ThreadID
+		if ni.ThreadName != "" {
+			thread = fmt.Sprintf("%s (%s)", ni.ThreadName, ni.ThreadID)
+		}
+		evidenceDisplay = append(evidenceDisplay, Evidence{
+			Name:  EvidenceNameThread,
+			Value: thread,
+		})
+	}
+	if ni.CallCount > 0 {
+		evidenceDisplay = append(evidenceDisplay, Evidence{
+			Name:  EvidenceNameCalls,
+			Value: fmt.Sprintf("%d calls from %s", ni.CallCount, ni.Caller),
+		})
+	}
 	switch ni.Category {
-	case FrameDrop:
+	case FrameDrop, SlowFrameDrop:
 	default:
 		nodeDuration := time.Duration(ni.Node.DurationNS).Round(10 * time.Microsecond)
-		profilePercentage := float64(ni.Node.DurationNS*100) / float64(p.DurationNS())
+		profilePercentage := float64(ni.Node.DurationNS*100) / float64(src.DurationNS)
 		var duration string
-		switch p.Platform() {
-		case platform.Android:
+		switch {
+		case src.DurationNS == 0:
+			duration = nodeDuration.String()
+		case src.Platform == platform.Android:
 			duration = fmt.Sprintf(
 				"%s (%0.2f%% of the profile)",
 				nodeDuration,
@@ -3,10 +3,15 @@ package occurrence
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/getsentry/vroom/internal/chunk"
 	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/profile"
 	"github.com/getsentry/vroom/internal/storageutil"
@@ -15,19 +20,23 @@ import (
 )
 
 type RegressedFunction struct {
-	OrganizationID           uint64                `json:"organization_id"`
-	ProjectID                uint64                `json:"project_id"`
-	ProfileID                string                `json:"profile_id"`
-	Example                  utils.ExampleMetadata `json:"example"`
-	Fingerprint              uint32                `json:"fingerprint"`
-	AbsolutePercentageChange float64               `json:"absolute_percentage_change"`
-	AggregateRange1          float64               `json:"aggregate_range_1"`
-	AggregateRange2          float64               `json:"aggregate_range_2"`
-	Breakpoint               uint64                `json:"breakpoint"`
-	TrendDifference          float64               `json:"trend_difference"`
-	TrendPercentage          float64               `json:"trend_percentage"`
-	UnweightedPValue         float64               `json:"unweighted_p_value"`
-	UnweightedTValue         float64               `json:"unweighted_t_value"`
+	OrganizationID uint64                `json:"organization_id"`
+	ProjectID      uint64                `json:"project_id"`
+	ProfileID      string                `json:"profile_id"`
+	Example        utils.ExampleMetadata `json:"example"`
+	// ExamplesBefore and ExamplesAfter are examples from both sides of
+	// the breakpoint, used to explain the regression.
+	ExamplesBefore           []utils.ExampleMetadata `json:"examples_before,omitempty"`
+	ExamplesAfter            []utils.ExampleMetadata `json:"examples_after,omitempty"`
+	Fingerprint              uint32                  `json:"fingerprint"`
+	AbsolutePercentageChange float64                 `json:"absolute_percentage_change"`
+	AggregateRange1          float64                 `json:"aggregate_range_1"`
+	AggregateRange2          float64                 `json:"aggregate_range_2"`
+	Breakpoint               uint64                  `json:"breakpoint"`
+	TrendDifference          float64                 `json:"trend_difference"`
+	TrendPercentage          float64                 `json:"trend_percentage"`
+	UnweightedPValue         float64                 `json:"unweighted_p_value"`
+	UnweightedTValue         float64                 `json:"unweighted_t_value"`
 }
 
 func ProcessRegressedFunction(
@@ -36,6 +45,10 @@ func ProcessRegressedFunction(
 	regressedFunction RegressedFunction,
 	jobs chan storageutil.ReadJob,
 ) (*Occurrence, error) {
+	if len(regressedFunction.ExamplesBefore) > 0 || len(regressedFunction.ExamplesAfter) > 0 {
+		return processRegressedFunctionExamples(ctx, profilesBucket, regressedFunction, jobs)
+	}
+
 	results := make(chan storageutil.ReadJobResult, 1)
 	defer close(results)
 
@@ -114,3 +127,271 @@ func getPlatformAndFrame(
 
 	return platform, frame, nil
 }
+
+type (
+	// calls holds the time spent in the callers and callees of a function,
+	// aggregated over the examples of one side of a breakpoint.
+	calls struct {
+		examples int
+		callers  map[nodeKey]uint64
+		callees  map[nodeKey]uint64
+	}
+
+	// callDiff is the change of the average time spent per example in
+	// a caller or a callee of a regressed function.
+	callDiff struct {
+		Name         string `json:"name"`
+		Package      string `json:"package"`
+		BeforeNS     uint64 `json:"before_ns"`
+		AfterNS      uint64 `json:"after_ns"`
+		DifferenceNS int64  `json:"difference_ns"`
+	}
+)
+
+const (
+	maxCallDiffs int = 10
+	// maxDisplayedCallees is the number of slower callees shown
+	// in the evidence display.
+	maxDisplayedCallees int = 3
+)
+
+func newCalls() *calls {
+	return &calls{
+		callers: make(map[nodeKey]uint64),
+		callees: make(map[nodeKey]uint64),
+	}
+}
+
+// processRegressedFunctionExamples reads the examples from both sides of
+// the breakpoint and adds the change of the time spent in the callers and
+// callees of the regressed function to the occurrence.
+func processRegressedFunctionExamples(
+	ctx context.Context,
+	profilesBucket *blob.Bucket,
+	regressedFunction RegressedFunction,
+	jobs chan storageutil.ReadJob,
+) (*Occurrence, error) {
+	var pf platform.Platform
+	var f frame.Frame
+	var found bool
+	sides := make([]*calls, 0, 2)
+	for _, examples := range [][]utils.ExampleMetadata{
+		regressedFunction.ExamplesBefore,
+		regressedFunction.ExamplesAfter,
+	} {
+		c := newCalls()
+		err := c.addExamples(ctx, profilesBucket, regressedFunction, examples, jobs, func(res storageutil.ReadJobResult) {
+			if found {
+				return
+			}
+			p, fr, err := getPlatformAndFrame(ctx, res, regressedFunction.Fingerprint)
+			if err == nil {
+				pf, f, found = p, fr, true
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		sides = append(sides, c)
+	}
+	if !found {
+		return nil, frame.ErrFrameNotFound
+	}
+
+	o := FromRegressedFunction(pf, regressedFunction, f)
+	before, after := sides[0], sides[1]
+	o.EvidenceData["examples_before"] = before.examples
+	o.EvidenceData["examples_after"] = after.examples
+	if before.examples == 0 || after.examples == 0 {
+		return o, nil
+	}
+	o.EvidenceData["caller_diff"] = diffCalls(before.callers, before.examples, after.callers, after.examples)
+	calleeDiff := diffCalls(before.callees, before.examples, after.callees, after.examples)
+	o.EvidenceData["callee_diff"] = calleeDiff
+	if e, ok := slowerCalleesEvidence(pf, calleeDiff); ok {
+		o.EvidenceDisplay = append(o.EvidenceDisplay, e)
+	}
+	return o, nil
+}
+
+// addExamples reads the examples and aggregates the callers and callees of
+// the function, calling onResult for each example successfully read.
+func (c *calls) addExamples(
+	ctx context.Context,
+	profilesBucket *blob.Bucket,
+	regressedFunction RegressedFunction,
+	examples []utils.ExampleMetadata,
+	jobs chan storageutil.ReadJob,
+	onResult func(storageutil.ReadJobResult),
+) error {
+	if len(examples) == 0 {
+		return nil
+	}
+	hub := sentry.GetHubFromContext(ctx)
+	results := make(chan storageutil.ReadJobResult, len(examples))
+	defer close(results)
+
+	for _, example := range examples {
+		projectID := example.ProjectID
+		if projectID == 0 {
+			projectID = regressedFunction.ProjectID
+		}
+		if example.ProfileID != "" {
+			jobs <- profile.ReadJob{
+				Ctx:            ctx,
+				OrganizationID: regressedFunction.OrganizationID,
+				ProjectID:      projectID,
+				ProfileID:      example.ProfileID,
+				Storage:        profilesBucket,
+				Result:         results,
+			}
+		} else {
+			jobs <- chunk.ReadJob{
+				Ctx:            ctx,
+				OrganizationID: regressedFunction.OrganizationID,
+				ProjectID:      projectID,
+				ProfilerID:     example.ProfilerID,
+				ChunkID:        example.ChunkID,
+				TransactionID:  example.TransactionID,
+				ThreadID:       example.ThreadID,
+				Start:          uint64(example.Start * 1e9),
+				End:            uint64(example.End * 1e9),
+				Storage:        profilesBucket,
+				Result:         results,
+			}
+		}
+	}
+
+	for i := 0; i < len(examples); i++ {
+		res := <-results
+
+		err := res.Error()
+		if err != nil {
+			if errors.Is(err, storageutil.ErrObjectNotFound) {
+				continue
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			if hub != nil {
+				hub.CaptureException(err)
+			}
+			continue
+		}
+
+		if result, ok := res.(profile.ReadJobResult); ok {
+			callTrees, err := result.Profile.CallTrees()
+			if err != nil {
+				if hub != nil {
+					hub.CaptureException(err)
+				}
+				continue
+			}
+			addCallTrees(c, callTrees, regressedFunction.Fingerprint)
+		} else if result, ok := res.(chunk.ReadJobResult); ok {
+			callTrees, err := result.Chunk.CallTrees(result.ThreadID)
+			if err != nil {
+				if hub != nil {
+					hub.CaptureException(err)
+				}
+				continue
+			}
+			addCallTrees(c, callTrees, regressedFunction.Fingerprint)
+		} else {
+			// This should never happen
+			return errors.New("unexpected result from storage")
+		}
+		onResult(res)
+	}
+	return nil
+}
+
+func addCallTrees[T comparable](c *calls, callTrees map[T][]*nodetree.Node, target uint32) {
+	c.examples++
+	for _, callTreesForThread := range callTrees {
+		for _, root := range callTreesForThread {
+			c.addNode(root, nil, target)
+		}
+	}
+}
+
+// addNode adds the callers and callees of the outermost calls to the target
+// function, recursive calls being already accounted for.
+func (c *calls) addNode(n, parent *nodetree.Node, target uint32) {
+	if n.Frame.Fingerprint() != target {
+		for _, child := range n.Children {
+			c.addNode(child, n, target)
+		}
+		return
+	}
+	if parent != nil {
+		c.callers[nodeKey{Package: parent.Package, Function: parent.Name}] += n.DurationNS
+	}
+	for _, child := range n.Children {
+		c.callees[nodeKey{Package: child.Package, Function: child.Name}] += child.DurationNS
+	}
+}
+
+// diffCalls compares the average time spent per example in each function
+// and returns the functions which changed the most, slower ones first.
+func diffCalls(
+	before map[nodeKey]uint64,
+	beforeExamples int,
+	after map[nodeKey]uint64,
+	afterExamples int,
+) []callDiff {
+	diffs := make([]callDiff, 0, len(after))
+	add := func(k nodeKey) {
+		d := callDiff{
+			Name:     k.Function,
+			Package:  k.Package,
+			BeforeNS: before[k] / uint64(beforeExamples),
+			AfterNS:  after[k] / uint64(afterExamples),
+		}
+		d.DifferenceNS = int64(d.AfterNS) - int64(d.BeforeNS)
+		diffs = append(diffs, d)
+	}
+	for k := range after {
+		add(k)
+	}
+	for k := range before {
+		if _, exists := after[k]; !exists {
+			add(k)
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].DifferenceNS != diffs[j].DifferenceNS {
+			return diffs[i].DifferenceNS > diffs[j].DifferenceNS
+		}
+		if diffs[i].Package != diffs[j].Package {
+			return diffs[i].Package < diffs[j].Package
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	if len(diffs) > maxCallDiffs {
+		diffs = diffs[:maxCallDiffs]
+	}
+	return diffs
+}
+
+func slowerCalleesEvidence(pf platform.Platform, diffs []callDiff) (Evidence, bool) {
+	var callees []string
+	for _, d := range diffs {
+		if d.DifferenceNS <= 0 || len(callees) == maxDisplayedCallees {
+			break
+		}
+		name := frame.Frame{Function: d.Name, Package: d.Package}.FullyQualifiedName(pf)
+		callees = append(callees, fmt.Sprintf(
+			"%s (+%s)",
+			name,
+			time.Duration(d.DifferenceNS).Round(10*time.Microsecond),
+		))
+	}
+	if len(callees) == 0 {
+		return Evidence{}, false
+	}
+	return Evidence{
+		Name:  EvidenceSlowerCallees,
+		Value: strings.Join(callees, ", "),
+	}, true
+}
@@ -0,0 +1,91 @@
+package occurrence
+
+import (
+	"testing"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/nodetree"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestDiffCallsAroundRegressedFunction(t *testing.T) {
+	node := func(function string, durationNS uint64, children ...*nodetree.Node) *nodetree.Node {
+		return &nodetree.Node{
+			Children:   children,
+			DurationNS: durationNS,
+			Frame:      frame.Frame{Function: function, Package: "package"},
+			Name:       function,
+			Package:    "package",
+		}
+	}
+	target := frame.Frame{Function: "regressed", Package: "package"}.Fingerprint()
+
+	before := newCalls()
+	addCallTrees(before, map[uint64][]*nodetree.Node{
+		1: {
+			node("main", 100,
+				node("regressed", 40,
+					node("parse", 20),
+					node("read", 20),
+				),
+			),
+		},
+	}, target)
+	addCallTrees(before, map[uint64][]*nodetree.Node{
+		1: {
+			node("main", 100,
+				node("regressed", 40,
+					node("parse", 20),
+					node("read", 20),
+				),
+			),
+		},
+	}, target)
+
+	after := newCalls()
+	addCallTrees(after, map[uint64][]*nodetree.Node{
+		1: {
+			node("main", 200,
+				node("regressed", 120,
+					node("parse", 20),
+					node("validate", 90,
+						// recursive calls are only counted once
+						node("regressed", 90,
+							node("read", 90),
+						),
+					),
+				),
+			),
+		},
+	}, target)
+
+	callees := diffCalls(before.callees, before.examples, after.callees, after.examples)
+	wantCallees := []callDiff{
+		{Name: "validate", Package: "package", AfterNS: 90, DifferenceNS: 90},
+		{Name: "parse", Package: "package", BeforeNS: 20, AfterNS: 20},
+		{Name: "read", Package: "package", BeforeNS: 20, DifferenceNS: -20},
+	}
+	if diff := testutil.Diff(callees, wantCallees); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+
+	callers := diffCalls(before.callers, before.examples, after.callers, after.examples)
+	wantCallers := []callDiff{
+		{Name: "main", Package: "package", BeforeNS: 40, AfterNS: 120, DifferenceNS: 80},
+	}
+	if diff := testutil.Diff(callers, wantCallers); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+
+	e, ok := slowerCalleesEvidence(platform.Python, []callDiff{
+		{Name: "validate", Package: "package", AfterNS: 90_000_000, DifferenceNS: 90_000_000},
+		{Name: "read", Package: "package", BeforeNS: 20_000_000, DifferenceNS: -20_000_000},
+	})
+	if !ok {
+		t.Fatal("expected slower callees")
+	}
+	if e.Value != "package.validate (+90ms)" {
+		t.Fatalf("unexpected evidence: %s", e.Value)
+	}
+}
@@ -0,0 +1,282 @@
+package occurrence
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/getsentry/vroom/internal/platform"
+)
+
+type (
+	// RuleSet holds the detection jobs to run per platform and the metadata
+	// of the categories they produce.
+	RuleSet struct {
+		Categories map[Category]CategoryMetadata
+		Jobs       map[platform.Platform][]DetectFrameOptions
+		// JobNames holds the name of each job, by platform and in the same
+		// order as Jobs. Built-in jobs don't have a name.
+		JobNames map[platform.Platform][]string
+		// HangThreshold is the minimum duration the stack of the main thread
+		// has to stay the same to be considered as a hang. It's 0 for the
+		// built-in rules, hangs then not being detected: it has to be set
+		// with hang_threshold in the rules config.
+		HangThreshold time.Duration
+	}
+
+	// RulesConfig is the declarative representation of detection rules,
+	// loaded from a YAML or JSON document.
+	RulesConfig struct {
+		// ReplaceBuiltin disables the built-in rules and categories when set.
+		ReplaceBuiltin bool                        `yaml:"replace_builtin"`
+		Categories     map[Category]CategoryConfig `yaml:"categories"`
+		Rules          []RuleConfig                `yaml:"rules"`
+		// HangThreshold enables the detection of hangs on the main thread of
+		// UI platforms when set, hangs being off by default.
+		HangThreshold time.Duration `yaml:"hang_threshold"`
+	}
+
+	CategoryConfig struct {
+		IssueTitle IssueTitle `yaml:"issue_title"`
+		Type       Type       `yaml:"type"`
+	}
+
+	RuleConfig struct {
+		Name      string              `yaml:"name"`
+		Platforms []platform.Platform `yaml:"platforms"`
+		// Matcher selects how frames are matched: "exact" matches on package
+		// and function name, "android" strips the method signature first,
+		// "java" matches on the fully qualified method name and "in_app"
+		// matches any application frame with a high self time. No built-in
+		// rule uses "in_app", slow functions are only detected when
+		// configured.
+		Matcher            string                         `yaml:"matcher"`
+		ActiveThreadOnly   bool                           `yaml:"active_thread_only"`
+		DurationThreshold  time.Duration                  `yaml:"duration_threshold"`
+		SampleThreshold    int                            `yaml:"sample_threshold"`
+		FunctionsByPackage map[string]map[string]Category `yaml:"functions_by_package"`
+
+		// Options only used by the "in_app" matcher, DurationThreshold
+		// being the minimum self time of the frame.
+		Category        Category `yaml:"category"`
+		MinProfileRatio float64  `yaml:"min_profile_ratio"`
+		MaxOccurrences  int      `yaml:"max_occurrences"`
+	}
+)
+
+const (
+	MatcherExact   = "exact"
+	MatcherAndroid = "android"
+	MatcherJava    = "java"
+	MatcherInApp   = "in_app"
+)
+
+var (
+	currentRuleSet atomic.Pointer[RuleSet]
+
+	supportedPlatforms = map[platform.Platform]struct{}{
+		platform.Android:    {},
+		platform.Cocoa:      {},
+		platform.CSharp:     {},
+		platform.Go:         {},
+		platform.Java:       {},
+		platform.JavaScript: {},
+		platform.Node:       {},
+		platform.PHP:        {},
+		platform.Python:     {},
+		platform.Ruby:       {},
+		platform.Rust:       {},
+	}
+)
+
+// DefaultRuleSet returns the built-in rules.
+func DefaultRuleSet() *RuleSet {
+	return &RuleSet{
+		Categories: issueTitles,
+		Jobs:       detectFrameJobs,
+	}
+}
+
+// SetRuleSet replaces the rules used to detect occurrences. Passing nil
+// restores the built-in rules. It's safe to call concurrently with Find.
+func SetRuleSet(rs *RuleSet) {
+	currentRuleSet.Store(rs)
+}
+
+func rules() *RuleSet {
+	if rs := currentRuleSet.Load(); rs != nil {
+		return rs
+	}
+	return DefaultRuleSet()
+}
+
+// jobName returns the name of a job, generating one for built-in jobs.
+func (rs *RuleSet) jobName(p platform.Platform, i int) string {
+	if names := rs.JobNames[p]; i < len(names) && names[i] != "" {
+		return names[i]
+	}
+	return fmt.Sprintf("builtin:%s:%d", p, i)
+}
+
+func (rs *RuleSet) categoryMetadata(c Category) (CategoryMetadata, bool) {
+	cm, exists := rs.Categories[c]
+	return cm, exists
+}
+
+// ParseRules decodes rules from a YAML or JSON document, validates them
+// and merges them with the built-in rules.
+func ParseRules(b []byte) (*RuleSet, error) {
+	var config RulesConfig
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	if err := d.Decode(&config); err != nil {
+		return nil, fmt.Errorf("can't decode rules: %w", err)
+	}
+	return config.RuleSet()
+}
+
+// RuleSet validates the configuration and returns the resulting rules.
+func (config RulesConfig) RuleSet() (*RuleSet, error) {
+	rs := RuleSet{
+		Categories: make(map[Category]CategoryMetadata),
+		Jobs:       make(map[platform.Platform][]DetectFrameOptions),
+		JobNames:   make(map[platform.Platform][]string),
+	}
+	if !config.ReplaceBuiltin {
+		for c, cm := range issueTitles {
+			rs.Categories[c] = cm
+		}
+		for p, jobs := range detectFrameJobs {
+			rs.Jobs[p] = append([]DetectFrameOptions{}, jobs...)
+			rs.JobNames[p] = make([]string, len(jobs))
+		}
+	}
+
+	var errs []error
+	if config.HangThreshold < 0 {
+		errs = append(errs, errors.New("hang_threshold can't be negative"))
+	} else {
+		rs.HangThreshold = config.HangThreshold
+	}
+	for c, cc := range config.Categories {
+		if c == "" {
+			errs = append(errs, errors.New("category name can't be empty"))
+			continue
+		}
+		if cc.IssueTitle == "" {
+			errs = append(errs, fmt.Errorf("category %q: issue_title is required", c))
+			continue
+		}
+		rs.Categories[c] = CategoryMetadata{IssueTitle: cc.IssueTitle, Type: cc.Type}
+	}
+
+	for i, rule := range config.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		options, err := rule.options(rs.Categories)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", name, err))
+			continue
+		}
+		for _, p := range rule.Platforms {
+			rs.Jobs[p] = append(rs.Jobs[p], options)
+			rs.JobNames[p] = append(rs.JobNames[p], name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &rs, nil
+}
+
+func (rule RuleConfig) options(categories map[Category]CategoryMetadata) (DetectFrameOptions, error) {
+	if len(rule.Platforms) == 0 {
+		return nil, errors.New("at least one platform is required")
+	}
+	for _, p := range rule.Platforms {
+		if _, exists := supportedPlatforms[p]; !exists {
+			return nil, fmt.Errorf("unknown platform %q", p)
+		}
+	}
+	if rule.DurationThreshold < 0 {
+		return nil, errors.New("duration_threshold can't be negative")
+	}
+	if rule.SampleThreshold < 0 {
+		return nil, errors.New("sample_threshold can't be negative")
+	}
+	if rule.Matcher == MatcherInApp {
+		return rule.inAppOptions(categories)
+	}
+	if len(rule.FunctionsByPackage) == 0 {
+		return nil, errors.New("functions_by_package can't be empty")
+	}
+	for pkg, functions := range rule.FunctionsByPackage {
+		if len(functions) == 0 {
+			return nil, fmt.Errorf("package %q has no functions", pkg)
+		}
+		for function, c := range functions {
+			if _, exists := categories[c]; !exists {
+				return nil, fmt.Errorf("function %q in package %q has unknown category %q", function, pkg, c)
+			}
+		}
+	}
+
+	switch rule.Matcher {
+	case "", MatcherExact:
+		return DetectExactFrameOptions{
+			ActiveThreadOnly:   rule.ActiveThreadOnly,
+			DurationThreshold:  rule.DurationThreshold,
+			FunctionsByPackage: rule.FunctionsByPackage,
+			SampleThreshold:    rule.SampleThreshold,
+		}, nil
+	case MatcherAndroid:
+		return DetectAndroidFrameOptions{
+			ActiveThreadOnly:   rule.ActiveThreadOnly,
+			DurationThreshold:  rule.DurationThreshold,
+			FunctionsByPackage: rule.FunctionsByPackage,
+			SampleThreshold:    rule.SampleThreshold,
+		}, nil
+	case MatcherJava:
+		return DetectJavaFrameOptions{
+			ActiveThreadOnly:   rule.ActiveThreadOnly,
+			DurationThreshold:  rule.DurationThreshold,
+			FunctionsByPackage: rule.FunctionsByPackage,
+			SampleThreshold:    rule.SampleThreshold,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown matcher %q", rule.Matcher)
+}
+
+func (rule RuleConfig) inAppOptions(categories map[Category]CategoryMetadata) (DetectFrameOptions, error) {
+	if len(rule.FunctionsByPackage) > 0 {
+		return nil, errors.New("functions_by_package can't be used with the in_app matcher")
+	}
+	if rule.MinProfileRatio < 0 || rule.MinProfileRatio > 1 {
+		return nil, errors.New("min_profile_ratio has to be between 0 and 1")
+	}
+	if rule.MaxOccurrences < 0 {
+		return nil, errors.New("max_occurrences can't be negative")
+	}
+	category := rule.Category
+	if category == "" {
+		category = SlowFunction
+	}
+	if _, exists := categories[category]; !exists {
+		return nil, fmt.Errorf("unknown category %q", category)
+	}
+	return DetectInAppFrameOptions{
+		ActiveThreadOnly:  rule.ActiveThreadOnly,
+		Category:          category,
+		SelfTimeThreshold: rule.DurationThreshold,
+		MinProfileRatio:   rule.MinProfileRatio,
+		MaxOccurrences:    rule.MaxOccurrences,
+		SampleThreshold:   rule.SampleThreshold,
+	}, nil
+}
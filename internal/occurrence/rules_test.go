@@ -0,0 +1,212 @@
+package occurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		rules          string
+		wantErr        string
+		wantCategories map[Category]CategoryMetadata
+		wantJobs       map[platform.Platform][]DetectFrameOptions
+	}{
+		{
+			name: "yaml with a new category",
+			rules: `
+replace_builtin: true
+categories:
+  slow_hash:
+    issue_title: Hashing on Main Thread
+    type: 2001
+rules:
+  - name: hashing
+    platforms: [cocoa, rust]
+    active_thread_only: true
+    duration_threshold: 40ms
+    sample_threshold: 2
+    functions_by_package:
+      libcommonCrypto.dylib:
+        CC_SHA256: slow_hash
+`,
+			wantCategories: map[Category]CategoryMetadata{
+				"slow_hash": {IssueTitle: "Hashing on Main Thread", Type: FileIOType},
+			},
+			wantJobs: map[platform.Platform][]DetectFrameOptions{
+				platform.Cocoa: {
+					DetectExactFrameOptions{
+						ActiveThreadOnly:  true,
+						DurationThreshold: 40 * time.Millisecond,
+						SampleThreshold:   2,
+						FunctionsByPackage: map[string]map[string]Category{
+							"libcommonCrypto.dylib": {"CC_SHA256": "slow_hash"},
+						},
+					},
+				},
+				platform.Rust: {
+					DetectExactFrameOptions{
+						ActiveThreadOnly:  true,
+						DurationThreshold: 40 * time.Millisecond,
+						SampleThreshold:   2,
+						FunctionsByPackage: map[string]map[string]Category{
+							"libcommonCrypto.dylib": {"CC_SHA256": "slow_hash"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "json referencing a replaced built-in category",
+			rules: `{
+				"replace_builtin": true,
+				"rules": [{
+					"platforms": ["android"],
+					"matcher": "android",
+					"functions_by_package": {"com.google.gson": {"com.google.gson.Gson.fromJson": "json_decode"}}
+				}]
+			}`,
+			wantErr: `unknown category "json_decode"`,
+		},
+		{
+			name: "unknown platform",
+			rules: `
+rules:
+  - platforms: [cobol]
+    functions_by_package:
+      pkg:
+        fn: json_decode
+`,
+			wantErr: `unknown platform "cobol"`,
+		},
+		{
+			name: "unknown matcher",
+			rules: `
+rules:
+  - platforms: [node]
+    matcher: fuzzy
+    functions_by_package:
+      pkg:
+        fn: json_decode
+`,
+			wantErr: `unknown matcher "fuzzy"`,
+		},
+		{
+			name: "in-app matcher",
+			rules: `
+replace_builtin: true
+categories:
+  slow_function:
+    issue_title: Slow Function on Main Thread
+    type: 2001
+rules:
+  - platforms: [cocoa]
+    matcher: in_app
+    active_thread_only: true
+    duration_threshold: 100ms
+    min_profile_ratio: 0.1
+    max_occurrences: 3
+`,
+			wantCategories: map[Category]CategoryMetadata{
+				SlowFunction: {IssueTitle: "Slow Function on Main Thread", Type: FileIOType},
+			},
+			wantJobs: map[platform.Platform][]DetectFrameOptions{
+				platform.Cocoa: {
+					DetectInAppFrameOptions{
+						ActiveThreadOnly:  true,
+						Category:          SlowFunction,
+						SelfTimeThreshold: 100 * time.Millisecond,
+						MinProfileRatio:   0.1,
+						MaxOccurrences:    3,
+					},
+				},
+			},
+		},
+		{
+			name: "in-app matcher with an invalid ratio",
+			rules: `
+rules:
+  - platforms: [cocoa]
+    matcher: in_app
+    min_profile_ratio: 10
+`,
+			wantErr: "min_profile_ratio has to be between 0 and 1",
+		},
+		{
+			name: "unknown field",
+			rules: `
+rules:
+  - platform: node
+`,
+			wantErr: "field platform not found",
+		},
+		{
+			name: "category without title",
+			rules: `
+categories:
+  slow_hash:
+    type: 2001
+`,
+			wantErr: "issue_title is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := ParseRules([]byte(tt.rules))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := testutil.Diff(rs.Categories, tt.wantCategories); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+			if diff := testutil.Diff(rs.Jobs, tt.wantJobs); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseRulesMergesBuiltin(t *testing.T) {
+	rs, err := ParseRules([]byte(`
+hang_threshold: 5s
+rules:
+  - platforms: [node]
+    functions_by_package:
+      "node:crypto":
+        pbkdf2Sync: thread_wait
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := len(rs.Jobs[platform.Node]), len(detectFrameJobs[platform.Node])+1; got != want {
+		t.Fatalf("expected %d jobs for node, got %d", want, got)
+	}
+	if got, want := len(rs.Jobs[platform.Cocoa]), len(detectFrameJobs[platform.Cocoa]); got != want {
+		t.Fatalf("expected %d jobs for cocoa, got %d", want, got)
+	}
+	if _, exists := rs.Categories[FrameDrop]; !exists {
+		t.Fatal("expected built-in categories to be kept")
+	}
+	builtin := len(detectFrameJobs[platform.Node])
+	if got := rs.jobName(platform.Node, builtin); got != "#0" {
+		t.Fatalf("expected the configured rule to be named #0, got %q", got)
+	}
+	if got := rs.jobName(platform.Node, 0); got != "builtin:node:0" {
+		t.Fatalf("expected a built-in rule name, got %q", got)
+	}
+	if rs.HangThreshold != 5*time.Second {
+		t.Fatalf("expected a hang threshold of 5s, got %s", rs.HangThreshold)
+	}
+}
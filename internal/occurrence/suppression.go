@@ -0,0 +1,190 @@
+package occurrence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// SuppressionStore holds the counters used to suppress occurrences.
+	// Implementations shared between instances (Redis, Memcached, ...) allow
+	// to apply the limits globally instead of per instance.
+	SuppressionStore interface {
+		// Increment increments the counter stored at key and returns its new
+		// value. A new counter expires after the given duration.
+		Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
+		// Reset sets the counter stored at key to 0 and returns its previous value.
+		Reset(ctx context.Context, key string) (int64, error)
+	}
+
+	SuppressionOptions struct {
+		// Window is the duration over which the limits are applied.
+		Window time.Duration
+		// MaxPerFingerprint is the number of occurrences sent per fingerprint
+		// and per window.
+		MaxPerFingerprint int64
+		// ProjectBudget is the number of occurrences sent per project and
+		// per window, no limit if 0.
+		ProjectBudget int64
+	}
+
+	// Suppressor samples occurrences sharing the same fingerprint and limits
+	// the number of occurrences sent per project.
+	Suppressor struct {
+		options SuppressionOptions
+		store   SuppressionStore
+		now     func() time.Time
+	}
+
+	// MemorySuppressionStore is an in-process SuppressionStore.
+	MemorySuppressionStore struct {
+		mu        sync.Mutex
+		counters  map[string]memoryCounter
+		nextSweep time.Time
+		now       func() time.Time
+	}
+
+	memoryCounter struct {
+		value     int64
+		expiresAt time.Time
+	}
+)
+
+const (
+	// EvidenceSuppressedCount is the evidence data key holding the number
+	// of occurrences suppressed with the same fingerprint since the last
+	// one sent.
+	EvidenceSuppressedCount = "suppressed_count"
+
+	// suppressedCountExpiration is how long we keep the number of suppressed
+	// occurrences of a fingerprint, waiting for one to be sent.
+	suppressedCountExpiration = 24 * time.Hour
+
+	memorySweepInterval = time.Minute
+)
+
+var ErrInvalidSuppressionOptions = errors.New("suppression window and max per fingerprint have to be positive")
+
+func NewSuppressor(store SuppressionStore, options SuppressionOptions) (*Suppressor, error) {
+	if options.Window <= 0 || options.MaxPerFingerprint <= 0 || options.ProjectBudget < 0 {
+		return nil, ErrInvalidSuppressionOptions
+	}
+	return &Suppressor{
+		options: options,
+		store:   store,
+		now:     time.Now,
+	}, nil
+}
+
+// Filter returns the occurrences to send, adding the number of occurrences
+// suppressed since the last one sent to their evidence data.
+// In case of errors from the store, occurrences are sent.
+func (s *Suppressor) Filter(ctx context.Context, occurrences []*Occurrence) ([]*Occurrence, error) {
+	window := s.now().UnixNano() / int64(s.options.Window)
+	filtered := occurrences[:0]
+	var errs []error
+	for _, o := range occurrences {
+		fingerprint := strings.Join(o.Fingerprint, ",")
+		send, err := s.allow(ctx, o.ProjectID, fingerprint, window)
+		if err != nil {
+			errs = append(errs, err)
+			filtered = append(filtered, o)
+			continue
+		}
+		suppressedKey := fmt.Sprintf("suppressed:%d:%s", o.ProjectID, fingerprint)
+		if !send {
+			if _, err := s.store.Increment(ctx, suppressedKey, suppressedCountExpiration); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		suppressed, err := s.store.Reset(ctx, suppressedKey)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if o.EvidenceData == nil {
+			o.EvidenceData = make(map[string]interface{})
+		}
+		o.EvidenceData[EvidenceSuppressedCount] = suppressed
+		filtered = append(filtered, o)
+	}
+	return filtered, errors.Join(errs...)
+}
+
+func (s *Suppressor) allow(ctx context.Context, projectID uint64, fingerprint string, window int64) (bool, error) {
+	count, err := s.store.Increment(
+		ctx,
+		fmt.Sprintf("fingerprint:%d:%s:%d", projectID, fingerprint, window),
+		s.options.Window,
+	)
+	if err != nil {
+		return false, err
+	}
+	if count > s.options.MaxPerFingerprint {
+		return false, nil
+	}
+	if s.options.ProjectBudget == 0 {
+		return true, nil
+	}
+	count, err = s.store.Increment(
+		ctx,
+		fmt.Sprintf("project:%d:%d", projectID, window),
+		s.options.Window,
+	)
+	if err != nil {
+		return false, err
+	}
+	return count <= s.options.ProjectBudget, nil
+}
+
+func NewMemorySuppressionStore() *MemorySuppressionStore {
+	return &MemorySuppressionStore{
+		counters: make(map[string]memoryCounter),
+		now:      time.Now,
+	}
+}
+
+func (m *MemorySuppressionStore) Increment(_ context.Context, key string, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	c, exists := m.counters[key]
+	if !exists || !now.Before(c.expiresAt) {
+		c = memoryCounter{expiresAt: now.Add(expiration)}
+	}
+	c.value++
+	m.counters[key] = c
+	return c.value, nil
+}
+
+func (m *MemorySuppressionStore) Reset(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, exists := m.counters[key]
+	if !exists {
+		return 0, nil
+	}
+	delete(m.counters, key)
+	if !m.now().Before(c.expiresAt) {
+		return 0, nil
+	}
+	return c.value, nil
+}
+
+// sweep removes expired counters, at most once per sweep interval.
+func (m *MemorySuppressionStore) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	for key, c := range m.counters {
+		if !now.Before(c.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+	m.nextSweep = now.Add(memorySweepInterval)
+}
@@ -0,0 +1,99 @@
+package occurrence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestSuppressorFilter(t *testing.T) {
+	type batch struct {
+		at           time.Duration
+		fingerprints []string
+	}
+	type sent struct {
+		Fingerprint string
+		Suppressed  int64
+	}
+
+	tests := []struct {
+		name    string
+		options SuppressionOptions
+		batches []batch
+		want    [][]sent
+	}{
+		{
+			name: "sample per fingerprint",
+			options: SuppressionOptions{
+				Window:            time.Minute,
+				MaxPerFingerprint: 1,
+			},
+			batches: []batch{
+				{at: 0, fingerprints: []string{"a", "a", "b"}},
+				{at: 30 * time.Second, fingerprints: []string{"a"}},
+				{at: 90 * time.Second, fingerprints: []string{"a", "b"}},
+			},
+			want: [][]sent{
+				{{Fingerprint: "a"}, {Fingerprint: "b"}},
+				{},
+				{{Fingerprint: "a", Suppressed: 2}, {Fingerprint: "b"}},
+			},
+		},
+		{
+			name: "project budget",
+			options: SuppressionOptions{
+				Window:            time.Minute,
+				MaxPerFingerprint: 2,
+				ProjectBudget:     3,
+			},
+			batches: []batch{
+				{at: 0, fingerprints: []string{"a", "a", "a", "b", "c"}},
+				{at: 60 * time.Second, fingerprints: []string{"c"}},
+			},
+			want: [][]sent{
+				{{Fingerprint: "a"}, {Fingerprint: "a"}, {Fingerprint: "b"}},
+				{{Fingerprint: "c", Suppressed: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			now := start
+			store := NewMemorySuppressionStore()
+			store.now = func() time.Time { return now }
+			s, err := NewSuppressor(store, tt.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s.now = store.now
+
+			got := make([][]sent, 0, len(tt.batches))
+			for _, b := range tt.batches {
+				now = start.Add(b.at)
+				occurrences := make([]*Occurrence, 0, len(b.fingerprints))
+				for _, f := range b.fingerprints {
+					occurrences = append(occurrences, &Occurrence{ProjectID: 1, Fingerprint: []string{f}})
+				}
+				filtered, err := s.Filter(context.Background(), occurrences)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				batchSent := make([]sent, 0, len(filtered))
+				for _, o := range filtered {
+					batchSent = append(batchSent, sent{
+						Fingerprint: o.Fingerprint[0],
+						Suppressed:  o.EvidenceData[EvidenceSuppressedCount].(int64),
+					})
+				}
+				got = append(got, batchSent)
+			}
+			if diff := testutil.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
@@ -53,3 +53,27 @@ func IsAndroidApplicationPackage(packageName string) bool {
 	}
 	return true
 }
+
+var (
+	dotnetSystemNamespaces = []string{
+		"Internal",
+		"Interop",
+		"Microsoft",
+		"Mono",
+		"Sentry",
+		"System",
+		"mscorlib",
+		"netstandard",
+	}
+)
+
+// IsDotNetApplicationNamespace returns false if a type or a namespace belongs
+// to the .NET runtime, its libraries or the SDK.
+func IsDotNetApplicationNamespace(namespace string) bool {
+	for _, n := range dotnetSystemNamespaces {
+		if namespace == n || strings.HasPrefix(namespace, n+".") {
+			return false
+		}
+	}
+	return true
+}
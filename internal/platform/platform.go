@@ -5,10 +5,13 @@ type Platform string
 const (
 	Android    Platform = "android"
 	Cocoa      Platform = "cocoa"
+	CSharp     Platform = "csharp"
+	Go         Platform = "go"
 	Java       Platform = "java"
 	JavaScript Platform = "javascript"
 	Node       Platform = "node"
 	PHP        Platform = "php"
 	Python     Platform = "python"
+	Ruby       Platform = "ruby"
 	Rust       Platform = "rust"
 )
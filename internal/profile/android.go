@@ -16,6 +16,7 @@ import (
 	"github.com/getsentry/vroom/internal/packageutil"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/speedscope"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 )
 
 type (
@@ -43,6 +44,9 @@ type (
 		// for react-native apps where we have js frames turned into android methods
 		JsSymbolicated *bool `json:"symbolicated,omitempty"`
 		OrigInApp      *int8 `json:"orig_in_app,omitempty"`
+		// ExcludeFromGroup is set by stack trace rules to exclude a method
+		// from function aggregation.
+		ExcludeFromGroup bool `json:"exclude_from_group,omitempty"`
 	}
 )
 
@@ -71,6 +75,7 @@ func (m AndroidMethod) Frame() frame.Frame {
 	return frame.Frame{
 		Data: frame.Data{
 			DeobfuscationStatus: m.Data.DeobfuscationStatus,
+			ExcludeFromGroup:    m.Data.ExcludeFromGroup,
 			JsSymbolicated:      m.Data.JsSymbolicated,
 		},
 		File:     path.Base(m.SourceFile),
@@ -459,6 +464,34 @@ func (p *Android) NormalizeMethods(pi profileInterface) {
 	}
 }
 
+// ApplyStackTraceRules applies stack trace rules to the methods and their
+// inline frames, matching modules against the fully qualified class name.
+func (p *Android) ApplyStackTraceRules(rules stacktracerules.Rules) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range p.Methods {
+		method := p.Methods[i]
+		for j := range method.InlineFrames {
+			method.InlineFrames[j].applyStackTraceRules(rules)
+		}
+		method.applyStackTraceRules(rules)
+		p.Methods[i] = method
+	}
+}
+
+func (m *AndroidMethod) applyStackTraceRules(rules stacktracerules.Rules) {
+	f := m.Frame()
+	f.Module = m.ClassName
+	r := rules.Result(f)
+	if r.InApp != nil {
+		m.InApp = r.InApp
+	}
+	if r.Group != nil {
+		m.Data.ExcludeFromGroup = !*r.Group
+	}
+}
+
 func (p Android) Speedscope() (speedscope.Output, error) {
 	return p.SpeedscopeWithMaxDepth(MaxStackDepth)
 }
@@ -0,0 +1,63 @@
+package profile
+
+import (
+	"strings"
+
+	"github.com/getsentry/vroom/internal/proguard"
+)
+
+// IsObfuscated returns true if the methods were not deobfuscated yet.
+func (p Android) IsObfuscated() bool {
+	for _, m := range p.Methods {
+		if m.Data.DeobfuscationStatus != "" {
+			return false
+		}
+	}
+	return len(p.Methods) > 0
+}
+
+// Deobfuscate remaps the class names, method names and lines of the methods
+// with a ProGuard or R8 mapping file. Methods inlined by the compiler are
+// added as inline frames, the outermost one first.
+func (p *Android) Deobfuscate(m *proguard.Mapping) {
+	for i := range p.Methods {
+		p.Methods[i] = deobfuscateMethod(p.Methods[i], m)
+	}
+}
+
+func deobfuscateMethod(method AndroidMethod, m *proguard.Mapping) AndroidMethod {
+	frames, status := m.Frames(method.ClassName, method.Name, method.Signature, method.SourceLine)
+	method.Data.DeobfuscationStatus = string(status)
+	switch status {
+	case proguard.StatusPartial:
+		method.ClassName, _ = m.Class(method.ClassName)
+		return method
+	case proguard.StatusMissing:
+		return method
+	}
+	if len(frames) > 1 {
+		method.InlineFrames = make([]AndroidMethod, 0, len(frames))
+		for _, f := range frames {
+			inlineFrame := AndroidMethod{
+				Data:     Data{DeobfuscationStatus: string(status)},
+				ID:       method.ID,
+				Platform: method.Platform,
+			}
+			inlineFrame.applyFrame(f)
+			method.InlineFrames = append(method.InlineFrames, inlineFrame)
+		}
+	}
+	// the method itself is the outermost frame, the one actually called
+	method.applyFrame(frames[0])
+	return method
+}
+
+func (m *AndroidMethod) applyFrame(f proguard.Frame) {
+	m.ClassName = f.ClassName
+	m.Name = f.Name
+	m.Signature = "(" + strings.Join(f.Arguments, ", ") + ")"
+	m.SourceLine = f.Line
+	if f.SourceFile != "" {
+		m.SourceFile = f.SourceFile
+	}
+}
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/proguard"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestAndroidDeobfuscate(t *testing.T) {
+	m, err := proguard.Parse(strings.NewReader(`io.sentry.samples.MainActivity -> a.a:
+# {"id":"sourceFile","fileName":"MainActivity.kt"}
+    5:5:void onCreate(android.os.Bundle):20:20 -> a
+    6:6:void io.sentry.samples.Renderer.draw(int):30:30 -> b
+    6:6:void render():40 -> b
+`))
+	if err != nil {
+		t.Fatalf("couldn't parse mapping: %v", err)
+	}
+	p := Android{
+		Methods: []AndroidMethod{
+			{ID: 1, ClassName: "a.a", Name: "a", Signature: "(Landroid/os/Bundle;)V", SourceFile: "SourceFile", SourceLine: 5},
+			{ID: 2, ClassName: "a.a", Name: "b", Signature: "()V", SourceFile: "SourceFile", SourceLine: 6},
+			{ID: 3, ClassName: "a.a", Name: "c", Signature: "()V", SourceFile: "SourceFile"},
+			{ID: 4, ClassName: "android.app.Activity", Name: "onCreate", Signature: "(Landroid/os/Bundle;)V"},
+		},
+	}
+	if !p.IsObfuscated() {
+		t.Fatal("expected the trace to be obfuscated")
+	}
+
+	p.Deobfuscate(m)
+
+	want := []AndroidMethod{
+		{
+			ID:         1,
+			ClassName:  "io.sentry.samples.MainActivity",
+			Name:       "onCreate",
+			Signature:  "(android.os.Bundle)",
+			SourceFile: "MainActivity.kt",
+			SourceLine: 20,
+			Data:       Data{DeobfuscationStatus: "deobfuscated"},
+		},
+		{
+			ID:         2,
+			ClassName:  "io.sentry.samples.MainActivity",
+			Name:       "render",
+			Signature:  "()",
+			SourceFile: "MainActivity.kt",
+			SourceLine: 40,
+			Data:       Data{DeobfuscationStatus: "deobfuscated"},
+			InlineFrames: []AndroidMethod{
+				{
+					ID:         2,
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "render",
+					Signature:  "()",
+					SourceFile: "MainActivity.kt",
+					SourceLine: 40,
+					Data:       Data{DeobfuscationStatus: "deobfuscated"},
+				},
+				{
+					ID:         2,
+					ClassName:  "io.sentry.samples.Renderer",
+					Name:       "draw",
+					Signature:  "(int)",
+					SourceLine: 30,
+					Data:       Data{DeobfuscationStatus: "deobfuscated"},
+				},
+			},
+		},
+		{
+			ID:         3,
+			ClassName:  "io.sentry.samples.MainActivity",
+			Name:       "c",
+			Signature:  "()V",
+			SourceFile: "SourceFile",
+			Data:       Data{DeobfuscationStatus: "partial"},
+		},
+		{
+			ID:        4,
+			ClassName: "android.app.Activity",
+			Name:      "onCreate",
+			Signature: "(Landroid/os/Bundle;)V",
+			Data:      Data{DeobfuscationStatus: "missing"},
+		},
+	}
+	if diff := testutil.Diff(p.Methods, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if p.IsObfuscated() {
+		t.Fatal("expected the trace to be deobfuscated")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/getsentry/vroom/internal/frame"
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/speedscope"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/testutil"
 )
 
@@ -976,3 +977,51 @@ func TestAddTimeDelta(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyStackTraceRules(t *testing.T) {
+	rules, err := stacktracerules.Parse(`stack.module:io.sentry.samples.* +app
+stack.function:*Binding* -group`)
+	if err != nil {
+		t.Fatalf("couldn't parse rules: %v", err)
+	}
+	yes, no := true, false
+	trace := Android{
+		Methods: []AndroidMethod{
+			{
+				ClassName: "io.sentry.samples.MainActivity",
+				ID:        1,
+				InApp:     &no,
+				InlineFrames: []AndroidMethod{
+					{
+						ClassName: "androidx.databinding.ViewDataBinding",
+						ID:        1,
+						InApp:     &no,
+						Name:      "executePendingBindings",
+					},
+				},
+				Name: "onCreate",
+			},
+		},
+	}
+	trace.ApplyStackTraceRules(rules)
+	want := []AndroidMethod{
+		{
+			ClassName: "io.sentry.samples.MainActivity",
+			ID:        1,
+			InApp:     &yes,
+			InlineFrames: []AndroidMethod{
+				{
+					ClassName: "androidx.databinding.ViewDataBinding",
+					Data:      Data{ExcludeFromGroup: true},
+					ID:        1,
+					InApp:     &no,
+					Name:      "executePendingBindings",
+				},
+			},
+			Name: "onCreate",
+		},
+	}
+	if diff := testutil.Diff(trace.Methods, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
@@ -0,0 +1,243 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Method traces are written by ART with Debug.startMethodTracing. They start
+// with a text header listing the threads and methods, followed by binary
+// records for each method entry and exit.
+//
+// See https://android.googlesource.com/platform/art/+/refs/heads/main/runtime/trace.cc
+const (
+	artTraceMagic          uint32 = 0x574f4c53 // "SLOW"
+	artTraceStreamingFlag  uint16 = 0xf0
+	artTraceHeaderLength   int    = 16
+	artTraceVersionSection        = "*version"
+	artTraceThreadsSection        = "*threads"
+	artTraceMethodsSection        = "*methods"
+	artTraceEndSection            = "*end"
+
+	artTraceMethodEnter  uint32 = 0
+	artTraceMethodExit   uint32 = 1
+	artTraceMethodUnwind uint32 = 2
+	artTraceActionMask   uint32 = 0x03
+)
+
+var (
+	ErrInvalidAndroidTrace   = errors.New("invalid android method trace")
+	ErrStreamingAndroidTrace = errors.New("streaming android method traces are not supported")
+
+	artTraceClocks = map[string]Clock{
+		"dual":       DualClock,
+		"thread-cpu": CPUClock,
+		"wall":       WallClock,
+	}
+)
+
+// UnmarshalJSON decodes a trace in the JSON format or, when it's a string,
+// an ART method trace encoded in base64.
+func (p *Android) UnmarshalJSON(b []byte) error {
+	if len(b) == 0 || b[0] != '"' {
+		type android Android
+		return json.Unmarshal(b, (*android)(p))
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAndroidTrace, err)
+	}
+	t, err := ParseAndroidTrace(raw)
+	if err != nil {
+		return err
+	}
+	*p = t
+	return nil
+}
+
+// ParseAndroidTrace parses an ART method trace (.trace file).
+func ParseAndroidTrace(b []byte) (Android, error) {
+	if len(b) >= 4 && binary.LittleEndian.Uint32(b) == artTraceMagic {
+		return Android{}, ErrStreamingAndroidTrace
+	}
+	end := []byte("\n" + artTraceEndSection + "\n")
+	i := bytes.Index(b, end)
+	if !bytes.HasPrefix(b, []byte(artTraceVersionSection+"\n")) || i == -1 {
+		return Android{}, fmt.Errorf("%w: no header", ErrInvalidAndroidTrace)
+	}
+	p := Android{Clock: CPUClock}
+	if err := p.parseTraceHeader(b[:i+1]); err != nil {
+		return Android{}, err
+	}
+	if err := p.parseTraceRecords(b[i+len(end):]); err != nil {
+		return Android{}, err
+	}
+	return p, nil
+}
+
+func (p *Android) parseTraceHeader(b []byte) error {
+	var section string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(make([]byte, 0, 64*1024), len(b))
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "*") {
+			section = line
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		switch section {
+		case artTraceVersionSection:
+			key, value, found := strings.Cut(line, "=")
+			if !found || key != "clock" {
+				continue
+			}
+			clock, exists := artTraceClocks[value]
+			if !exists {
+				return fmt.Errorf("%w: unknown clock %q", ErrInvalidAndroidTrace, value)
+			}
+			p.Clock = clock
+		case artTraceThreadsSection:
+			rawID, name, _ := strings.Cut(line, "\t")
+			id, err := strconv.ParseUint(rawID, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: invalid thread %q", ErrInvalidAndroidTrace, line)
+			}
+			p.Threads = append(p.Threads, AndroidThread{ID: id, Name: name})
+		case artTraceMethodsSection:
+			m, err := parseTraceMethod(line)
+			if err != nil {
+				return err
+			}
+			p.Methods = append(p.Methods, m)
+		}
+	}
+	return s.Err()
+}
+
+// parseTraceMethod parses a method line: its ID, class name, name, signature
+// and optionally its source file and line.
+func parseTraceMethod(line string) (AndroidMethod, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 4 {
+		return AndroidMethod{}, fmt.Errorf("%w: invalid method %q", ErrInvalidAndroidTrace, line)
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 64)
+	if err != nil {
+		return AndroidMethod{}, fmt.Errorf("%w: invalid method %q", ErrInvalidAndroidTrace, line)
+	}
+	m := AndroidMethod{
+		ID:        id,
+		ClassName: strings.ReplaceAll(fields[1], "/", "."),
+		Name:      fields[2],
+		Signature: fields[3],
+	}
+	if len(fields) > 4 {
+		m.SourceFile = fields[4]
+	}
+	if len(fields) > 5 {
+		if line, err := strconv.ParseUint(fields[5], 10, 32); err == nil {
+			m.SourceLine = uint32(line)
+		}
+	}
+	return m, nil
+}
+
+func (p *Android) parseTraceRecords(b []byte) error {
+	if len(b) < artTraceHeaderLength || binary.LittleEndian.Uint32(b) != artTraceMagic {
+		return fmt.Errorf("%w: invalid data header", ErrInvalidAndroidTrace)
+	}
+	version := binary.LittleEndian.Uint16(b[4:])
+	if version&artTraceStreamingFlag == artTraceStreamingFlag {
+		return ErrStreamingAndroidTrace
+	}
+	offset := int(binary.LittleEndian.Uint16(b[6:]))
+	startTimeUS := binary.LittleEndian.Uint64(b[8:])
+	p.StartTime = startTimeUS * uint64(time.Microsecond)
+
+	threadIDSize := 2
+	clocks := 1
+	if p.Clock == DualClock {
+		clocks = 2
+	}
+	var recordSize int
+	switch version {
+	case 1:
+		threadIDSize = 1
+		recordSize = 1 + 4 + 4*clocks
+	case 2:
+		recordSize = 2 + 4 + 4*clocks
+	case 3:
+		if len(b) < artTraceHeaderLength+2 {
+			return fmt.Errorf("%w: invalid data header", ErrInvalidAndroidTrace)
+		}
+		recordSize = int(binary.LittleEndian.Uint16(b[artTraceHeaderLength:]))
+	default:
+		return fmt.Errorf("%w: unknown version %d", ErrInvalidAndroidTrace, version)
+	}
+	if recordSize < threadIDSize+4+4*clocks || offset > len(b) {
+		return fmt.Errorf("%w: invalid data header", ErrInvalidAndroidTrace)
+	}
+
+	records := b[offset:]
+	p.Events = make([]AndroidEvent, 0, len(records)/recordSize)
+	for ; len(records) >= recordSize; records = records[recordSize:] {
+		var threadID uint64
+		if threadIDSize == 1 {
+			threadID = uint64(records[0])
+		} else {
+			threadID = uint64(binary.LittleEndian.Uint16(records))
+		}
+		methodValue := binary.LittleEndian.Uint32(records[threadIDSize:])
+		var action Action
+		switch methodValue & artTraceActionMask {
+		case artTraceMethodEnter:
+			action = EnterAction
+		case artTraceMethodExit:
+			action = ExitAction
+		case artTraceMethodUnwind:
+			action = UnwindAction
+		default:
+			return fmt.Errorf("%w: unknown action", ErrInvalidAndroidTrace)
+		}
+		e := AndroidEvent{
+			Action:   action,
+			ThreadID: threadID,
+			MethodID: uint64(methodValue &^ artTraceActionMask),
+		}
+		times := records[threadIDSize+4:]
+		switch p.Clock {
+		case DualClock:
+			e.Time.Monotonic.CPU = durationFromMicroseconds(binary.LittleEndian.Uint32(times))
+			e.Time.Monotonic.Wall = durationFromMicroseconds(binary.LittleEndian.Uint32(times[4:]))
+		case CPUClock:
+			e.Time.Monotonic.CPU = durationFromMicroseconds(binary.LittleEndian.Uint32(times))
+		default:
+			e.Time.Monotonic.Wall = durationFromMicroseconds(binary.LittleEndian.Uint32(times))
+		}
+		p.Events = append(p.Events, e)
+	}
+	return nil
+}
+
+func durationFromMicroseconds(us uint32) Duration {
+	ns := uint64(us) * uint64(time.Microsecond)
+	return Duration{
+		Secs:  ns / uint64(time.Second),
+		Nanos: ns % uint64(time.Second),
+	}
+}
@@ -0,0 +1,193 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+type artTraceRecord struct {
+	threadID uint16
+	method   uint32
+	cpuUS    uint32
+	wallUS   uint32
+}
+
+// artTrace builds a method trace with a dual clock.
+func artTrace(version uint16, recordSize uint16, records []artTraceRecord) []byte {
+	var b bytes.Buffer
+	b.WriteString("*version\n3\ndata-file-overflow=false\nclock=dual\nvm=art\n")
+	b.WriteString("*threads\n1\tmain\n2\tRenderThread\n")
+	b.WriteString("*methods\n")
+	b.WriteString("0x1000\tandroid/app/Activity\tonCreate\t(Landroid/os/Bundle;)V\tActivity.java\t42\n")
+	b.WriteString("0x1004\tio.sentry.App\tload\t()V\n")
+	b.WriteString("*end\n")
+
+	header := make([]byte, 32)
+	binary.LittleEndian.PutUint32(header, artTraceMagic)
+	binary.LittleEndian.PutUint16(header[4:], version)
+	binary.LittleEndian.PutUint16(header[6:], uint16(len(header)))
+	binary.LittleEndian.PutUint64(header[8:], 1_700_000_000_000_000)
+	binary.LittleEndian.PutUint16(header[16:], recordSize)
+	b.Write(header)
+
+	for _, r := range records {
+		record := make([]byte, recordSize)
+		binary.LittleEndian.PutUint16(record, r.threadID)
+		binary.LittleEndian.PutUint32(record[2:], r.method)
+		binary.LittleEndian.PutUint32(record[6:], r.cpuUS)
+		binary.LittleEndian.PutUint32(record[10:], r.wallUS)
+		b.Write(record)
+	}
+	return b.Bytes()
+}
+
+func TestParseAndroidTrace(t *testing.T) {
+	records := []artTraceRecord{
+		{threadID: 1, method: 0x1000, cpuUS: 10, wallUS: 1_000},
+		{threadID: 1, method: 0x1004, cpuUS: 20, wallUS: 2_000},
+		{threadID: 1, method: 0x1004 | 2, cpuUS: 30, wallUS: 1_500_000},
+		{threadID: 1, method: 0x1000 | 1, cpuUS: 40, wallUS: 1_600_000},
+	}
+	want := Android{
+		Clock:     DualClock,
+		StartTime: 1_700_000_000_000_000_000,
+		Threads: []AndroidThread{
+			{ID: 1, Name: "main"},
+			{ID: 2, Name: "RenderThread"},
+		},
+		Methods: []AndroidMethod{
+			{
+				ID:         0x1000,
+				ClassName:  "android.app.Activity",
+				Name:       "onCreate",
+				Signature:  "(Landroid/os/Bundle;)V",
+				SourceFile: "Activity.java",
+				SourceLine: 42,
+			},
+			{
+				ID:        0x1004,
+				ClassName: "io.sentry.App",
+				Name:      "load",
+				Signature: "()V",
+			},
+		},
+		Events: []AndroidEvent{
+			{
+				Action:   EnterAction,
+				ThreadID: 1,
+				MethodID: 0x1000,
+				Time: EventTime{Monotonic: EventMonotonic{
+					CPU:  Duration{Nanos: 10_000},
+					Wall: Duration{Nanos: 1_000_000},
+				}},
+			},
+			{
+				Action:   EnterAction,
+				ThreadID: 1,
+				MethodID: 0x1004,
+				Time: EventTime{Monotonic: EventMonotonic{
+					CPU:  Duration{Nanos: 20_000},
+					Wall: Duration{Nanos: 2_000_000},
+				}},
+			},
+			{
+				Action:   UnwindAction,
+				ThreadID: 1,
+				MethodID: 0x1004,
+				Time: EventTime{Monotonic: EventMonotonic{
+					CPU:  Duration{Nanos: 30_000},
+					Wall: Duration{Secs: 1, Nanos: 500_000_000},
+				}},
+			},
+			{
+				Action:   ExitAction,
+				ThreadID: 1,
+				MethodID: 0x1000,
+				Time: EventTime{Monotonic: EventMonotonic{
+					CPU:  Duration{Nanos: 40_000},
+					Wall: Duration{Secs: 1, Nanos: 600_000_000},
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		version    uint16
+		recordSize uint16
+	}{
+		{name: "version 2", version: 2, recordSize: 14},
+		{name: "version 3 with padded records", version: 3, recordSize: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAndroidTrace(artTrace(tt.version, tt.recordSize, records))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := testutil.Diff(got, want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseAndroidTraceErrors(t *testing.T) {
+	streaming := make([]byte, 32)
+	binary.LittleEndian.PutUint32(streaming, artTraceMagic)
+	binary.LittleEndian.PutUint16(streaming[4:], 3|artTraceStreamingFlag)
+
+	tests := []struct {
+		name  string
+		trace []byte
+		want  error
+	}{
+		{name: "json", trace: []byte(`{"clock":"Dual"}`), want: ErrInvalidAndroidTrace},
+		{name: "streaming", trace: streaming, want: ErrStreamingAndroidTrace},
+		{name: "no data", trace: []byte("*version\n3\n*end\n"), want: ErrInvalidAndroidTrace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAndroidTrace(tt.trace); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalProfileWithAndroidTrace(t *testing.T) {
+	trace := artTrace(3, 14, []artTraceRecord{
+		{threadID: 1, method: 0x1000, cpuUS: 10, wallUS: 1_000},
+		{threadID: 1, method: 0x1004, cpuUS: 20, wallUS: 2_000},
+		{threadID: 1, method: 0x1004 | 1, cpuUS: 30, wallUS: 30_000},
+		{threadID: 1, method: 0x1000 | 1, cpuUS: 40, wallUS: 40_000},
+	})
+	b := []byte(fmt.Sprintf(
+		`{"platform":%q,"profile":%q,"duration_ns":39000000,"transaction_metadata":{},"transaction_name":"main"}`,
+		platform.Android,
+		base64.StdEncoding.EncodeToString(trace),
+	))
+	var p LegacyProfile
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatal(err)
+	}
+	android, ok := p.Trace.(*Android)
+	if !ok {
+		t.Fatalf("unexpected trace: %T", p.Trace)
+	}
+	if len(android.Events) != 4 || len(android.Methods) != 2 {
+		t.Fatalf("unexpected trace: %+v", android)
+	}
+	callTrees := android.CallTrees()
+	if len(callTrees[1]) != 1 || callTrees[1][0].DurationNS != 39_000_000 {
+		t.Fatalf("unexpected call trees: %+v", callTrees)
+	}
+}
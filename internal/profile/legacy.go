@@ -18,6 +18,7 @@ import (
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/sample"
 	"github.com/getsentry/vroom/internal/speedscope"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/timeutil"
 	"github.com/getsentry/vroom/internal/transaction"
 	"github.com/getsentry/vroom/internal/utils"
@@ -118,16 +119,18 @@ func (p *LegacyProfile) UnmarshalJSON(b []byte) error {
 	if len(p.Profile) == 0 {
 		return nil
 	}
-	var raw []byte
+	raw := p.Profile
 	if p.Profile[0] == '"' {
 		var s string
 		err := json.Unmarshal(p.Profile, &s)
 		if err != nil {
 			return err
 		}
-		raw = []byte(s)
-	} else {
-		raw = p.Profile
+		// a string is either a JSON trace or a method trace
+		// encoded in base64, decoded by Android
+		if strings.HasPrefix(strings.TrimSpace(s), "{") {
+			raw = []byte(s)
+		}
 	}
 	switch p.Platform {
 	case platform.Android:
@@ -295,10 +298,11 @@ func (p LegacyProfile) GetReceived() time.Time {
 	return p.Received.Time()
 }
 
-func (p *LegacyProfile) Normalize() {
+func (p *LegacyProfile) Normalize(rules stacktracerules.Rules) {
 	switch t := p.Trace.(type) {
 	case *Android:
 		t.NormalizeMethods(p)
+		t.ApplyStackTraceRules(rules)
 		if len(p.JsProfile) > 0 {
 			st, err := unmarshalSampleProfile(p.JsProfile)
 			if err == nil {
@@ -308,7 +312,7 @@ func (p *LegacyProfile) Normalize() {
 						Trace:    st.Profile,
 					},
 				}
-				jsProf.Normalize()
+				jsProf.Normalize(rules)
 				st.Profile = jsProf.Trace
 				jsonRawJsProfile, err := json.Marshal(st)
 				if err == nil {
@@ -351,6 +355,17 @@ func (p LegacyProfile) IsSampled() bool {
 	return p.Sampled
 }
 
+func (p LegacyProfile) GetThreadName(threadID uint64) string {
+	if t, ok := p.Trace.(*Android); ok {
+		for _, thread := range t.Threads {
+			if thread.ID == threadID {
+				return thread.Name
+			}
+		}
+	}
+	return ""
+}
+
 func (p LegacyProfile) GetMeasurements() map[string]measurements.Measurement {
 	return p.Measurements
 }
@@ -548,6 +563,22 @@ type NestedProfile struct {
 	ProcessedBySymbolicator *bool        `json:"processed_by_symbolicator,omitempty"`
 }
 
+// updateJsProfileFrames calls fn with the frames of the JavaScript profile
+// of a React Native profile and stores the changes made to them.
+func (p *LegacyProfile) updateJsProfileFrames(fn func(frames []frame.Frame, callers []int) error) error {
+	np, err := unmarshalSampleProfile(p.JsProfile)
+	if err != nil {
+		return err
+	}
+	fnErr := fn(np.Profile.Frames, sample.CallerFrameIDs(np.Profile.Stacks, len(np.Profile.Frames)))
+	b, err := json.Marshal(np)
+	if err != nil {
+		return errors.Join(fnErr, err)
+	}
+	p.JsProfile = b
+	return fnErr
+}
+
 func unmarshalSampleProfile(p json.RawMessage) (NestedProfile, error) {
 	var np NestedProfile
 	err := json.Unmarshal(p, &np)
@@ -330,7 +330,7 @@ func TestNormalizeLegacyAndroidProfile(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			if diff := testutil.Diff(test.input, test.output); diff != "" {
 				t.Fatalf("Result mismatch: got - want +\n%s", diff)
 			}
@@ -12,6 +12,7 @@ import (
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/sample"
 	"github.com/getsentry/vroom/internal/speedscope"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/transaction"
 	"github.com/getsentry/vroom/internal/utils"
 )
@@ -29,6 +30,7 @@ type (
 		GetReceived() time.Time
 		GetRelease() string
 		GetRetentionDays() int
+		GetThreadName(threadID uint64) string
 		GetTimestamp() time.Time
 		GetTransaction() transaction.Transaction
 		GetTransactionMetadata() transaction.Metadata
@@ -37,7 +39,7 @@ type (
 		CallTrees() (map[uint64][]*nodetree.Node, error)
 		IsSampleFormat() bool
 		Metadata() metadata.Metadata
-		Normalize()
+		Normalize(rules stacktracerules.Rules)
 		Speedscope() (speedscope.Output, error)
 		StoragePath() string
 		IsSampled() bool
@@ -120,8 +122,8 @@ func (p *Profile) Platform() platform.Platform {
 	return p.profile.GetPlatform()
 }
 
-func (p *Profile) Normalize() {
-	p.profile.Normalize()
+func (p *Profile) Normalize(rules stacktracerules.Rules) {
+	p.profile.Normalize(rules)
 }
 
 func (p *Profile) Transaction() transaction.Transaction {
@@ -168,6 +170,10 @@ func (p *Profile) SetProfileID(ID string) {
 	p.profile.SetProfileID(ID)
 }
 
+func (p *Profile) ThreadName(threadID uint64) string {
+	return p.profile.GetThreadName(threadID)
+}
+
 func (p *Profile) Measurements() map[string]measurements.Measurement {
 	return p.profile.GetMeasurements()
 }
@@ -179,3 +185,49 @@ func (p *Profile) GetOptions() utils.Options {
 func (p *Profile) GetFrameWithFingerprint(target uint32) (frame.Frame, error) {
 	return p.profile.GetFrameWithFingerprint(target)
 }
+
+// ObfuscatedAndroidTrace returns the trace of an Android profile not
+// deobfuscated yet and the debug ID of its mapping file, if it has one.
+func (p *Profile) ObfuscatedAndroidTrace() (*Android, string, bool) {
+	lp, ok := p.profile.(*LegacyProfile)
+	if !ok {
+		return nil, "", false
+	}
+	t, ok := lp.Trace.(*Android)
+	if !ok || !t.IsObfuscated() {
+		return nil, "", false
+	}
+	debugID := lp.BuildID
+	if debugID == "" {
+		debugID = lp.DebugMeta.ProguardDebugID()
+	}
+	return t, debugID, debugID != ""
+}
+
+// SampleTrace returns the trace of a profile in the sample format.
+func (p *Profile) SampleTrace() (*sample.Trace, bool) {
+	sp, ok := p.profile.(*sample.Profile)
+	if !ok {
+		return nil, false
+	}
+	return &sp.Trace, true
+}
+
+// UpdateJavaScriptFrames calls fn with the frames of a JavaScript profile or
+// of the JavaScript profile of a React Native profile, keeping the changes
+// made to them, and with the ID of a frame calling each frame or -1.
+func (p *Profile) UpdateJavaScriptFrames(fn func(frames []frame.Frame, callers []int) error) error {
+	switch t := p.profile.(type) {
+	case *sample.Profile:
+		if t.Platform != platform.JavaScript {
+			return nil
+		}
+		return fn(t.Trace.Frames, sample.CallerFrameIDs(t.Trace.Stacks, len(t.Trace.Frames)))
+	case *LegacyProfile:
+		if len(t.JsProfile) == 0 {
+			return nil
+		}
+		return t.updateJsProfileFrames(fn)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package proguard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Mapping holds the classes and methods of a ProGuard or R8 mapping file,
+	// by obfuscated name.
+	Mapping struct {
+		classes map[string]*class
+		// sourceFiles holds the source file of classes, by original name.
+		sourceFiles map[string]string
+	}
+
+	class struct {
+		name       string
+		sourceFile string
+		methods    map[string][]method
+	}
+
+	method struct {
+		// className is only set for methods inlined from another class.
+		className string
+		name      string
+		arguments []string
+		// startLine and endLine are the obfuscated line range, 0 if unknown.
+		startLine uint32
+		endLine   uint32
+		// originalStartLine and originalEndLine are the original line range,
+		// 0 if unknown.
+		originalStartLine uint32
+		originalEndLine   uint32
+	}
+
+	// Frame is a deobfuscated frame.
+	Frame struct {
+		ClassName  string
+		Name       string
+		Arguments  []string
+		SourceFile string
+		Line       uint32
+	}
+
+	// Status is the result of the deobfuscation of a frame.
+	Status string
+
+	sourceFileMetadata struct {
+		ID       string `json:"id"`
+		FileName string `json:"fileName"`
+	}
+)
+
+const (
+	// StatusDeobfuscated is set when the class and the method were found.
+	StatusDeobfuscated Status = "deobfuscated"
+	// StatusPartial is set when only the class was found.
+	StatusPartial Status = "partial"
+	// StatusMissing is set when the class was not found, likely
+	// because it was not obfuscated.
+	StatusMissing Status = "missing"
+)
+
+var javaPrimitiveTypes = map[byte]string{
+	'B': "byte",
+	'C': "char",
+	'D': "double",
+	'F': "float",
+	'I': "int",
+	'J': "long",
+	'S': "short",
+	'V': "void",
+	'Z': "boolean",
+}
+
+// Parse reads a mapping file.
+func Parse(r io.Reader) (*Mapping, error) {
+	m := &Mapping{
+		classes:     make(map[string]*class),
+		sourceFiles: make(map[string]string),
+	}
+	var current *class
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			if current != nil {
+				var metadata sourceFileMetadata
+				err := json.Unmarshal([]byte(strings.TrimSpace(trimmed[1:])), &metadata)
+				if err == nil && metadata.ID == "sourceFile" {
+					current.sourceFile = metadata.FileName
+					m.sourceFiles[current.name] = metadata.FileName
+				}
+			}
+			continue
+		case line[0] != ' ' && line[0] != '\t':
+			c, obfuscated, err := parseClass(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			current = c
+			m.classes[obfuscated] = c
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("line %d: member outside of a class", n)
+			}
+			mt, obfuscatedName, ok, err := parseMember(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if ok {
+				current.methods[obfuscatedName] = append(current.methods[obfuscatedName], mt)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseClass parses a line like "com.example.Original -> a.b:".
+func parseClass(line string) (*class, string, error) {
+	original, obfuscated, found := strings.Cut(strings.TrimSuffix(line, ":"), " -> ")
+	if !found {
+		return nil, "", fmt.Errorf("invalid class mapping %q", line)
+	}
+	return &class{
+		name:    original,
+		methods: make(map[string][]method),
+	}, obfuscated, nil
+}
+
+// parseMember parses a method line like
+// "1:3:void method(int,java.lang.String):10:12 -> a",
+// fields being ignored.
+func parseMember(line string) (method, string, bool, error) {
+	left, obfuscatedName, found := strings.Cut(line, " -> ")
+	if !found {
+		return method{}, "", false, fmt.Errorf("invalid member mapping %q", line)
+	}
+	open := strings.IndexByte(left, '(')
+	if open == -1 {
+		// a field
+		return method{}, "", false, nil
+	}
+	closing := strings.IndexByte(left, ')')
+	if closing < open {
+		return method{}, "", false, fmt.Errorf("invalid method mapping %q", line)
+	}
+
+	var m method
+	declaration := left[:open]
+	if start, rest, ok := cutLineNumber(declaration); ok {
+		end, rest, ok := cutLineNumber(rest)
+		if !ok {
+			return method{}, "", false, fmt.Errorf("invalid line range %q", line)
+		}
+		m.startLine, m.endLine = start, end
+		declaration = rest
+	}
+	_, name, found := strings.Cut(declaration, " ")
+	if !found {
+		return method{}, "", false, fmt.Errorf("invalid method mapping %q", line)
+	}
+	if i := strings.LastIndexByte(name, '.'); i != -1 {
+		m.className, name = name[:i], name[i+1:]
+	}
+	m.name = name
+	if arguments := left[open+1 : closing]; arguments != "" {
+		m.arguments = strings.Split(arguments, ",")
+	}
+	if original := strings.TrimPrefix(left[closing+1:], ":"); original != "" {
+		start, end, _ := strings.Cut(original, ":")
+		m.originalStartLine = parseLineNumber(start)
+		m.originalEndLine = parseLineNumber(end)
+	}
+	return m, obfuscatedName, true, nil
+}
+
+func cutLineNumber(s string) (uint32, string, bool) {
+	before, after, found := strings.Cut(s, ":")
+	if !found {
+		return 0, s, false
+	}
+	n, err := strconv.ParseUint(before, 10, 32)
+	if err != nil {
+		return 0, s, false
+	}
+	return uint32(n), after, true
+}
+
+func parseLineNumber(s string) uint32 {
+	n, _ := strconv.ParseUint(s, 10, 32)
+	return uint32(n)
+}
+
+// Class returns the original name of a class.
+func (m *Mapping) Class(obfuscated string) (string, bool) {
+	c, exists := m.classes[obfuscated]
+	if !exists {
+		return "", false
+	}
+	return c.name, true
+}
+
+// Frames returns the original frames of a method, the outermost one first
+// when methods were inlined. The descriptor is the JVM descriptor of the
+// method, used to choose between overloads when the line is not known.
+func (m *Mapping) Frames(className, methodName, descriptor string, line uint32) ([]Frame, Status) {
+	c, exists := m.classes[className]
+	if !exists {
+		return nil, StatusMissing
+	}
+	methods := c.methods[methodName]
+	var candidates []method
+	if line > 0 {
+		for _, mt := range methods {
+			if mt.startLine <= line && line <= mt.endLine {
+				candidates = append(candidates, mt)
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		frames := make([]Frame, 0, len(candidates))
+		for i := len(candidates) - 1; i >= 0; i-- {
+			mt := candidates[i]
+			// only the innermost frame is at the given line, others
+			// are at the line of the call
+			frameLine := mt.originalStartLine
+			if i == 0 {
+				frameLine = mt.originalLine(line)
+			}
+			frames = append(frames, m.frame(c, mt, frameLine))
+		}
+		return frames, StatusDeobfuscated
+	}
+
+	for _, mt := range methods {
+		if mt.startLine == 0 && mt.endLine == 0 {
+			candidates = append(candidates, mt)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = methods
+	}
+	if len(candidates) > 1 && !sameMethod(candidates) {
+		arguments, ok := m.descriptorArguments(descriptor)
+		var matching []method
+		if ok {
+			for _, mt := range candidates {
+				if equalArguments(mt.arguments, arguments) {
+					matching = append(matching, mt)
+				}
+			}
+		}
+		if len(matching) == 0 || !sameMethod(matching) {
+			return nil, StatusPartial
+		}
+		candidates = matching
+	}
+	if len(candidates) == 0 {
+		return nil, StatusPartial
+	}
+	mt := candidates[0]
+	return []Frame{m.frame(c, mt, mt.originalLine(line))}, StatusDeobfuscated
+}
+
+func (m *Mapping) frame(c *class, mt method, line uint32) Frame {
+	f := Frame{
+		ClassName:  c.name,
+		Name:       mt.name,
+		Arguments:  mt.arguments,
+		SourceFile: c.sourceFile,
+		Line:       line,
+	}
+	if mt.className != "" {
+		f.ClassName = mt.className
+		f.SourceFile = m.sourceFiles[mt.className]
+	}
+	return f
+}
+
+// originalLine maps an obfuscated line to the original one.
+func (mt method) originalLine(line uint32) uint32 {
+	switch {
+	case mt.originalStartLine == 0:
+		if mt.startLine == 0 {
+			return 0
+		}
+		return line
+	case mt.originalEndLine > mt.originalStartLine && line >= mt.startLine:
+		return mt.originalStartLine + (line - mt.startLine)
+	}
+	return mt.originalStartLine
+}
+
+func sameMethod(methods []method) bool {
+	for _, mt := range methods[1:] {
+		if mt.className != methods[0].className ||
+			mt.name != methods[0].name ||
+			!equalArguments(mt.arguments, methods[0].arguments) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalArguments(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// descriptorArguments returns the deobfuscated Java types of the arguments
+// of a JVM method descriptor like "(La/b;[I)V".
+func (m *Mapping) descriptorArguments(descriptor string) ([]string, bool) {
+	if !strings.HasPrefix(descriptor, "(") {
+		return nil, false
+	}
+	end := strings.IndexByte(descriptor, ')')
+	if end == -1 {
+		return nil, false
+	}
+	var arguments []string
+	d := descriptor[1:end]
+	for len(d) > 0 {
+		var dimensions int
+		for len(d) > 0 && d[0] == '[' {
+			dimensions++
+			d = d[1:]
+		}
+		if len(d) == 0 {
+			return nil, false
+		}
+		var t string
+		if d[0] == 'L' {
+			i := strings.IndexByte(d, ';')
+			if i == -1 {
+				return nil, false
+			}
+			t = strings.ReplaceAll(d[1:i], "/", ".")
+			if original, ok := m.Class(t); ok {
+				t = original
+			}
+			d = d[i+1:]
+		} else {
+			primitive, ok := javaPrimitiveTypes[d[0]]
+			if !ok {
+				return nil, false
+			}
+			t = primitive
+			d = d[1:]
+		}
+		arguments = append(arguments, t+strings.Repeat("[]", dimensions))
+	}
+	return arguments, true
+}
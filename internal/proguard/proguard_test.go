@@ -0,0 +1,191 @@
+package proguard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+const testMapping = `# compiler: R8
+# pg_map_id: 4e1a1e9
+io.sentry.samples.MainActivity -> a.a:
+# {"id":"sourceFile","fileName":"MainActivity.kt"}
+    java.lang.String title -> a
+    1:4:void <init>():10:13 -> <init>
+    5:5:void onCreate(android.os.Bundle):20:20 -> a
+    6:6:void io.sentry.samples.Renderer.draw(int):30:30 -> b
+    6:6:void render():40 -> b
+    7:9:void render():41:43 -> b
+    void load(int) -> c
+    void load(io.sentry.samples.Renderer) -> c
+    void save() -> d
+    void save() -> d
+io.sentry.samples.Renderer -> a.b:
+# {"id":"sourceFile","fileName":"Renderer.kt"}
+    1:1:void draw(int):30:30 -> a
+`
+
+func TestFrames(t *testing.T) {
+	m, err := Parse(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatalf("couldn't parse mapping: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		className  string
+		methodName string
+		descriptor string
+		line       uint32
+		wantFrames []Frame
+		wantStatus Status
+	}{
+		{
+			name:       "method with a line range",
+			className:  "a.a",
+			methodName: "<init>",
+			descriptor: "()V",
+			line:       3,
+			wantFrames: []Frame{
+				{
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "<init>",
+					SourceFile: "MainActivity.kt",
+					Line:       12,
+				},
+			},
+			wantStatus: StatusDeobfuscated,
+		},
+		{
+			name:       "inlined method",
+			className:  "a.a",
+			methodName: "b",
+			descriptor: "()V",
+			line:       6,
+			wantFrames: []Frame{
+				{
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "render",
+					SourceFile: "MainActivity.kt",
+					Line:       40,
+				},
+				{
+					ClassName:  "io.sentry.samples.Renderer",
+					Name:       "draw",
+					Arguments:  []string{"int"},
+					SourceFile: "Renderer.kt",
+					Line:       30,
+				},
+			},
+			wantStatus: StatusDeobfuscated,
+		},
+		{
+			name:       "line outside of the inlined range",
+			className:  "a.a",
+			methodName: "b",
+			descriptor: "()V",
+			line:       8,
+			wantFrames: []Frame{
+				{
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "render",
+					SourceFile: "MainActivity.kt",
+					Line:       42,
+				},
+			},
+			wantStatus: StatusDeobfuscated,
+		},
+		{
+			name:       "overload chosen with the descriptor",
+			className:  "a.a",
+			methodName: "c",
+			descriptor: "(La/b;)V",
+			wantFrames: []Frame{
+				{
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "load",
+					Arguments:  []string{"io.sentry.samples.Renderer"},
+					SourceFile: "MainActivity.kt",
+				},
+			},
+			wantStatus: StatusDeobfuscated,
+		},
+		{
+			name:       "ambiguous overload",
+			className:  "a.a",
+			methodName: "c",
+			descriptor: "(J)V",
+			wantStatus: StatusPartial,
+		},
+		{
+			name:       "duplicated method without lines",
+			className:  "a.a",
+			methodName: "d",
+			descriptor: "()V",
+			wantFrames: []Frame{
+				{
+					ClassName:  "io.sentry.samples.MainActivity",
+					Name:       "save",
+					SourceFile: "MainActivity.kt",
+				},
+			},
+			wantStatus: StatusDeobfuscated,
+		},
+		{
+			name:       "unknown method",
+			className:  "a.a",
+			methodName: "z",
+			descriptor: "()V",
+			wantStatus: StatusPartial,
+		},
+		{
+			name:       "unknown class",
+			className:  "android.app.Activity",
+			methodName: "onCreate",
+			descriptor: "(Landroid/os/Bundle;)V",
+			line:       10,
+			wantStatus: StatusMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, status := m.Frames(tt.className, tt.methodName, tt.descriptor, tt.line)
+			if status != tt.wantStatus {
+				t.Fatalf("expected status %q, got %q", tt.wantStatus, status)
+			}
+			if diff := testutil.Diff(frames, tt.wantFrames); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseInvalidMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+	}{
+		{
+			name:    "invalid class",
+			mapping: "io.sentry.samples.MainActivity\n",
+		},
+		{
+			name:    "member outside of a class",
+			mapping: "    void onCreate() -> a\n",
+		},
+		{
+			name:    "invalid line range",
+			mapping: "io.sentry.samples.MainActivity -> a.a:\n    1:void onCreate() -> a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.mapping)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
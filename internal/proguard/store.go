@@ -0,0 +1,51 @@
+package proguard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gocloud.dev/blob"
+
+	"github.com/getsentry/vroom/internal/storageutil"
+)
+
+type (
+	// Store loads mapping files from a bucket and keeps the most recently
+	// loaded ones in memory.
+	Store struct {
+		mappings *storageutil.ObjectCache[*Mapping]
+	}
+)
+
+// StoragePath returns the path of the mapping file of a project with the
+// given debug ID. Mapping files are stored as plain text.
+func StoragePath(organizationID, projectID uint64, debugID string) string {
+	return fmt.Sprintf(
+		"proguard/%d/%d/%s",
+		organizationID,
+		projectID,
+		strings.ToLower(debugID),
+	)
+}
+
+// NewStore returns a store keeping up to size mappings in memory.
+func NewStore(bucket *blob.Bucket, size int) *Store {
+	return &Store{
+		mappings: storageutil.NewObjectCache(
+			bucket,
+			storageutil.ObjectCacheOptions{Size: size},
+			Parse,
+		),
+	}
+}
+
+// Mapping returns the mapping file of a project with the given debug ID or
+// storageutil.ErrObjectNotFound if it was not uploaded.
+func (s *Store) Mapping(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	debugID string,
+) (*Mapping, error) {
+	return s.mappings.Get(ctx, StoragePath(organizationID, projectID, debugID))
+}
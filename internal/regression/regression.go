@@ -0,0 +1,250 @@
+package regression
+
+import (
+	"math"
+	"sort"
+
+	"github.com/getsentry/vroom/internal/metrics"
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+type (
+	Point struct {
+		// Timestamp is the start of the bucket, in seconds since epoch.
+		Timestamp uint64 `json:"timestamp"`
+		// Value is the aggregated duration for the bucket, usually the p95, in nanoseconds.
+		Value   float64               `json:"value"`
+		Count   uint64                `json:"count"`
+		Example utils.ExampleMetadata `json:"example"`
+		// Examples are a uniform sample of the examples of the bucket,
+		// Example being the worst one.
+		Examples []utils.ExampleMetadata `json:"examples,omitempty"`
+	}
+
+	Series struct {
+		ProjectID   uint64  `json:"project_id"`
+		Fingerprint uint32  `json:"fingerprint"`
+		Points      []Point `json:"points"`
+	}
+
+	Options struct {
+		// MinWindow is the minimum number of points required on each
+		// side of a breakpoint.
+		MinWindow int
+		// MaxPValue is the highest p-value for a breakpoint to be considered
+		// statistically significant.
+		MaxPValue float64
+		// MinTrendPercentage is the minimum ratio between the aggregates
+		// after and before the breakpoint for it to be reported.
+		MinTrendPercentage float64
+		// MinTrendDifference is the minimum increase, in nanoseconds, between
+		// the aggregates after and before the breakpoint for it to be reported.
+		MinTrendDifference float64
+	}
+)
+
+// maxExamplesPerSide is the number of examples kept on each side of the
+// breakpoint to explain a regression.
+const maxExamplesPerSide = 5
+
+var DefaultOptions = Options{
+	MinWindow:          3,
+	MaxPValue:          0.01,
+	MinTrendPercentage: 1.1,
+	MinTrendDifference: float64(100_000),
+}
+
+// SeriesFromTimeSeries converts function time series of a project into
+// series suitable for detection, using the p95 of each bucket and its worst
+// example. Empty buckets are skipped.
+func SeriesFromTimeSeries(projectID uint64, timeSeries []metrics.FunctionTimeSeries) []Series {
+	series := make([]Series, 0, len(timeSeries))
+	for _, ts := range timeSeries {
+		s := Series{
+			ProjectID:   projectID,
+			Fingerprint: uint32(ts.Fingerprint),
+			Points:      make([]Point, 0, len(ts.Buckets)),
+		}
+		for _, b := range ts.Buckets {
+			if b.Metrics == nil {
+				continue
+			}
+			s.Points = append(s.Points, Point{
+				Timestamp: b.Timestamp,
+				Value:     float64(b.Metrics.P95),
+				Count:     b.Metrics.Count,
+				Example:   b.Metrics.Worst,
+				Examples:  b.Metrics.Examples,
+			})
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// Detect looks for the split of the series maximizing the Welch's t-value
+// between both sides and returns a regressed function if the increase is
+// statistically significant and large enough.
+func Detect(
+	organizationID uint64,
+	series Series,
+	options Options,
+) (occurrence.RegressedFunction, bool) {
+	minWindow := max(options.MinWindow, 2)
+	points := make([]Point, 0, len(series.Points))
+	for _, p := range series.Points {
+		if p.Count == 0 {
+			continue
+		}
+		points = append(points, p)
+	}
+	if len(points) < 2*minWindow {
+		return occurrence.RegressedFunction{}, false
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Timestamp < points[j].Timestamp
+	})
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+
+	breakpoint := -1
+	bestT, bestDF := math.Inf(-1), 0.0
+	for i := minWindow; i <= len(values)-minWindow; i++ {
+		t, df := welchTTest(values[:i], values[i:])
+		if t > bestT {
+			breakpoint, bestT, bestDF = i, t, df
+		}
+	}
+	if breakpoint == -1 || bestT <= 0 {
+		return occurrence.RegressedFunction{}, false
+	}
+
+	pValue := oneSidedPValue(bestT, bestDF)
+	before, _ := meanAndVariance(values[:breakpoint])
+	after, _ := meanAndVariance(values[breakpoint:])
+	if before == 0 || pValue > options.MaxPValue {
+		return occurrence.RegressedFunction{}, false
+	}
+	trendPercentage := after / before
+	trendDifference := after - before
+	if trendPercentage < options.MinTrendPercentage || trendDifference < options.MinTrendDifference {
+		return occurrence.RegressedFunction{}, false
+	}
+
+	// pick the slowest example after the breakpoint
+	var example utils.ExampleMetadata
+	var maxValue float64
+	for _, p := range points[breakpoint:] {
+		if p.Example == (utils.ExampleMetadata{}) || p.Value < maxValue {
+			continue
+		}
+		example, maxValue = p.Example, p.Value
+	}
+	if example == (utils.ExampleMetadata{}) {
+		return occurrence.RegressedFunction{}, false
+	}
+
+	if math.IsInf(bestT, 1) {
+		// a step without noise on either side has a p-value of 0, keep a
+		// t-value that can be serialized
+		bestT = math.MaxFloat64
+	}
+
+	projectID := series.ProjectID
+	if projectID == 0 {
+		projectID = example.ProjectID
+	}
+
+	return occurrence.RegressedFunction{
+		OrganizationID:           organizationID,
+		ProjectID:                projectID,
+		Example:                  example,
+		ExamplesBefore:           representativeExamples(points[:breakpoint], maxExamplesPerSide),
+		ExamplesAfter:            slowestExamples(points[breakpoint:], maxExamplesPerSide),
+		Fingerprint:              series.Fingerprint,
+		AbsolutePercentageChange: math.Abs(trendPercentage),
+		AggregateRange1:          before,
+		AggregateRange2:          after,
+		Breakpoint:               points[breakpoint].Timestamp,
+		TrendDifference:          trendDifference,
+		TrendPercentage:          trendPercentage,
+		UnweightedPValue:         pValue,
+		UnweightedTValue:         bestT,
+	}, true
+}
+
+// representativeExamples returns distinct examples of the points closest to
+// the median value, preferring the sampled examples of each point to its
+// worst one, for the examples not to be slower than usual.
+func representativeExamples(points []Point, n int) []utils.ExampleMetadata {
+	if len(points) == 0 {
+		return []utils.ExampleMetadata{}
+	}
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	sort.Float64s(values)
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + median) / 2
+	}
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return math.Abs(sorted[i].Value-median) < math.Abs(sorted[j].Value-median)
+	})
+	examples := make([]utils.ExampleMetadata, 0, n)
+	seen := make(map[utils.ExampleMetadata]struct{})
+	for _, p := range sorted {
+		if len(examples) == n {
+			break
+		}
+		candidates := p.Examples
+		if len(candidates) == 0 {
+			candidates = []utils.ExampleMetadata{p.Example}
+		}
+		// keep one example per point, from as many points as possible
+		for _, example := range candidates {
+			if example == (utils.ExampleMetadata{}) {
+				continue
+			}
+			if _, exists := seen[example]; exists {
+				continue
+			}
+			seen[example] = struct{}{}
+			examples = append(examples, example)
+			break
+		}
+	}
+	return examples
+}
+
+// slowestExamples returns the distinct examples of the slowest points.
+func slowestExamples(points []Point, n int) []utils.ExampleMetadata {
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Value > sorted[j].Value
+	})
+	examples := make([]utils.ExampleMetadata, 0, n)
+	seen := make(map[utils.ExampleMetadata]struct{})
+	for _, p := range sorted {
+		if len(examples) == n {
+			break
+		}
+		if p.Example == (utils.ExampleMetadata{}) {
+			continue
+		}
+		if _, exists := seen[p.Example]; exists {
+			continue
+		}
+		seen[p.Example] = struct{}{}
+		examples = append(examples, p.Example)
+	}
+	return examples
+}
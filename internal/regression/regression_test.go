@@ -0,0 +1,180 @@
+package regression
+
+import (
+	"math"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/occurrence"
+	"github.com/getsentry/vroom/internal/testutil"
+	"github.com/getsentry/vroom/internal/utils"
+)
+
+func TestOneSidedPValue(t *testing.T) {
+	tests := []struct {
+		name string
+		t    float64
+		df   float64
+		want float64
+	}{
+		{name: "zero", t: 0, df: 10, want: 0.5},
+		{name: "positive", t: 2, df: 10, want: 0.036694},
+		{name: "negative", t: -2, df: 10, want: 0.963306},
+		{name: "large df", t: 1.644854, df: 1e6, want: 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oneSidedPValue(tt.t, tt.df); math.Abs(got-tt.want) > 1e-5 {
+				t.Fatalf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	pointsFromValues := func(values ...float64) []Point {
+		points := make([]Point, 0, len(values))
+		for i, v := range values {
+			points = append(points, Point{
+				Timestamp: uint64(i) * 3600,
+				Value:     v,
+				Count:     10,
+				Example:   utils.ExampleMetadata{ProjectID: 1, ProfileID: string(rune('a' + i))},
+			})
+		}
+		return points
+	}
+
+	tests := []struct {
+		name   string
+		series Series
+		want   *occurrence.RegressedFunction
+	}{
+		{
+			name: "regression",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(10e6, 11e6, 9e6, 10e6, 20e6, 21e6, 19e6, 20e6),
+			},
+			want: &occurrence.RegressedFunction{
+				OrganizationID: 1,
+				ProjectID:      1,
+				Example:        utils.ExampleMetadata{ProjectID: 1, ProfileID: "f"},
+				ExamplesBefore: []utils.ExampleMetadata{
+					{ProjectID: 1, ProfileID: "a"},
+					{ProjectID: 1, ProfileID: "d"},
+					{ProjectID: 1, ProfileID: "b"},
+					{ProjectID: 1, ProfileID: "c"},
+				},
+				ExamplesAfter: []utils.ExampleMetadata{
+					{ProjectID: 1, ProfileID: "f"},
+					{ProjectID: 1, ProfileID: "e"},
+					{ProjectID: 1, ProfileID: "h"},
+					{ProjectID: 1, ProfileID: "g"},
+				},
+				Fingerprint:              42,
+				AbsolutePercentageChange: 2,
+				AggregateRange1:          10e6,
+				AggregateRange2:          20e6,
+				Breakpoint:               4 * 3600,
+				TrendDifference:          10e6,
+				TrendPercentage:          2,
+			},
+		},
+		{
+			name: "no regression",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(10e6, 11e6, 9e6, 10e6, 10e6, 11e6, 9e6, 10e6),
+			},
+		},
+		{
+			name: "improvement",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(20e6, 21e6, 19e6, 20e6, 10e6, 11e6, 9e6, 10e6),
+			},
+		},
+		{
+			name: "noiseless step",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(10e6, 10e6, 10e6, 10e6, 20e6, 20e6, 20e6, 20e6),
+			},
+			want: &occurrence.RegressedFunction{
+				OrganizationID: 1,
+				ProjectID:      1,
+				Example:        utils.ExampleMetadata{ProjectID: 1, ProfileID: "h"},
+				ExamplesBefore: []utils.ExampleMetadata{
+					{ProjectID: 1, ProfileID: "a"},
+					{ProjectID: 1, ProfileID: "b"},
+					{ProjectID: 1, ProfileID: "c"},
+					{ProjectID: 1, ProfileID: "d"},
+				},
+				ExamplesAfter: []utils.ExampleMetadata{
+					{ProjectID: 1, ProfileID: "e"},
+					{ProjectID: 1, ProfileID: "f"},
+					{ProjectID: 1, ProfileID: "g"},
+					{ProjectID: 1, ProfileID: "h"},
+				},
+				Fingerprint:              42,
+				AbsolutePercentageChange: 2,
+				AggregateRange1:          10e6,
+				AggregateRange2:          20e6,
+				Breakpoint:               4 * 3600,
+				TrendDifference:          10e6,
+				TrendPercentage:          2,
+			},
+		},
+		{
+			name: "no variance",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(10e6, 10e6, 10e6, 10e6, 10e6, 10e6, 10e6, 10e6),
+			},
+		},
+		{
+			name: "not enough points",
+			series: Series{
+				Fingerprint: 42,
+				Points:      pointsFromValues(10e6, 20e6),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect(1, tt.series, DefaultOptions)
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("expected no regression, got %+v", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected a regression")
+			}
+			if got.UnweightedPValue > DefaultOptions.MaxPValue || got.UnweightedTValue <= 0 {
+				t.Fatalf("unexpected t-test result: t=%f, p=%f", got.UnweightedTValue, got.UnweightedPValue)
+			}
+			got.UnweightedPValue, got.UnweightedTValue = 0, 0
+			if diff := testutil.Diff(got, *tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRepresentativeExamples(t *testing.T) {
+	example := func(id string) utils.ExampleMetadata {
+		return utils.ExampleMetadata{ProjectID: 1, ProfileID: id}
+	}
+	points := []Point{
+		{Value: 10e6, Example: example("worst-a"), Examples: []utils.ExampleMetadata{example("a1"), example("a2")}},
+		{Value: 50e6, Example: example("worst-b"), Examples: []utils.ExampleMetadata{example("b1")}},
+		{Value: 11e6, Example: example("worst-c")},
+	}
+	want := []utils.ExampleMetadata{example("worst-c"), example("a1"), example("b1")}
+	if diff := testutil.Diff(representativeExamples(points, 5), want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
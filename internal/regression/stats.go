@@ -0,0 +1,127 @@
+package regression
+
+import "math"
+
+const (
+	betaMaxIterations = 200
+	betaEpsilon       = 3e-14
+	betaMinValue      = 1e-300
+)
+
+// welchTTest returns the t-value and the Welch–Satterthwaite degrees of
+// freedom for the difference of means between b and a. A positive t-value
+// means the mean of b is greater than the mean of a.
+func welchTTest(a, b []float64) (float64, float64) {
+	meanA, varA := meanAndVariance(a)
+	meanB, varB := meanAndVariance(b)
+	nA, nB := float64(len(a)), float64(len(b))
+	seA, seB := varA/nA, varB/nB
+	se := seA + seB
+	if se == 0 {
+		switch {
+		case meanB > meanA:
+			return math.Inf(1), nA + nB - 2
+		case meanB < meanA:
+			return math.Inf(-1), nA + nB - 2
+		}
+		return 0, nA + nB - 2
+	}
+	t := (meanB - meanA) / math.Sqrt(se)
+	df := se * se / (seA*seA/(nA-1) + seB*seB/(nB-1))
+	return t, df
+}
+
+// oneSidedPValue returns the probability of observing a t-value at least
+// as large as t under the null hypothesis, using the Student's
+// t-distribution with df degrees of freedom.
+func oneSidedPValue(t, df float64) float64 {
+	if math.IsInf(t, 1) {
+		return 0
+	}
+	if math.IsInf(t, -1) {
+		return 1
+	}
+	p := 0.5 * regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+	if t < 0 {
+		return 1 - p
+	}
+	return p
+}
+
+func meanAndVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, sq / float64(len(values)-1)
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) using its continued
+// fraction representation, evaluated with the modified Lentz's method.
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// the continued fraction converges faster on this side
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < betaMinValue {
+		d = betaMinValue
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= betaMaxIterations; m++ {
+		fm := float64(m)
+		// even step
+		aa := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + aa*d
+		if math.Abs(d) < betaMinValue {
+			d = betaMinValue
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < betaMinValue {
+			c = betaMinValue
+		}
+		d = 1 / d
+		h *= d * c
+		// odd step
+		aa = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + aa*d
+		if math.Abs(d) < betaMinValue {
+			d = betaMinValue
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < betaMinValue {
+			c = betaMinValue
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < betaEpsilon {
+			break
+		}
+	}
+	return h
+}
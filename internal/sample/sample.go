@@ -18,6 +18,7 @@ import (
 	"github.com/getsentry/vroom/internal/nodetree"
 	"github.com/getsentry/vroom/internal/platform"
 	"github.com/getsentry/vroom/internal/speedscope"
+	"github.com/getsentry/vroom/internal/stacktracerules"
 	"github.com/getsentry/vroom/internal/timeutil"
 	"github.com/getsentry/vroom/internal/transaction"
 	"github.com/getsentry/vroom/internal/utils"
@@ -53,7 +54,10 @@ type (
 		QueueAddress        string `json:"queue_address,omitempty"`
 		StackID             int    `json:"stack_id"`
 		State               State  `json:"state,omitempty"`
-		ThreadID            uint64 `json:"thread_id"`
+		// TaskID is the ID of the async task running when the sample was
+		// taken, if any.
+		TaskID   string `json:"task_id,omitempty"`
+		ThreadID uint64 `json:"thread_id"`
 	}
 
 	ThreadMetadata struct {
@@ -65,14 +69,29 @@ type (
 		Label string `json:"label"`
 	}
 
+	// AsyncTaskMetadata describes an async task, an asyncio task or a chain
+	// of promises, for its stacks to be stitched to the ones of the task
+	// awaiting it.
+	AsyncTaskMetadata struct {
+		// EntryFrame is the index of the outermost frame of the task, the
+		// coroutine or the async function resumed by the event loop.
+		EntryFrame int `json:"entry_frame"`
+		// AwaitedBy is the ID of the task awaiting this one, if any.
+		AwaitedBy string `json:"awaited_by,omitempty"`
+		// AwaitingFrames are the indexes of the frames of the awaiting task
+		// suspended until this one completes, the innermost frame first.
+		AwaitingFrames []int `json:"awaiting_frames,omitempty"`
+	}
+
 	Stack []int
 
 	Trace struct {
-		Frames         []frame.Frame             `json:"frames"`
-		QueueMetadata  map[string]QueueMetadata  `json:"queue_metadata"`
-		Samples        []Sample                  `json:"samples"`
-		Stacks         []Stack                   `json:"stacks"`
-		ThreadMetadata map[string]ThreadMetadata `json:"thread_metadata"`
+		AsyncTaskMetadata map[string]AsyncTaskMetadata `json:"async_task_metadata,omitempty"`
+		Frames            []frame.Frame                `json:"frames"`
+		QueueMetadata     map[string]QueueMetadata     `json:"queue_metadata"`
+		Samples           []Sample                     `json:"samples"`
+		Stacks            []Stack                      `json:"stacks"`
+		ThreadMetadata    map[string]ThreadMetadata    `json:"thread_metadata"`
 	}
 
 	Profile struct {
@@ -182,6 +201,23 @@ func (p Profile) GetRetentionDays() int {
 	return p.RetentionDays
 }
 
+// GetThreadName returns the name of a thread, falling back on the label
+// of the queue of its first sample.
+func (p Profile) GetThreadName(threadID uint64) string {
+	var queueAddress string
+	for _, s := range p.Trace.Samples {
+		if s.ThreadID == threadID {
+			queueAddress = s.QueueAddress
+			break
+		}
+	}
+	return p.Trace.ThreadName(
+		strconv.FormatUint(threadID, 10),
+		queueAddress,
+		threadID == p.Transaction.ActiveThreadID,
+	)
+}
+
 func (p Profile) GetDurationNS() uint64 {
 	maxSampleIndex := len(p.Trace.Samples) - 1
 	if maxSampleIndex < 0 {
@@ -426,10 +462,14 @@ func (p *Profile) Metadata() metadata.Metadata {
 	}
 }
 
-func (p *Profile) Normalize() {
+func (p *Profile) Normalize(rules stacktracerules.Rules) {
 	for i := range p.Trace.Frames {
 		f := p.Trace.Frames[i]
-		f.Normalize(p.Platform)
+		f.NormalizeForRuntime(p.Platform, frame.Runtime{
+			Version:      p.Runtime.Version,
+			GoMainModule: p.Options.GoMainModule,
+		})
+		rules.Apply(&f)
 		p.Trace.Frames[i] = f
 	}
 
@@ -439,6 +479,9 @@ func (p *Profile) Normalize() {
 		p.Trace.trimPythonStacks()
 	}
 
+	if p.Options.StitchAsyncStacks {
+		p.Trace.stitchAsyncStacks()
+	}
 	p.Trace.ReplaceIdleStacks()
 }
 
@@ -584,6 +627,128 @@ func (t Trace) CollectFrames(stackID int) []frame.Frame {
 	return frames
 }
 
+// ReplaceFrames replaces the frames, each frame of the stacks and of the
+// async task metadata being replaced by the frames at the given indexes.
+func (t *Trace) ReplaceFrames(frames []frame.Frame, indexes [][]int) {
+	ReplaceStackFrames(t.Stacks, t.AsyncTaskMetadata, indexes, len(frames))
+	t.Frames = frames
+}
+
+// ReplaceStackFrames replaces each frame ID of the stacks and of the async
+// task metadata by the frame IDs at its index in indexes, frameCount being
+// the number of new frames. Frame IDs out of range are kept out of range.
+func ReplaceStackFrames[S ~[]int](
+	stacks []S,
+	tasks map[string]AsyncTaskMetadata,
+	indexes [][]int,
+	frameCount int,
+) {
+	for i, stack := range stacks {
+		replaced := make(S, 0, len(stack))
+		for _, frameID := range stack {
+			replaced = append(replaced, replaceFrameID(frameID, indexes, frameCount)...)
+		}
+		stacks[i] = replaced
+	}
+	for taskID, task := range tasks {
+		// the entry frame is the outermost frame of the task
+		if entryFrames := replaceFrameID(task.EntryFrame, indexes, frameCount); len(entryFrames) > 0 {
+			task.EntryFrame = entryFrames[len(entryFrames)-1]
+		}
+		var awaitingFrames []int
+		for _, frameID := range task.AwaitingFrames {
+			awaitingFrames = append(awaitingFrames, replaceFrameID(frameID, indexes, frameCount)...)
+		}
+		task.AwaitingFrames = awaitingFrames
+		tasks[taskID] = task
+	}
+}
+
+func replaceFrameID(frameID int, indexes [][]int, frameCount int) []int {
+	switch {
+	case frameID < 0:
+		return []int{frameID}
+	case frameID >= len(indexes):
+		return []int{frameCount + frameID - len(indexes)}
+	default:
+		return indexes[frameID]
+	}
+}
+
+// FramePositions reports which frames are at the top of the stacks and
+// which are below it, see the function of the same name.
+func (t *Trace) FramePositions() ([]bool, []bool) {
+	return FramePositions(t.Stacks, len(t.Frames))
+}
+
+// SplitCallerFrames makes the stacks use the copies of frames below their
+// top, see the function of the same name.
+func (t *Trace) SplitCallerFrames(copies map[int]int) {
+	SplitCallerFrames(t.Stacks, copies)
+}
+
+// FramePositions reports, for each frame, whether it's at the top of the
+// stacks and whether it's below it, its instruction address then being a
+// return address. Leaves are nil if some frame IDs are out of range, frames
+// found at both positions not being told apart then since copying them
+// would make those IDs valid.
+func FramePositions[S ~[]int](stacks []S, frameCount int) ([]bool, []bool) {
+	leaves := make([]bool, frameCount)
+	callers := make([]bool, frameCount)
+	outOfRange := false
+	for _, stack := range stacks {
+		for i, frameID := range stack {
+			switch {
+			case frameID < 0:
+			case frameID >= frameCount:
+				outOfRange = true
+			case i == 0:
+				leaves[frameID] = true
+			default:
+				callers[frameID] = true
+			}
+		}
+	}
+	if outOfRange {
+		return nil, callers
+	}
+	return leaves, callers
+}
+
+// SplitCallerFrames replaces the frame IDs below the top of the stacks by
+// the ID of their copy, for frames symbolicated differently there.
+func SplitCallerFrames[S ~[]int](stacks []S, copies map[int]int) {
+	if len(copies) == 0 {
+		return
+	}
+	for _, stack := range stacks {
+		for i := 1; i < len(stack); i++ {
+			if frameID, exists := copies[stack[i]]; exists {
+				stack[i] = frameID
+			}
+		}
+	}
+}
+
+// CallerFrameIDs returns, for each frame, the ID of the first frame found
+// calling it in the stacks or -1 if it's never called.
+func CallerFrameIDs[S ~[]int](stacks []S, frameCount int) []int {
+	callers := make([]int, frameCount)
+	for i := range callers {
+		callers[i] = -1
+	}
+	for _, stack := range stacks {
+		for i := 0; i+1 < len(stack); i++ {
+			frameID := stack[i]
+			if frameID < 0 || frameID >= frameCount || callers[frameID] >= 0 {
+				continue
+			}
+			callers[frameID] = stack[i+1]
+		}
+	}
+	return callers
+}
+
 func (p *RawProfile) moveTransaction() {
 	if len(p.Transactions) > 0 {
 		p.Transaction = p.Transactions[0]
@@ -666,6 +831,115 @@ func (t *Trace) trimPythonStacks() {
 	}
 }
 
+// stitchAsyncStacks replaces the stacks of the samples taken while an async
+// task was running by their logical stacks, see StitchAsyncStacks.
+func (t *Trace) stitchAsyncStacks() {
+	if len(t.AsyncTaskMetadata) == 0 {
+		return
+	}
+	stackIDs := make([]int, len(t.Samples))
+	taskIDs := make([]string, len(t.Samples))
+	for i, s := range t.Samples {
+		stackIDs[i] = s.StackID
+		taskIDs[i] = s.TaskID
+	}
+	t.Stacks = StitchAsyncStacks(t.Stacks, stackIDs, taskIDs, t.AsyncTaskMetadata, len(t.Frames))
+	for i := range t.Samples {
+		t.Samples[i].StackID = stackIDs[i]
+	}
+}
+
+// StitchAsyncStacks stitches the stack of each sample taken while an async
+// task was running, for the time spent in the task to be attributed to the
+// tasks awaiting it. stackIDs and taskIDs hold the stack and the task of each
+// sample. Stitched stacks are appended to the stacks returned and the stack
+// IDs are updated to point to them, samples sharing a stack and a task
+// sharing the stitched stack.
+func StitchAsyncStacks[S ~[]int](
+	stacks []S,
+	stackIDs []int,
+	taskIDs []string,
+	tasks map[string]AsyncTaskMetadata,
+	frameCount int,
+) []S {
+	type taskStack struct {
+		stackID int
+		taskID  string
+	}
+	stitchedStackIDs := make(map[taskStack]int)
+	for i, stackID := range stackIDs {
+		taskID := taskIDs[i]
+		if taskID == "" || stackID < 0 || stackID >= len(stacks) {
+			continue
+		}
+		key := taskStack{stackID: stackID, taskID: taskID}
+		stitchedStackID, exists := stitchedStackIDs[key]
+		if !exists {
+			stitchedStackID = stackID
+			stack := StitchAsyncStack(stacks[stackID], taskID, tasks, frameCount)
+			if len(stack) != len(stacks[stackID]) {
+				stitchedStackID = len(stacks)
+				stacks = append(stacks, stack)
+			}
+			stitchedStackIDs[key] = stitchedStackID
+		}
+		stackIDs[i] = stitchedStackID
+	}
+	return stacks
+}
+
+// StitchAsyncStack returns the logical stack of a sample taken while a task
+// was running, the frames of the task up to its entry frame followed by the
+// frames of the tasks awaiting it, then by the frames of the event loop.
+// Stacks have the innermost frame first and the stack is returned as is if
+// the task is unknown or its entry frame is not in the stack.
+func StitchAsyncStack(
+	stack []int,
+	taskID string,
+	tasks map[string]AsyncTaskMetadata,
+	frameCount int,
+) []int {
+	task, exists := tasks[taskID]
+	if !exists {
+		return stack
+	}
+	// the entry frame closest to the root, in case the task is recursive
+	entry := -1
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == task.EntryFrame {
+			entry = i
+			break
+		}
+	}
+	if entry == -1 {
+		return stack
+	}
+	var awaitingFrames []int
+	visited := map[string]struct{}{taskID: {}}
+	for {
+		for _, frameID := range task.AwaitingFrames {
+			if frameID >= 0 && frameID < frameCount {
+				awaitingFrames = append(awaitingFrames, frameID)
+			}
+		}
+		if _, seen := visited[task.AwaitedBy]; seen || task.AwaitedBy == "" {
+			break
+		}
+		visited[task.AwaitedBy] = struct{}{}
+		task, exists = tasks[task.AwaitedBy]
+		if !exists {
+			break
+		}
+	}
+	if len(awaitingFrames) == 0 {
+		return stack
+	}
+	stitched := make([]int, 0, len(stack)+len(awaitingFrames))
+	stitched = append(stitched, stack[:entry+1]...)
+	stitched = append(stitched, awaitingFrames...)
+	return append(stitched, stack[entry+1:]...)
+}
+
 func (p RawProfile) GetTransactionMetadata() transaction.Metadata {
 	return p.TransactionMetadata
 }
@@ -902,7 +902,7 @@ func TestTrimCocoaStacks(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			if diff := testutil.Diff(test.input, test.output); diff != "" {
 				t.Fatalf("Result mismatch: got - want +\n%s", diff)
 			}
@@ -983,7 +983,7 @@ func TestTrimPythonStacks(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			if diff := testutil.Diff(test.input, test.output); diff != "" {
 				t.Fatalf("Result mismatch: got - want +\n%s", diff)
 			}
@@ -1207,7 +1207,7 @@ func TestNormalizeFramesPerPlatform(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			if diff := testutil.Diff(test.input, test.output); diff != "" {
 				t.Fatalf("Result mismatch: got - want +\n%s", diff)
 			}
@@ -1393,7 +1393,7 @@ func TestCallTreesFingerprintPerPlatform(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			test.input.Normalize()
+			test.input.Normalize(nil)
 			callTrees, err := test.input.CallTrees()
 			if err != nil {
 				t.Fatal(err)
@@ -1404,3 +1404,275 @@ func TestCallTreesFingerprintPerPlatform(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceFrames(t *testing.T) {
+	trace := Trace{
+		Frames: []frame.Frame{
+			{Function: "main"},
+			{InstructionAddr: "0x1118"},
+			{Function: "run"},
+		},
+		Stacks: []Stack{
+			{1, 0},
+			{2, 1, 0},
+			{3},
+		},
+	}
+	frames := []frame.Frame{
+		{Function: "main"},
+		{Function: "square", InstructionAddr: "0x1118"},
+		{Function: "compute", InstructionAddr: "0x1118", SymAddr: "0x1110"},
+		{Function: "run"},
+	}
+
+	trace.ReplaceFrames(frames, [][]int{{0}, {1, 2}, {3}})
+
+	want := Trace{
+		Frames: frames,
+		Stacks: []Stack{
+			{1, 2, 0},
+			{3, 1, 2, 0},
+			{4},
+		},
+	}
+	if diff := testutil.Diff(trace, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestFramePositions(t *testing.T) {
+	stacks := []Stack{
+		{1, 2, 0},
+		{2, 0},
+		{3, 0},
+	}
+
+	leaves, callers := FramePositions(stacks, 4)
+
+	if diff := testutil.Diff(leaves, []bool{false, true, true, true}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if diff := testutil.Diff(callers, []bool{true, false, true, false}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+
+	leaves, _ = FramePositions(append(stacks, Stack{4}), 4)
+
+	if leaves != nil {
+		t.Fatalf("expected no leaves with frame IDs out of range, got %v", leaves)
+	}
+}
+
+func TestSplitCallerFrames(t *testing.T) {
+	trace := Trace{
+		Frames: []frame.Frame{
+			{Function: "main"},
+			{InstructionAddr: "0x1118"},
+			{InstructionAddr: "0x1139"},
+			{Function: "run"},
+		},
+		Stacks: []Stack{
+			{1, 2, 0},
+			{2, 0},
+			{3, 0},
+		},
+	}
+
+	trace.SplitCallerFrames(map[int]int{2: 4})
+
+	want := []Stack{
+		{1, 4, 0},
+		{2, 0},
+		{3, 0},
+	}
+	if diff := testutil.Diff(trace.Stacks, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestCallerFrameIDs(t *testing.T) {
+	stacks := []Stack{
+		{1, 2, 0},
+		{2, 3, 0},
+		{4, -1},
+	}
+
+	got := CallerFrameIDs(stacks, 5)
+
+	if diff := testutil.Diff(got, []int{-1, 2, 0, 0, -1}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestReplaceFramesOfAsyncTasks(t *testing.T) {
+	// the event loop (0) resumed the fetch task (2), awaited by the handler
+	// (3), while it was in a native function (1) inlining another one
+	trace := Trace{
+		AsyncTaskMetadata: map[string]AsyncTaskMetadata{
+			"fetch":   {EntryFrame: 2, AwaitedBy: "handler", AwaitingFrames: []int{3}},
+			"handler": {EntryFrame: 3},
+		},
+		Frames: []frame.Frame{
+			{Function: "run_forever"},
+			{InstructionAddr: "0x1118"},
+			{Function: "fetch"},
+			{Function: "handle_request"},
+		},
+		Samples: []Sample{{StackID: 0, TaskID: "fetch"}},
+		Stacks:  []Stack{{1, 2, 0}},
+	}
+	frames := []frame.Frame{
+		{Function: "run_forever"},
+		{Function: "square", InstructionAddr: "0x1118"},
+		{Function: "compute", InstructionAddr: "0x1118"},
+		{Function: "fetch"},
+		{Function: "handle_request"},
+	}
+
+	trace.ReplaceFrames(frames, [][]int{{0}, {1, 2}, {3}, {4}})
+	trace.stitchAsyncStacks()
+
+	wantTasks := map[string]AsyncTaskMetadata{
+		"fetch":   {EntryFrame: 3, AwaitedBy: "handler", AwaitingFrames: []int{4}},
+		"handler": {EntryFrame: 4},
+	}
+	if diff := testutil.Diff(trace.AsyncTaskMetadata, wantTasks); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	got := trace.Stacks[trace.Samples[0].StackID]
+	if diff := testutil.Diff(got, Stack{1, 2, 3, 4, 0}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestStitchAsyncStack(t *testing.T) {
+	// frames are the event loop (0, 1), a task fetching data (2, 3), the
+	// request handler awaiting it (4, 5) and the server awaiting the handler (6)
+	tasks := map[string]AsyncTaskMetadata{
+		"fetch": {
+			EntryFrame:     2,
+			AwaitedBy:      "handler",
+			AwaitingFrames: []int{5, 4},
+		},
+		"handler": {
+			EntryFrame:     4,
+			AwaitedBy:      "server",
+			AwaitingFrames: []int{6},
+		},
+		"server": {
+			EntryFrame: 6,
+		},
+		"a": {
+			EntryFrame:     2,
+			AwaitedBy:      "b",
+			AwaitingFrames: []int{4},
+		},
+		"b": {
+			EntryFrame:     4,
+			AwaitedBy:      "a",
+			AwaitingFrames: []int{2},
+		},
+		"invalid": {
+			EntryFrame:     2,
+			AwaitingFrames: []int{42},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		stack  []int
+		taskID string
+		want   []int
+	}{
+		{
+			name:   "awaiting tasks",
+			stack:  []int{3, 2, 1, 0},
+			taskID: "fetch",
+			want:   []int{3, 2, 5, 4, 6, 1, 0},
+		},
+		{
+			name:   "awaited task",
+			stack:  []int{5, 4, 1, 0},
+			taskID: "handler",
+			want:   []int{5, 4, 6, 1, 0},
+		},
+		{
+			name:   "task not awaited",
+			stack:  []int{6, 1, 0},
+			taskID: "server",
+			want:   []int{6, 1, 0},
+		},
+		{
+			name:   "unknown task",
+			stack:  []int{3, 2, 1, 0},
+			taskID: "unknown",
+			want:   []int{3, 2, 1, 0},
+		},
+		{
+			name:   "entry frame not in the stack",
+			stack:  []int{1, 0},
+			taskID: "fetch",
+			want:   []int{1, 0},
+		},
+		{
+			name:   "recursive task",
+			stack:  []int{2, 3, 2, 1, 0},
+			taskID: "fetch",
+			want:   []int{2, 3, 2, 5, 4, 6, 1, 0},
+		},
+		{
+			name:   "tasks awaiting each other",
+			stack:  []int{3, 2, 1, 0},
+			taskID: "a",
+			want:   []int{3, 2, 4, 2, 1, 0},
+		},
+		{
+			name:   "invalid awaiting frame",
+			stack:  []int{3, 2, 1, 0},
+			taskID: "invalid",
+			want:   []int{3, 2, 1, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stack := StitchAsyncStack(test.stack, test.taskID, tasks, 7)
+			if diff := testutil.Diff(stack, test.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStitchAsyncStacks(t *testing.T) {
+	tasks := map[string]AsyncTaskMetadata{
+		"fetch": {
+			EntryFrame:     2,
+			AwaitedBy:      "handler",
+			AwaitingFrames: []int{4},
+		},
+		"handler": {
+			EntryFrame: 4,
+		},
+	}
+	stacks := []Stack{
+		{3, 2, 1, 0},
+		{4, 1, 0},
+	}
+	stackIDs := []int{0, 0, 1, 0, 42}
+	taskIDs := []string{"fetch", "fetch", "handler", "", "fetch"}
+
+	stacks = StitchAsyncStacks(stacks, stackIDs, taskIDs, tasks, 5)
+
+	if diff := testutil.Diff(stackIDs, []int{2, 2, 1, 0, 42}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	wantStacks := []Stack{
+		{3, 2, 1, 0},
+		{4, 1, 0},
+		{3, 2, 4, 1, 0},
+	}
+	if diff := testutil.Diff(stacks, wantStacks); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
@@ -0,0 +1,274 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// SourceMap maps positions in a generated file to positions in the
+	// original sources, following the source map revision 3 format.
+	SourceMap struct {
+		sources []string
+		names   []string
+		// lines holds the segments of each generated line, sorted by column.
+		lines [][]segment
+		// sections holds the maps of an index map, sorted by offset.
+		sections []section
+	}
+
+	// Token is a position in an original source. Lines and columns start
+	// at 1.
+	Token struct {
+		Source string
+		Line   uint32
+		Column uint32
+		// Name is the original name at this position, if any.
+		Name string
+	}
+
+	segment struct {
+		generatedColumn int
+		// source and name are -1 if the segment doesn't have any.
+		source       int
+		sourceLine   int
+		sourceColumn int
+		name         int
+	}
+
+	section struct {
+		line      int
+		column    int
+		sourceMap *SourceMap
+	}
+
+	rawSourceMap struct {
+		Version    int          `json:"version"`
+		Sources    []string     `json:"sources"`
+		SourceRoot string       `json:"sourceRoot"`
+		Names      []string     `json:"names"`
+		Mappings   string       `json:"mappings"`
+		Sections   []rawSection `json:"sections"`
+	}
+
+	rawSection struct {
+		Offset struct {
+			Line   int `json:"line"`
+			Column int `json:"column"`
+		} `json:"offset"`
+		Map *rawSourceMap `json:"map"`
+	}
+)
+
+var (
+	ErrInvalidSourceMap = errors.New("invalid source map")
+
+	base64Values = func() [256]int8 {
+		var values [256]int8
+		for i := range values {
+			values[i] = -1
+		}
+		const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+		for i := 0; i < len(alphabet); i++ {
+			values[alphabet[i]] = int8(i)
+		}
+		return values
+	}()
+)
+
+const (
+	vlqContinuationBit = 32
+	vlqValueMask       = 31
+)
+
+// Parse reads a source map or an index map.
+func Parse(r io.Reader) (*SourceMap, error) {
+	var raw rawSourceMap
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSourceMap, err)
+	}
+	return raw.sourceMap()
+}
+
+func (raw *rawSourceMap) sourceMap() (*SourceMap, error) {
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSourceMap, raw.Version)
+	}
+	if len(raw.Sections) > 0 {
+		m := &SourceMap{sections: make([]section, 0, len(raw.Sections))}
+		for _, s := range raw.Sections {
+			if s.Map == nil {
+				return nil, fmt.Errorf("%w: section without a map", ErrInvalidSourceMap)
+			}
+			sm, err := s.Map.sourceMap()
+			if err != nil {
+				return nil, err
+			}
+			m.sections = append(m.sections, section{
+				line:      s.Offset.Line,
+				column:    s.Offset.Column,
+				sourceMap: sm,
+			})
+		}
+		sort.SliceStable(m.sections, func(i, j int) bool {
+			return m.sections[i].before(m.sections[j].line, m.sections[j].column)
+		})
+		return m, nil
+	}
+
+	m := &SourceMap{
+		sources: make([]string, 0, len(raw.Sources)),
+		names:   raw.Names,
+	}
+	for _, source := range raw.Sources {
+		if raw.SourceRoot != "" {
+			source = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + source
+		}
+		m.sources = append(m.sources, source)
+	}
+	lines, err := parseMappings(raw.Mappings, len(m.sources), len(m.names))
+	if err != nil {
+		return nil, err
+	}
+	m.lines = lines
+	return m, nil
+}
+
+// parseMappings decodes the segments of each generated line, encoded as
+// base64 VLQ values relative to the previous segment.
+func parseMappings(mappings string, sources, names int) ([][]segment, error) {
+	lines := make([][]segment, 0, strings.Count(mappings, ";")+1)
+	var source, sourceLine, sourceColumn, name int
+	for _, rawLine := range strings.Split(mappings, ";") {
+		var line []segment
+		generatedColumn := 0
+		for _, rawSegment := range strings.Split(rawLine, ",") {
+			if rawSegment == "" {
+				continue
+			}
+			values, err := decodeVLQ(rawSegment)
+			if err != nil {
+				return nil, err
+			}
+			s := segment{source: -1, name: -1}
+			generatedColumn += values[0]
+			s.generatedColumn = generatedColumn
+			switch len(values) {
+			case 1:
+			case 4, 5:
+				source += values[1]
+				sourceLine += values[2]
+				sourceColumn += values[3]
+				if source < 0 || source >= sources {
+					return nil, fmt.Errorf("%w: invalid source index %d", ErrInvalidSourceMap, source)
+				}
+				s.source, s.sourceLine, s.sourceColumn = source, sourceLine, sourceColumn
+				if len(values) == 5 {
+					name += values[4]
+					if name < 0 || name >= names {
+						return nil, fmt.Errorf("%w: invalid name index %d", ErrInvalidSourceMap, name)
+					}
+					s.name = name
+				}
+			default:
+				return nil, fmt.Errorf("%w: invalid segment %q", ErrInvalidSourceMap, rawSegment)
+			}
+			line = append(line, s)
+		}
+		sort.SliceStable(line, func(i, j int) bool {
+			return line[i].generatedColumn < line[j].generatedColumn
+		})
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func decodeVLQ(s string) ([]int, error) {
+	values := make([]int, 0, 5)
+	value, shift := 0, 0
+	for i := 0; i < len(s); i++ {
+		digit := base64Values[s[i]]
+		if digit < 0 {
+			return nil, fmt.Errorf("%w: invalid character %q", ErrInvalidSourceMap, s[i])
+		}
+		value += int(digit&vlqValueMask) << shift
+		if digit&vlqContinuationBit != 0 {
+			shift += 5
+			continue
+		}
+		// the lowest bit is the sign
+		if value&1 == 1 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("%w: truncated value %q", ErrInvalidSourceMap, s)
+	}
+	return values, nil
+}
+
+func (s section) before(line, column int) bool {
+	return s.line < line || (s.line == line && s.column < column)
+}
+
+// Lookup returns the original position of a position in the generated file,
+// lines and columns starting at 1.
+func (m *SourceMap) Lookup(line, column uint32) (Token, bool) {
+	if line == 0 {
+		return Token{}, false
+	}
+	l := int(line) - 1
+	c := 0
+	if column > 0 {
+		c = int(column) - 1
+	}
+	return m.lookup(l, c)
+}
+
+// lookup returns the original position of a position starting at 0.
+func (m *SourceMap) lookup(line, column int) (Token, bool) {
+	if len(m.sections) > 0 {
+		i := sort.Search(len(m.sections), func(i int) bool {
+			return !m.sections[i].before(line, column+1)
+		})
+		if i == 0 {
+			return Token{}, false
+		}
+		s := m.sections[i-1]
+		if line == s.line {
+			column -= s.column
+		}
+		return s.sourceMap.lookup(line-s.line, column)
+	}
+
+	if line >= len(m.lines) {
+		return Token{}, false
+	}
+	segments := m.lines[line]
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].generatedColumn > column
+	})
+	if i == 0 {
+		return Token{}, false
+	}
+	s := segments[i-1]
+	if s.source < 0 {
+		return Token{}, false
+	}
+	t := Token{
+		Source: m.sources[s.source],
+		Line:   uint32(s.sourceLine + 1),
+		Column: uint32(s.sourceColumn + 1),
+	}
+	if s.name >= 0 {
+		t.Name = m.names[s.name]
+	}
+	return t, true
+}
@@ -0,0 +1,228 @@
+package sourcemap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gocloud.dev/blob/memblob"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+const testSourceMap = `{
+	"version": 3,
+	"file": "main.js",
+	"sourceRoot": "webpack://app/",
+	"sources": ["./src/math.js", "./src/App.tsx"],
+	"names": ["double", "render"],
+	"mappings": "AAAA,SAASA,+BCUPC;IAUkG,wSDlBpGD"
+}`
+
+func TestLookup(t *testing.T) {
+	m, err := Parse(strings.NewReader(testSourceMap))
+	if err != nil {
+		t.Fatalf("couldn't parse source map: %v", err)
+	}
+	index, err := Parse(strings.NewReader(`{
+		"version": 3,
+		"sections": [
+			{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "names": [], "mappings": "AAAA"}},
+			{"offset": {"line": 10, "column": 5}, "map": ` + testSourceMap + `}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("couldn't parse index map: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		sourceMap *SourceMap
+		line      uint32
+		column    uint32
+		want      Token
+		wantOK    bool
+	}{
+		{
+			name:      "token with a name",
+			sourceMap: m,
+			line:      1,
+			column:    10,
+			want:      Token{Source: "webpack://app/./src/math.js", Line: 1, Column: 10, Name: "double"},
+			wantOK:    true,
+		},
+		{
+			name:      "position between tokens",
+			sourceMap: m,
+			line:      1,
+			column:    45,
+			want:      Token{Source: "webpack://app/./src/App.tsx", Line: 11, Column: 3, Name: "render"},
+			wantOK:    true,
+		},
+		{
+			name:      "token without a name",
+			sourceMap: m,
+			line:      2,
+			column:    5,
+			want:      Token{Source: "webpack://app/./src/App.tsx", Line: 21, Column: 101},
+			wantOK:    true,
+		},
+		{
+			name:      "negative offsets",
+			sourceMap: m,
+			line:      2,
+			column:    301,
+			want:      Token{Source: "webpack://app/./src/math.js", Line: 3, Column: 1, Name: "double"},
+			wantOK:    true,
+		},
+		{
+			name:      "position before the first token",
+			sourceMap: m,
+			line:      2,
+			column:    1,
+		},
+		{
+			name:      "unknown line",
+			sourceMap: m,
+			line:      3,
+			column:    1,
+		},
+		{
+			name:      "section of an index map",
+			sourceMap: index,
+			line:      11,
+			column:    15,
+			want:      Token{Source: "webpack://app/./src/math.js", Line: 1, Column: 10, Name: "double"},
+			wantOK:    true,
+		},
+		{
+			name:      "line after the offset of a section",
+			sourceMap: index,
+			line:      12,
+			column:    5,
+			want:      Token{Source: "webpack://app/./src/App.tsx", Line: 21, Column: 101},
+			wantOK:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.sourceMap.Lookup(tt.line, tt.column)
+			if ok != tt.wantOK {
+				t.Fatalf("expected %v, got %v", tt.wantOK, ok)
+			}
+			if diff := testutil.Diff(got, tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseInvalidSourceMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		sourceMap string
+	}{
+		{
+			name:      "unsupported version",
+			sourceMap: `{"version": 2, "sources": [], "names": [], "mappings": ""}`,
+		},
+		{
+			name:      "invalid character",
+			sourceMap: `{"version": 3, "sources": ["a.js"], "names": [], "mappings": "AA!A"}`,
+		},
+		{
+			name:      "invalid source index",
+			sourceMap: `{"version": 3, "sources": ["a.js"], "names": [], "mappings": "ACAA"}`,
+		},
+		{
+			name:      "truncated value",
+			sourceMap: `{"version": 3, "sources": ["a.js"], "names": [], "mappings": "AAAg"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.sourceMap)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestSymbolicate(t *testing.T) {
+	ctx := context.Background()
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	key := StoragePath(1, 2, "frontend@1.0.0", "", "https://example.com/static/js/main.js?v=1")
+	if key != "sourcemaps/1/2/frontend@1.0.0/_/static/js/main.js.map" {
+		t.Fatalf("unexpected storage path %q", key)
+	}
+	if err := bucket.WriteAll(ctx, key, []byte(testSourceMap), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	symbolicated := true
+	// a is called by b, itself called by c, the names mapped at the call
+	// sites of b and c being the ones of the functions they call.
+	frames := []frame.Frame{
+		{Function: "a", Path: "https://example.com/static/js/main.js", Line: 2, Column: 5},
+		{Function: "b", Path: "https://example.com/static/js/main.js", Line: 1, Column: 10},
+		{Function: "c", Path: "https://example.com/static/js/main.js", Line: 1, Column: 45, Platform: platform.JavaScript},
+		{Function: "d", Path: "https://example.com/static/js/vendor.js", Line: 1, Column: 10},
+		{Function: "e", Path: "https://example.com/static/js/main.js", Line: 1, Column: 10, Data: frame.Data{JsSymbolicated: &symbolicated}},
+		{Function: "f", Path: "/app/index.js", Line: 1, Column: 10, Platform: platform.Node},
+		{Function: "g", Path: "https://example.com/static/js/main.js", Line: 1, Column: 10},
+	}
+	callers := []int{1, 2, -1, -1, -1, -1, 3}
+
+	s := NewStore(bucket, 1)
+	if err := s.Symbolicate(ctx, 1, 2, "frontend@1.0.0", "", platform.JavaScript, frames, callers); err != nil {
+		t.Fatalf("couldn't symbolicate: %v", err)
+	}
+
+	symbolicatedData := frame.Data{JsSymbolicated: &symbolicated}
+	want := []frame.Frame{
+		{
+			Data:     symbolicatedData,
+			File:     "webpack://app/./src/App.tsx",
+			Function: "double",
+			Path:     "webpack://app/./src/App.tsx",
+			Line:     21,
+			Column:   101,
+		},
+		{
+			Data:     symbolicatedData,
+			File:     "webpack://app/./src/math.js",
+			Function: "render",
+			Path:     "webpack://app/./src/math.js",
+			Line:     1,
+			Column:   10,
+		},
+		{
+			Data:     symbolicatedData,
+			File:     "webpack://app/./src/App.tsx",
+			Function: "c",
+			Path:     "webpack://app/./src/App.tsx",
+			Line:     11,
+			Column:   3,
+			Platform: platform.JavaScript,
+		},
+		{Function: "d", Path: "https://example.com/static/js/vendor.js", Line: 1, Column: 10},
+		{Function: "e", Path: "https://example.com/static/js/main.js", Line: 1, Column: 10, Data: symbolicatedData},
+		{Function: "f", Path: "/app/index.js", Line: 1, Column: 10, Platform: platform.Node},
+		{
+			Data:     symbolicatedData,
+			File:     "webpack://app/./src/math.js",
+			Function: "g",
+			Path:     "webpack://app/./src/math.js",
+			Line:     1,
+			Column:   10,
+		},
+	}
+	if diff := testutil.Diff(frames, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
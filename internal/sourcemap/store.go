@@ -0,0 +1,139 @@
+package sourcemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"gocloud.dev/blob"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/storageutil"
+)
+
+type (
+	// Store loads source maps from a bucket and keeps the most recently
+	// loaded ones in memory.
+	Store struct {
+		sourceMaps *storageutil.ObjectCache[*SourceMap]
+	}
+)
+
+// noDist replaces the dist in the path of source maps of releases without
+// a dist.
+const noDist = "_"
+
+// StoragePath returns the path of the source map of a minified file for
+// a release and a dist, if any.
+func StoragePath(organizationID, projectID uint64, release, dist, file string) string {
+	if dist == "" {
+		dist = noDist
+	}
+	return fmt.Sprintf(
+		"sourcemaps/%d/%d/%s/%s/%s.map",
+		organizationID,
+		projectID,
+		url.PathEscape(release),
+		url.PathEscape(dist),
+		fileKey(file),
+	)
+}
+
+// fileKey returns the path of a minified file without its scheme, host and
+// query, "static/js/main.js" for "https://example.com/static/js/main.js?v=1".
+func fileKey(file string) string {
+	if u, err := url.Parse(file); err == nil {
+		file = u.Path
+	}
+	return strings.TrimPrefix(path.Clean("/"+file), "/")
+}
+
+// NewStore returns a store keeping up to size source maps in memory.
+func NewStore(bucket *blob.Bucket, size int) *Store {
+	return &Store{
+		sourceMaps: storageutil.NewObjectCache(
+			bucket,
+			storageutil.ObjectCacheOptions{Size: size},
+			Parse,
+		),
+	}
+}
+
+// SourceMap returns the source map of a minified file or
+// storageutil.ErrObjectNotFound if it was not uploaded.
+func (s *Store) SourceMap(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	release, dist, file string,
+) (*SourceMap, error) {
+	return s.sourceMaps.Get(ctx, StoragePath(organizationID, projectID, release, dist, file))
+}
+
+// Symbolicate applies the source maps of a release to the JavaScript frames
+// not symbolicated yet, frames without a platform having the default one.
+//
+// The name mapped at the position of a frame is the one of the function it
+// calls, so a frame gets its original function name from the frame calling
+// it, callers holding the ID of a frame calling each frame or -1 if there's
+// none. The function is left as is if the caller can't be mapped.
+func (s *Store) Symbolicate(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	release, dist string,
+	defaultPlatform platform.Platform,
+	frames []frame.Frame,
+	callers []int,
+) error {
+	var errs []error
+	// sourceMaps holds the source maps by file, nil if missing
+	sourceMaps := make(map[string]*SourceMap)
+	tokens := make([]*Token, len(frames))
+	for i, f := range frames {
+		p := f.Platform
+		if p == "" {
+			p = defaultPlatform
+		}
+		if p != platform.JavaScript || f.Path == "" || f.Line == 0 ||
+			(f.Data.JsSymbolicated != nil && *f.Data.JsSymbolicated) {
+			continue
+		}
+		m, exists := sourceMaps[f.Path]
+		if !exists {
+			var err error
+			m, err = s.SourceMap(ctx, organizationID, projectID, release, dist, f.Path)
+			sourceMaps[f.Path] = m
+			if err != nil && !errors.Is(err, storageutil.ErrObjectNotFound) {
+				errs = append(errs, err)
+			}
+		}
+		if m == nil {
+			continue
+		}
+		if t, ok := m.Lookup(f.Line, f.Column); ok {
+			tokens[i] = &t
+		}
+	}
+	for i, t := range tokens {
+		if t == nil {
+			continue
+		}
+		f := frames[i]
+		if i < len(callers) && callers[i] >= 0 && callers[i] < len(tokens) {
+			if ct := tokens[callers[i]]; ct != nil && ct.Name != "" {
+				f.Function = ct.Name
+			}
+		}
+		f.Path = t.Source
+		f.File = t.Source
+		f.Line = t.Line
+		f.Column = t.Column
+		symbolicated := true
+		f.Data.JsSymbolicated = &symbolicated
+		frames[i] = f
+	}
+	return errors.Join(errs...)
+}
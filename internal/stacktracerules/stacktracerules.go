@@ -0,0 +1,319 @@
+package stacktracerules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+)
+
+type (
+	// Rules are stack trace rules, in the spirit of Sentry's ones, changing
+	// if frames are application frames and if they're grouped. Rules are
+	// applied in order, the last matching one winning.
+	Rules []Rule
+
+	// Rule applies actions to the frames matching all its matchers, like
+	// `stack.module:com.mycompany.* +app`.
+	Rule struct {
+		matchers []matcher
+		// inApp and group are nil if the rule doesn't change them.
+		inApp *bool
+		group *bool
+	}
+
+	// Result holds the changes made by rules to a frame, nil if unchanged.
+	Result struct {
+		InApp *bool
+		Group *bool
+	}
+
+	matcher struct {
+		key     string
+		negated bool
+		// pattern is nil for the family and app matchers, comparing values.
+		pattern *regexp.Regexp
+		value   string
+	}
+)
+
+const (
+	familyNative     = "native"
+	familyJavaScript = "javascript"
+	familyOther      = "other"
+	familyAll        = "all"
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid stack trace rule")
+
+	// matcherKeys maps the keys of matchers to their canonical name.
+	matcherKeys = map[string]string{
+		"app":            "app",
+		"family":         "family",
+		"function":       "function",
+		"module":         "module",
+		"package":        "package",
+		"path":           "path",
+		"stack.abs_path": "path",
+		"stack.function": "function",
+		"stack.module":   "module",
+		"stack.package":  "package",
+	}
+
+	nativePlatforms = map[platform.Platform]struct{}{
+		platform.Cocoa: {},
+		platform.Rust:  {},
+	}
+)
+
+// Parse parses rules, one per line, ignoring empty lines and comments
+// starting with #. Invalid lines are skipped: the valid rules are returned
+// along with an error for each of them.
+func Parse(text string) (Rules, error) {
+	var rules Rules
+	var errs []error
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRule(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", i+1, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errors.Join(errs...)
+}
+
+func parseRule(line string) (Rule, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return Rule{}, err
+	}
+	var r Rule
+	for _, token := range tokens {
+		switch {
+		case token == "+app" || token == "-app":
+			inApp := token[0] == '+'
+			r.inApp = &inApp
+		case token == "+group" || token == "-group":
+			group := token[0] == '+'
+			r.group = &group
+		case strings.HasPrefix(token, "+") || strings.HasPrefix(token, "-") ||
+			strings.HasPrefix(token, "^") || strings.HasPrefix(token, "v+") ||
+			strings.HasPrefix(token, "v-"):
+			return Rule{}, fmt.Errorf("%w: unsupported action %q", ErrInvalidRule, token)
+		default:
+			if r.inApp != nil || r.group != nil {
+				return Rule{}, fmt.Errorf("%w: matcher %q after an action", ErrInvalidRule, token)
+			}
+			m, err := parseMatcher(token)
+			if err != nil {
+				return Rule{}, err
+			}
+			r.matchers = append(r.matchers, m)
+		}
+	}
+	if len(r.matchers) == 0 {
+		return Rule{}, fmt.Errorf("%w: no matcher", ErrInvalidRule)
+	}
+	if r.inApp == nil && r.group == nil {
+		return Rule{}, fmt.Errorf("%w: no action", ErrInvalidRule)
+	}
+	return r, nil
+}
+
+// tokenize splits a rule on spaces, except in quoted values.
+func tokenize(line string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	quoted := false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case (c == ' ' || c == '\t') && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(c)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidRule)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+func parseMatcher(token string) (matcher, error) {
+	var m matcher
+	if strings.HasPrefix(token, "!") {
+		m.negated = true
+		token = token[1:]
+	}
+	rawKey, value, found := strings.Cut(token, ":")
+	if !found || value == "" {
+		return matcher{}, fmt.Errorf("%w: invalid matcher %q", ErrInvalidRule, token)
+	}
+	key, exists := matcherKeys[rawKey]
+	if !exists {
+		return matcher{}, fmt.Errorf("%w: unknown matcher %q", ErrInvalidRule, rawKey)
+	}
+	m.key = key
+	switch key {
+	case "app":
+		switch value {
+		case "yes", "1", "true":
+			m.value = "yes"
+		case "no", "0", "false":
+			m.value = "no"
+		default:
+			return matcher{}, fmt.Errorf("%w: invalid app value %q", ErrInvalidRule, value)
+		}
+	case "family":
+		for _, family := range strings.Split(value, ",") {
+			switch family {
+			case familyNative, familyJavaScript, familyOther, familyAll:
+			default:
+				return matcher{}, fmt.Errorf("%w: unknown family %q", ErrInvalidRule, family)
+			}
+		}
+		m.value = value
+	case "package", "path":
+		m.pattern = globPattern(normalizePath(value))
+	default:
+		m.pattern = globPattern(value)
+	}
+	return m, nil
+}
+
+// globPattern compiles a glob where * matches anything but a slash,
+// ** matches anything and ? matches a single character.
+func globPattern(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Apply applies the rules to a frame.
+func (rs Rules) Apply(f *frame.Frame) {
+	r := rs.Result(*f)
+	if r.InApp != nil {
+		f.InApp = r.InApp
+	}
+	if r.Group != nil {
+		f.Data.ExcludeFromGroup = !*r.Group
+	}
+}
+
+// Result returns the changes the rules make to a frame.
+func (rs Rules) Result(f frame.Frame) Result {
+	var r Result
+	for _, rule := range rs {
+		inApp := f.IsInApp()
+		if r.InApp != nil {
+			inApp = *r.InApp
+		}
+		if !rule.matches(f, inApp) {
+			continue
+		}
+		if rule.inApp != nil {
+			r.InApp = rule.inApp
+		}
+		if rule.group != nil {
+			r.Group = rule.group
+		}
+	}
+	return r
+}
+
+func (r Rule) matches(f frame.Frame, inApp bool) bool {
+	for _, m := range r.matchers {
+		if m.matches(f, inApp) == m.negated {
+			return false
+		}
+	}
+	return true
+}
+
+func (m matcher) matches(f frame.Frame, inApp bool) bool {
+	switch m.key {
+	case "app":
+		return inApp == (m.value == "yes")
+	case "family":
+		family := frameFamily(f)
+		for _, value := range strings.Split(m.value, ",") {
+			if value == familyAll || value == family {
+				return true
+			}
+		}
+		return false
+	case "function":
+		return m.pattern.MatchString(f.Function)
+	case "module":
+		return m.pattern.MatchString(f.Module)
+	case "package":
+		return m.pattern.MatchString(normalizePath(f.Package))
+	case "path":
+		return (f.Path != "" && m.pattern.MatchString(normalizePath(f.Path))) ||
+			(f.File != "" && m.pattern.MatchString(normalizePath(f.File)))
+	}
+	return false
+}
+
+func frameFamily(f frame.Frame) string {
+	if _, exists := nativePlatforms[f.Platform]; exists {
+		return familyNative
+	}
+	switch f.Platform {
+	case platform.JavaScript, platform.Node:
+		return familyJavaScript
+	}
+	return familyOther
+}
+
+func normalizePath(p string) string {
+	return strings.ToLower(strings.ReplaceAll(p, "\\", "/"))
+}
+
+// StoragePath returns the path of the stack trace rules of a project.
+func StoragePath(organizationID, projectID uint64) string {
+	return fmt.Sprintf("stacktrace-rules/%d/%d", organizationID, projectID)
+}
+
+// Read reads and parses rules.
+func Read(r io.Reader) (Rules, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
@@ -0,0 +1,198 @@
+package stacktracerules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/platform"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{
+			name:  "no action",
+			rules: "stack.module:com.mycompany.*",
+		},
+		{
+			name:  "no matcher",
+			rules: "+app",
+		},
+		{
+			name:  "unknown matcher",
+			rules: "stack.filename:main.js +app",
+		},
+		{
+			name:  "unknown family",
+			rules: "family:python -app",
+		},
+		{
+			name:  "unsupported action",
+			rules: "stack.function:main ^-group",
+		},
+		{
+			name:  "matcher after an action",
+			rules: "+app stack.function:main",
+		},
+		{
+			name:  "unterminated quote",
+			rules: `stack.function:"main -app`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse("# comment\n\n" + test.rules)
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Fatalf("expected an invalid rule error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSkipsInvalidLines(t *testing.T) {
+	rules, err := Parse("stack.function:main +app\n+app\nstack.function:malloc -app\nfamily:python -app")
+	if !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("expected an invalid rule error, got %v", err)
+	}
+	for _, line := range []string{"line 2", "line 4"} {
+		if !strings.Contains(err.Error(), line) {
+			t.Fatalf("expected an error for %s, got %v", line, err)
+		}
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	no := false
+	if diff := testutil.Diff(rules.Result(frame.Frame{Function: "malloc"}), Result{InApp: &no}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestResult(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name  string
+		rules string
+		frame frame.Frame
+		want  Result
+	}{
+		{
+			name:  "module matching",
+			rules: "stack.module:com.mycompany.* +app",
+			frame: frame.Frame{
+				Module:   "com.mycompany.Renderer",
+				Platform: platform.Java,
+			},
+			want: Result{InApp: &yes},
+		},
+		{
+			name:  "star not matching a slash",
+			rules: "path:src/*.js +app",
+			frame: frame.Frame{
+				Path:     "src/lib/main.js",
+				Platform: platform.JavaScript,
+			},
+			want: Result{},
+		},
+		{
+			name:  "native vendored path",
+			rules: "family:native path:**/vendor/** -app",
+			frame: frame.Frame{
+				InApp:    &yes,
+				Path:     `C:\App\Vendor\lib\alloc.c`,
+				Platform: platform.Cocoa,
+			},
+			want: Result{InApp: &no},
+		},
+		{
+			name:  "family not matching",
+			rules: "family:native path:**/vendor/** -app",
+			frame: frame.Frame{
+				InApp:    &yes,
+				Path:     "/app/vendor/lib.js",
+				Platform: platform.JavaScript,
+			},
+			want: Result{},
+		},
+		{
+			name:  "negated matcher",
+			rules: "!stack.function:main* -group",
+			frame: frame.Frame{
+				Function: "render",
+				Platform: platform.Python,
+			},
+			want: Result{Group: &no},
+		},
+		{
+			name:  "quoted value",
+			rules: `stack.function:"operator new" -group +app`,
+			frame: frame.Frame{
+				Function: "operator new",
+				Platform: platform.Cocoa,
+			},
+			want: Result{InApp: &yes, Group: &no},
+		},
+		{
+			name: "last rule winning",
+			rules: `stack.module:com.mycompany.* +app
+stack.module:com.mycompany.generated.* -app`,
+			frame: frame.Frame{
+				Module:   "com.mycompany.generated.Binding",
+				Platform: platform.Java,
+			},
+			want: Result{InApp: &no},
+		},
+		{
+			name: "app matcher seeing previous rules",
+			rules: `stack.package:/usr/lib/** +app
+app:yes -group`,
+			frame: frame.Frame{
+				Package:  "/usr/lib/libc.so",
+				Platform: platform.Rust,
+			},
+			want: Result{InApp: &yes, Group: &no},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := Parse(test.rules)
+			if err != nil {
+				t.Fatalf("couldn't parse rules: %v", err)
+			}
+			if diff := testutil.Diff(rules.Result(test.frame), test.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules, err := Parse("stack.function:malloc -app -group")
+	if err != nil {
+		t.Fatalf("couldn't parse rules: %v", err)
+	}
+	yes, no := true, false
+	f := frame.Frame{
+		Function: "malloc",
+		InApp:    &yes,
+		Platform: platform.Cocoa,
+	}
+	rules.Apply(&f)
+	want := frame.Frame{
+		Data:     frame.Data{ExcludeFromGroup: true},
+		Function: "malloc",
+		InApp:    &no,
+		Platform: platform.Cocoa,
+	}
+	if diff := testutil.Diff(f, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
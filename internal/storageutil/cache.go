@@ -0,0 +1,166 @@
+package storageutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultObjectCacheTTL         = time.Hour
+	defaultObjectCacheMissTTL     = time.Minute
+	defaultObjectCacheReadTimeout = 30 * time.Second
+)
+
+type (
+	// ObjectCacheOptions configures an ObjectCache. Durations left to 0 use
+	// the defaults: an hour for objects, a minute for misses and 30 seconds
+	// to read an object.
+	ObjectCacheOptions struct {
+		// Size is the number of objects kept in memory.
+		Size int
+		// TTL is how long a parsed object is kept.
+		TTL time.Duration
+		// MissTTL is how long a missing or unparsable object is remembered,
+		// so an upload fixing it is picked up quickly.
+		MissTTL time.Duration
+		// ReadTimeout bounds the time spent reading and parsing an object.
+		ReadTimeout time.Duration
+	}
+
+	// ObjectCache reads and parses objects from a bucket and keeps them in
+	// memory until they expire. Missing objects and parse errors are kept
+	// for a shorter time. When full, the oldest entry is evicted first.
+	// Concurrent misses on the same key only read the object once.
+	ObjectCache[T any] struct {
+		bucket  *blob.Bucket
+		options ObjectCacheOptions
+		parse   func(io.Reader) (T, error)
+		now     func() time.Time
+		reads   singleflight.Group
+
+		mu      sync.Mutex
+		entries map[string]cacheEntry[T]
+		// keys holds the keys of the entries in the order they were added.
+		keys []string
+	}
+
+	cacheEntry[T any] struct {
+		object    T
+		err       error
+		expiresAt time.Time
+	}
+)
+
+// NewObjectCache returns a cache of objects parsed with parse.
+func NewObjectCache[T any](
+	bucket *blob.Bucket,
+	options ObjectCacheOptions,
+	parse func(io.Reader) (T, error),
+) *ObjectCache[T] {
+	if options.TTL <= 0 {
+		options.TTL = defaultObjectCacheTTL
+	}
+	if options.MissTTL <= 0 {
+		options.MissTTL = defaultObjectCacheMissTTL
+	}
+	if options.ReadTimeout <= 0 {
+		options.ReadTimeout = defaultObjectCacheReadTimeout
+	}
+	return &ObjectCache[T]{
+		bucket:  bucket,
+		options: options,
+		parse:   parse,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry[T], options.Size),
+	}
+}
+
+// Get returns the parsed object or ErrObjectNotFound if it doesn't exist.
+// On parse errors, it returns whatever parse returned with the error.
+func (c *ObjectCache[T]) Get(ctx context.Context, key string) (T, error) {
+	c.mu.Lock()
+	e, exists := c.entries[key]
+	c.mu.Unlock()
+	if exists && c.now().Before(e.expiresAt) {
+		return e.object, e.err
+	}
+
+	v, err, _ := c.reads.Do(key, func() (interface{}, error) {
+		e, cache := c.read(ctx, key)
+		if cache {
+			c.add(key, e)
+		}
+		return e, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	e = v.(cacheEntry[T])
+	return e.object, e.err
+}
+
+// read reads and parses an object, reporting whether the result can be
+// cached. Errors from the bucket other than a missing object aren't.
+func (c *ObjectCache[T]) read(ctx context.Context, key string) (cacheEntry[T], bool) {
+	// The read is shared with other callers, so it shouldn't be canceled
+	// with the context of the first one.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.options.ReadTimeout)
+	defer cancel()
+	r, err := c.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			var zero T
+			return c.miss(zero, fmt.Errorf("%w: %s", ErrObjectNotFound, key)), true
+		}
+		return cacheEntry[T]{err: err}, false
+	}
+	defer r.Close()
+	o, err := c.parse(r)
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			return cacheEntry[T]{object: o, err: err}, false
+		}
+		return c.miss(o, err), true
+	}
+	return cacheEntry[T]{
+		object:    o,
+		expiresAt: c.now().Add(c.options.TTL),
+	}, true
+}
+
+// miss returns an entry for an object that is missing or couldn't be fully
+// parsed, keeping whatever parse returned along with the error.
+func (c *ObjectCache[T]) miss(o T, err error) cacheEntry[T] {
+	return cacheEntry[T]{
+		object:    o,
+		err:       err,
+		expiresAt: c.now().Add(c.options.MissTTL),
+	}
+}
+
+func (c *ObjectCache[T]) add(key string, e cacheEntry[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.options.Size <= 0 {
+		return
+	}
+	if _, exists := c.entries[key]; exists {
+		c.entries[key] = e
+		return
+	}
+	if len(c.keys) >= c.options.Size {
+		delete(c.entries, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.entries[key] = e
+	c.keys = append(c.keys, key)
+}
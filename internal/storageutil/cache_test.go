@@ -0,0 +1,168 @@
+package storageutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestObjectCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	errInvalid := errors.New("invalid object")
+	var reads atomic.Int32
+	parse := func(r io.Reader) (string, error) {
+		reads.Add(1)
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		if string(b) == "invalid" {
+			return "", errInvalid
+		}
+		return string(b), nil
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		elapsed  time.Duration
+		wantErr  error
+		wantRead int32
+	}{
+		{
+			name:     "found object is kept for the ttl",
+			content:  "object",
+			elapsed:  30 * time.Minute,
+			wantRead: 1,
+		},
+		{
+			name:     "found object is read again after the ttl",
+			content:  "object",
+			elapsed:  2 * time.Hour,
+			wantRead: 2,
+		},
+		{
+			name:     "missing object is kept for the miss ttl",
+			elapsed:  30 * time.Second,
+			wantErr:  ErrObjectNotFound,
+			wantRead: 0,
+		},
+		{
+			name:     "missing object is read again after the miss ttl",
+			elapsed:  2 * time.Minute,
+			wantErr:  ErrObjectNotFound,
+			wantRead: 0,
+		},
+		{
+			name:     "parse error is kept for the miss ttl",
+			content:  "invalid",
+			elapsed:  30 * time.Second,
+			wantErr:  errInvalid,
+			wantRead: 1,
+		},
+		{
+			name:     "parse error is read again after the miss ttl",
+			content:  "invalid",
+			elapsed:  2 * time.Minute,
+			wantErr:  errInvalid,
+			wantRead: 2,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "cache/" + string(rune('a'+i))
+			if tt.content != "" {
+				if err := fileBlobBucket.WriteAll(ctx, key, []byte(tt.content), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reads.Store(0)
+			now := time.Now()
+			c := NewObjectCache(fileBlobBucket, ObjectCacheOptions{Size: 1}, parse)
+			c.now = func() time.Time { return now }
+
+			for _, elapsed := range []time.Duration{0, tt.elapsed} {
+				now = now.Add(elapsed)
+				o, err := c.Get(ctx, key)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				if err == nil && o != tt.content {
+					t.Fatalf("expected %q, got %q", tt.content, o)
+				}
+			}
+			if got := reads.Load(); got != tt.wantRead {
+				t.Fatalf("expected %d parses, got %d", tt.wantRead, got)
+			}
+		})
+	}
+}
+
+func TestObjectCacheEvictsOldestEntry(t *testing.T) {
+	ctx := context.Background()
+	var reads atomic.Int32
+	parse := func(r io.Reader) (string, error) {
+		reads.Add(1)
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+	for _, key := range []string{"eviction/a", "eviction/b", "eviction/c"} {
+		if err := fileBlobBucket.WriteAll(ctx, key, []byte(key), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewObjectCache(fileBlobBucket, ObjectCacheOptions{Size: 2}, parse)
+
+	for _, key := range []string{"eviction/a", "eviction/b", "eviction/a", "eviction/c", "eviction/b", "eviction/a"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// c evicts a, the first one added, even though it was read since, so a
+	// is read a second time at the end.
+	if got := reads.Load(); got != 4 {
+		t.Fatalf("expected 4 parses, got %d", got)
+	}
+}
+
+func TestObjectCacheSharesConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+	key := "singleflight/object"
+	if err := fileBlobBucket.WriteAll(ctx, key, []byte("object"), nil); err != nil {
+		t.Fatal(err)
+	}
+	var reads atomic.Int32
+	release := make(chan struct{})
+	parse := func(r io.Reader) (string, error) {
+		reads.Add(1)
+		<-release
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+	c := NewObjectCache(fileBlobBucket, ObjectCacheOptions{Size: 1}, parse)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o, err := c.Get(ctx, key)
+			if err != nil || o != "object" {
+				t.Errorf("expected object, got %q, %v", o, err)
+			}
+		}()
+	}
+	// Give the goroutines time to wait on the first read.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := reads.Load(); got != 1 {
+		t.Fatalf("expected 1 parse, got %d", got)
+	}
+}
@@ -0,0 +1,422 @@
+package symbolication
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+type (
+	// DebugFile holds the functions and lines of an ELF file, from its DWARF
+	// debug information or, if it has none, from its symbol table.
+	DebugFile struct {
+		// baseAddress is the virtual address the file is expected to be
+		// loaded at.
+		baseAddress uint64
+		functions   []functionRange
+		lines       []lineRow
+		symbols     []symbol
+	}
+
+	// Frame is a symbolicated frame.
+	Frame struct {
+		Function string
+		Path     string
+		Line     uint32
+		// SymbolAddress is the address of the function, 0 for inlined
+		// functions.
+		SymbolAddress uint64
+	}
+
+	function struct {
+		name   string
+		ranges [][2]uint64
+		// callPath and callLine are the location of the call of an inlined
+		// function in its caller.
+		callPath string
+		callLine uint32
+		inlined  []*function
+	}
+
+	functionRange struct {
+		low      uint64
+		high     uint64
+		function *function
+	}
+
+	lineRow struct {
+		address     uint64
+		path        string
+		line        uint32
+		endSequence bool
+	}
+
+	symbol struct {
+		name    string
+		address uint64
+		size    uint64
+	}
+
+	// entry is a debug information entry being read, with its children.
+	entry struct {
+		scope    string
+		function *function
+	}
+)
+
+const maxNestedFunctions = 8
+
+var ErrNoDebugInformation = errors.New("no debug information")
+
+// ParseDebugFile reads an ELF file, either an executable or a library with
+// its DWARF debug information or a separate debug file.
+func ParseDebugFile(r io.ReaderAt) (*DebugFile, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := &DebugFile{baseAddress: baseAddress(f)}
+	if data, err := f.DWARF(); err == nil {
+		d.readDWARF(data)
+	}
+	d.readSymbols(f)
+	if len(d.functions) == 0 && len(d.symbols) == 0 {
+		return nil, ErrNoDebugInformation
+	}
+	return d, nil
+}
+
+// baseAddress returns the lowest virtual address of the loadable segments.
+func baseAddress(f *elf.File) uint64 {
+	var base uint64
+	found := false
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		if !found || p.Vaddr < base {
+			base = p.Vaddr
+			found = true
+		}
+	}
+	return base
+}
+
+// readDWARF reads the functions and lines of the compile units, skipping
+// the rest of a unit with an invalid entry.
+func (d *DebugFile) readDWARF(data *dwarf.Data) {
+	// names holds the qualified names of functions, by offset, to resolve
+	// the origin of inlined functions and the specification of definitions.
+	names := make(map[dwarf.Offset]string)
+	origins := make(map[*function]dwarf.Offset)
+	var files []*dwarf.LineFile
+	var stack []entry
+	// unit is the offset of the compile unit being read
+	var unit dwarf.Offset
+	inUnit := false
+
+	r := data.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			if !inUnit || !skipUnit(r, unit) {
+				break
+			}
+			inUnit = false
+			stack = nil
+			continue
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == 0 {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		var parent entry
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		current := entry{scope: parent.scope, function: parent.function}
+		name, _ := e.Val(dwarf.AttrName).(string)
+
+		switch e.Tag {
+		case dwarf.TagCompileUnit, dwarf.TagPartialUnit:
+			unit, inUnit = e.Offset, true
+			current = entry{}
+			files = nil
+			if lr, err := data.LineReader(e); err == nil && lr != nil {
+				files = lr.Files()
+				d.readLines(lr)
+			}
+		case dwarf.TagNamespace, dwarf.TagStructType, dwarf.TagClassType, dwarf.TagUnionType:
+			if name != "" {
+				current.scope = qualifiedName(parent.scope, name)
+			}
+		case dwarf.TagSubprogram, dwarf.TagInlinedSubroutine:
+			if name != "" {
+				names[e.Offset] = qualifiedName(parent.scope, name)
+			}
+			ranges, err := data.Ranges(e)
+			if err != nil || len(ranges) == 0 {
+				break
+			}
+			fn := &function{name: names[e.Offset], ranges: ranges}
+			for _, attr := range []dwarf.Attr{dwarf.AttrAbstractOrigin, dwarf.AttrSpecification} {
+				if offset, ok := e.Val(attr).(dwarf.Offset); ok {
+					origins[fn] = offset
+					break
+				}
+			}
+			if e.Tag == dwarf.TagInlinedSubroutine && parent.function != nil {
+				if i, ok := e.Val(dwarf.AttrCallFile).(int64); ok && i >= 0 && int(i) < len(files) && files[i] != nil {
+					fn.callPath = files[i].Name
+				}
+				if line, ok := e.Val(dwarf.AttrCallLine).(int64); ok {
+					fn.callLine = uint32(line)
+				}
+				parent.function.inlined = append(parent.function.inlined, fn)
+			} else if e.Tag == dwarf.TagSubprogram {
+				for _, rg := range ranges {
+					d.functions = append(d.functions, functionRange{low: rg[0], high: rg[1], function: fn})
+				}
+			}
+			current.function = fn
+		}
+		if e.Children {
+			stack = append(stack, current)
+		}
+	}
+
+	// origins can be in another compile unit, so they're resolved once
+	// all units were read, following declarations to their specification
+	for fn, offset := range origins {
+		for i := 0; fn.name == "" && i < 4; i++ {
+			if name, exists := names[offset]; exists {
+				fn.name = name
+				break
+			}
+			r.Seek(offset)
+			e, err := r.Next()
+			if err != nil || e == nil {
+				break
+			}
+			next, ok := e.Val(dwarf.AttrSpecification).(dwarf.Offset)
+			if !ok {
+				next, ok = e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+			}
+			if !ok {
+				break
+			}
+			offset = next
+		}
+	}
+
+	sort.Slice(d.functions, func(i, j int) bool {
+		return d.functions[i].low < d.functions[j].low
+	})
+	// a sequence can start where another one ends, the end being sorted first
+	sort.SliceStable(d.lines, func(i, j int) bool {
+		if d.lines[i].address == d.lines[j].address {
+			return d.lines[i].endSequence && !d.lines[j].endSequence
+		}
+		return d.lines[i].address < d.lines[j].address
+	})
+}
+
+// skipUnit moves the reader to the compile unit following the one at an
+// offset, reporting whether the unit could be read again.
+func skipUnit(r *dwarf.Reader, offset dwarf.Offset) bool {
+	r.Seek(offset)
+	if e, err := r.Next(); err != nil || e == nil {
+		return false
+	}
+	r.SkipChildren()
+	return true
+}
+
+func (d *DebugFile) readLines(lr *dwarf.LineReader) {
+	var le dwarf.LineEntry
+	for {
+		if err := lr.Next(&le); err != nil {
+			return
+		}
+		row := lineRow{
+			address:     le.Address,
+			line:        uint32(le.Line),
+			endSequence: le.EndSequence,
+		}
+		if le.File != nil {
+			row.path = le.File.Name
+		}
+		d.lines = append(d.lines, row)
+	}
+}
+
+func (d *DebugFile) readSymbols(f *elf.File) {
+	symbols, _ := f.Symbols()
+	dynamicSymbols, _ := f.DynamicSymbols()
+	for _, s := range append(symbols, dynamicSymbols...) {
+		if elf.ST_TYPE(s.Info) != elf.STT_FUNC || s.Value == 0 || s.Section == elf.SHN_UNDEF {
+			continue
+		}
+		d.symbols = append(d.symbols, symbol{
+			name:    demangle(s.Name),
+			address: s.Value,
+			size:    s.Size,
+		})
+	}
+	sort.Slice(d.symbols, func(i, j int) bool {
+		return d.symbols[i].address < d.symbols[j].address
+	})
+}
+
+func qualifiedName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "::" + name
+}
+
+// Lookup returns the frames at an address relative to the base address of
+// the file, the innermost one first when functions were inlined.
+func (d *DebugFile) Lookup(address uint64) []Frame {
+	address += d.baseAddress
+	if fn := d.function(address); fn != nil {
+		chain := []*function{fn}
+		for {
+			inlined := fn.inlinedAt(address)
+			if inlined == nil {
+				break
+			}
+			chain = append(chain, inlined)
+			fn = inlined
+		}
+		path, line := d.line(address)
+		frames := make([]Frame, 0, len(chain))
+		for i := len(chain) - 1; i >= 0; i-- {
+			frames = append(frames, Frame{
+				Function: chain[i].name,
+				Path:     path,
+				Line:     line,
+			})
+			path, line = chain[i].callPath, chain[i].callLine
+		}
+		frames[len(frames)-1].SymbolAddress = chain[0].ranges[0][0] - d.baseAddress
+		return frames
+	}
+	if s, ok := d.symbol(address); ok {
+		return []Frame{{Function: s.name, SymbolAddress: s.address - d.baseAddress}}
+	}
+	return nil
+}
+
+func (d *DebugFile) function(address uint64) *function {
+	i := sort.Search(len(d.functions), func(i int) bool {
+		return d.functions[i].low > address
+	})
+	// ranges of functions don't overlap, except for the few functions nested
+	// in another one, so only the closest ones are checked
+	for j := i - 1; j >= 0 && j >= i-maxNestedFunctions; j-- {
+		if address < d.functions[j].high {
+			return d.functions[j].function
+		}
+	}
+	return nil
+}
+
+func (fn *function) inlinedAt(address uint64) *function {
+	for _, inlined := range fn.inlined {
+		for _, rg := range inlined.ranges {
+			if rg[0] <= address && address < rg[1] {
+				return inlined
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DebugFile) line(address uint64) (string, uint32) {
+	i := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i].address > address
+	})
+	if i == 0 || d.lines[i-1].endSequence {
+		return "", 0
+	}
+	row := d.lines[i-1]
+	return row.path, row.line
+}
+
+func (d *DebugFile) symbol(address uint64) (symbol, bool) {
+	i := sort.Search(len(d.symbols), func(i int) bool {
+		return d.symbols[i].address > address
+	})
+	if i == 0 {
+		return symbol{}, false
+	}
+	s := d.symbols[i-1]
+	if s.size > 0 && address >= s.address+s.size {
+		return symbol{}, false
+	}
+	return s, true
+}
+
+// demangle demangles the legacy Rust and C++ mangled names of the form
+// _ZN{length}{identifier}...E, dropping the hash Rust adds at the end.
+func demangle(name string) string {
+	if !strings.HasPrefix(name, "_ZN") {
+		return name
+	}
+	s := name[3:]
+	var parts []string
+	for len(s) > 0 && s[0] != 'E' {
+		n := 0
+		for len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+			n = n*10 + int(s[0]-'0')
+			s = s[1:]
+		}
+		if n == 0 || n > len(s) {
+			return name
+		}
+		part := s[:n]
+		// identifiers starting with an escape are prefixed with an underscore
+		if strings.HasPrefix(part, "_$") {
+			part = part[1:]
+		}
+		parts = append(parts, part)
+		s = s[n:]
+	}
+	if len(parts) == 0 || len(s) == 0 {
+		return name
+	}
+	if last := parts[len(parts)-1]; len(last) == 17 && last[0] == 'h' {
+		parts = parts[:len(parts)-1]
+	}
+	demangled := strings.Join(parts, "::")
+	return rustEscapes.Replace(demangled)
+}
+
+var rustEscapes = strings.NewReplacer(
+	"$LT$", "<",
+	"$GT$", ">",
+	"$RF$", "&",
+	"$BP$", "*",
+	"$C$", ",",
+	"$SP$", "@",
+	"$u20$", " ",
+	"$u27$", "'",
+	"$u5b$", "[",
+	"$u5d$", "]",
+	"$u7b$", "{",
+	"$u7d$", "}",
+	"$u7e$", "~",
+	"..", "::",
+)
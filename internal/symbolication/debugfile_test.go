@@ -0,0 +1,107 @@
+package symbolication
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestLookup(t *testing.T) {
+	f, err := os.Open("testdata/libinline.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := ParseDebugFile(f)
+	if err != nil {
+		t.Fatalf("couldn't parse debug file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		address uint64
+		want    []Frame
+	}{
+		{
+			name:    "inlined function",
+			address: 0x1118,
+			want: []Frame{
+				{Function: "square", Path: "inline.c", Line: 5},
+				{Function: "compute", Path: "inline.c", Line: 9, SymbolAddress: 0x1110},
+			},
+		},
+		{
+			name:    "function",
+			address: 0x111f,
+			want: []Frame{
+				{Function: "compute", Path: "inline.c", Line: 10, SymbolAddress: 0x1110},
+			},
+		},
+		{
+			name:    "unknown address",
+			address: 0x10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := d.Lookup(tt.address)
+			// paths are absolute, depending on where the file was built
+			for i := range frames {
+				frames[i].Path = path.Base(frames[i].Path)
+			}
+			if diff := testutil.Diff(frames, tt.want); diff != "" {
+				t.Fatalf("Result mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDebugFileWithInvalidEntry(t *testing.T) {
+	b, err := os.ReadFile("testdata/libinline.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the variable of compute, after the functions run and compute
+	r := data.Reader()
+	var variable *dwarf.Entry
+	for variable == nil {
+		e, err := r.Next()
+		if err != nil || e == nil {
+			t.Fatalf("couldn't find a variable: %v", err)
+		}
+		if e.Tag == dwarf.TagVariable {
+			variable = e
+		}
+	}
+	// an abbreviation code not defined by the unit
+	b[f.Section(".debug_info").Offset+uint64(variable.Offset)] = 0x7f
+	f.Close()
+
+	d, err := ParseDebugFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("couldn't parse debug file: %v", err)
+	}
+
+	frames := d.Lookup(0x1130)
+	for i := range frames {
+		frames[i].Path = path.Base(frames[i].Path)
+	}
+	want := []Frame{{Function: "run", Path: "inline.c", Line: 13, SymbolAddress: 0x1130}}
+	if diff := testutil.Diff(frames, want); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
@@ -0,0 +1,206 @@
+package symbolication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gocloud.dev/blob"
+
+	"github.com/getsentry/vroom/internal/debugmeta"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/storageutil"
+)
+
+type (
+	// Store loads debug files from a bucket and keeps the most recently
+	// loaded ones in memory.
+	Store struct {
+		files *storageutil.ObjectCache[*DebugFile]
+	}
+)
+
+const statusSymbolicated = "symbolicated"
+
+// StoragePath returns the path of the debug file of a project with the
+// given debug ID.
+func StoragePath(organizationID, projectID uint64, debugID string) string {
+	return fmt.Sprintf(
+		"debug-files/%d/%d/%s",
+		organizationID,
+		projectID,
+		strings.ToLower(debugID),
+	)
+}
+
+// NewStore returns a store keeping up to size debug files in memory.
+func NewStore(bucket *blob.Bucket, size int) *Store {
+	return &Store{
+		files: storageutil.NewObjectCache(
+			bucket,
+			storageutil.ObjectCacheOptions{Size: size},
+			readDebugFile,
+		),
+	}
+}
+
+// DebugFile returns the debug file of a project with the given debug ID or
+// storageutil.ErrObjectNotFound if it was not uploaded.
+func (s *Store) DebugFile(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	debugID string,
+) (*DebugFile, error) {
+	return s.files.Get(ctx, StoragePath(organizationID, projectID, debugID))
+}
+
+// readDebugFile reads the whole file in memory since ELF files are read
+// at random offsets.
+func readDebugFile(r io.Reader) (*DebugFile, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDebugFile(bytes.NewReader(b))
+}
+
+// Symbolicate symbolicates the native frames with an instruction address but
+// no function, using the debug file of the image containing the address.
+//
+// Leaves and callers tell which frames are at the top of the stacks and
+// which are below it. The address of a caller is a return address, pointing
+// after the call, so the instruction before it is looked up instead, like
+// symbolicator does. A frame found at both positions is looked up both
+// ways and, if it resolves below the top, copies tells the index of the
+// frames replacing it there.
+//
+// An address can resolve to several functions when some were inlined, so it
+// returns the new frames and, for each frame given then for each copy, the
+// indexes of the frames replacing it, the innermost one first. Frames are
+// nil if none was symbolicated.
+func (s *Store) Symbolicate(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	dm debugmeta.DebugMeta,
+	frames []frame.Frame,
+	leaves, callers []bool,
+) ([]frame.Frame, [][]int, map[int]int, error) {
+	symbolicatedFrames := make([]frame.Frame, 0, len(frames))
+	indexes := make([][]int, 0, len(frames))
+	var callerFrames [][]frame.Frame
+	var copies map[int]int
+	var errs []error
+	symbolicated := false
+	// files holds the debug files by debug ID, nil if missing
+	files := make(map[string]*DebugFile)
+	appendFrames := func(resolved []frame.Frame) []int {
+		frameIndexes := make([]int, 0, len(resolved))
+		for _, rf := range resolved {
+			frameIndexes = append(frameIndexes, len(symbolicatedFrames))
+			symbolicatedFrames = append(symbolicatedFrames, rf)
+		}
+		return frameIndexes
+	}
+	for i, f := range frames {
+		leaf := i < len(leaves) && leaves[i]
+		caller := i < len(callers) && callers[i]
+		resolved, err := s.symbolicateFrame(ctx, organizationID, projectID, dm, f, caller && !leaf, files)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if leaf && caller {
+			resolvedCaller, err := s.symbolicateFrame(ctx, organizationID, projectID, dm, f, true, files)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if len(resolvedCaller) > 0 {
+				if copies == nil {
+					copies = make(map[int]int)
+				}
+				copies[i] = len(frames) + len(callerFrames)
+				callerFrames = append(callerFrames, resolvedCaller)
+				symbolicated = true
+			}
+		}
+		if len(resolved) == 0 {
+			resolved = []frame.Frame{f}
+		} else {
+			symbolicated = true
+		}
+		indexes = append(indexes, appendFrames(resolved))
+	}
+	if !symbolicated {
+		return nil, nil, nil, errors.Join(errs...)
+	}
+	for _, resolved := range callerFrames {
+		indexes = append(indexes, appendFrames(resolved))
+	}
+	return symbolicatedFrames, indexes, copies, errors.Join(errs...)
+}
+
+func (s *Store) symbolicateFrame(
+	ctx context.Context,
+	organizationID, projectID uint64,
+	dm debugmeta.DebugMeta,
+	f frame.Frame,
+	caller bool,
+	files map[string]*DebugFile,
+) ([]frame.Frame, error) {
+	if f.InstructionAddr == "" || f.Function != "" {
+		return nil, nil
+	}
+	address, err := debugmeta.ParseAddress(f.InstructionAddr)
+	if err != nil {
+		return nil, nil
+	}
+	image, ok := dm.ImageContainingAddress(address)
+	if !ok || image.DebugID == "" {
+		return nil, nil
+	}
+	imageAddress, err := debugmeta.ParseAddress(image.ImageAddr)
+	if err != nil {
+		return nil, nil
+	}
+	d, exists := files[image.DebugID]
+	if !exists {
+		d, err = s.DebugFile(ctx, organizationID, projectID, image.DebugID)
+		files[image.DebugID] = d
+		if err != nil && !errors.Is(err, storageutil.ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+	if d == nil {
+		return nil, nil
+	}
+
+	lookupAddress := address - imageAddress
+	if caller && lookupAddress > 0 {
+		lookupAddress--
+	}
+	resolved := d.Lookup(lookupAddress)
+	frames := make([]frame.Frame, 0, len(resolved))
+	for _, r := range resolved {
+		sf := f
+		sf.Function = r.Function
+		sf.Line = r.Line
+		if r.Path != "" {
+			sf.Path = r.Path
+			sf.File = path.Base(r.Path)
+		}
+		if sf.Package == "" {
+			sf.Package = image.CodeFile
+		}
+		sf.SymAddr = ""
+		if r.SymbolAddress != 0 {
+			sf.SymAddr = fmt.Sprintf("%#x", imageAddress+r.SymbolAddress)
+		}
+		sf.Status = statusSymbolicated
+		sf.Data.SymbolicatorStatus = statusSymbolicated
+		frames = append(frames, sf)
+	}
+	return frames, nil
+}
@@ -0,0 +1,201 @@
+package symbolication
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"gocloud.dev/blob/memblob"
+
+	"github.com/getsentry/vroom/internal/debugmeta"
+	"github.com/getsentry/vroom/internal/frame"
+	"github.com/getsentry/vroom/internal/testutil"
+)
+
+func TestSymbolicate(t *testing.T) {
+	ctx := context.Background()
+	b, err := os.ReadFile("testdata/libinline.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	debugID := "5F3A1D0C-0B1E-4C3A-9D2B-7E6F5A4B3C2D"
+	if err := bucket.WriteAll(ctx, StoragePath(1, 2, debugID), b, nil); err != nil {
+		t.Fatal(err)
+	}
+	dm := debugmeta.DebugMeta{
+		Images: []debugmeta.Image{
+			{
+				CodeFile:  "/usr/lib/libinline.so",
+				DebugID:   debugID,
+				ImageAddr: "0x7f0000000000",
+				ImageSize: 0x5000,
+				Type:      "elf",
+			},
+			{
+				CodeFile:  "/usr/lib/libmissing.so",
+				DebugID:   "00000000-0000-0000-0000-000000000001",
+				ImageAddr: "0x7f0000010000",
+				ImageSize: 0x5000,
+				Type:      "elf",
+			},
+		},
+	}
+	frames := []frame.Frame{
+		{Function: "main", Package: "/usr/bin/app"},
+		{InstructionAddr: "0x7f0000001118"},
+		{InstructionAddr: "0x7f0000010010"},
+		{InstructionAddr: "0x7f0000001130"},
+		// returning from compute to run, in the middle of line 14 and not on
+		// line 15 starting at the return address when below the top
+		{InstructionAddr: "0x7f0000001139"},
+	}
+	// the last two frames are also found below the top of the stacks
+	leaves := []bool{false, true, true, true, true}
+	callers := []bool{true, false, true, false, true}
+
+	s := NewStore(bucket, 1)
+	symbolicated, indexes, copies, err := s.Symbolicate(ctx, 1, 2, dm, frames, leaves, callers)
+	if err != nil {
+		t.Fatalf("couldn't symbolicate: %v", err)
+	}
+	// paths are absolute, depending on where the file was built
+	for i := range symbolicated {
+		if symbolicated[i].Path != "" {
+			symbolicated[i].Path = path.Base(symbolicated[i].Path)
+		}
+	}
+
+	symbolicatedData := frame.Data{SymbolicatorStatus: "symbolicated"}
+	wantFrames := []frame.Frame{
+		{Function: "main", Package: "/usr/bin/app"},
+		{
+			Data:            symbolicatedData,
+			File:            "inline.c",
+			Function:        "square",
+			InstructionAddr: "0x7f0000001118",
+			Line:            5,
+			Package:         "/usr/lib/libinline.so",
+			Path:            "inline.c",
+			Status:          "symbolicated",
+		},
+		{
+			Data:            symbolicatedData,
+			File:            "inline.c",
+			Function:        "compute",
+			InstructionAddr: "0x7f0000001118",
+			Line:            9,
+			Package:         "/usr/lib/libinline.so",
+			Path:            "inline.c",
+			Status:          "symbolicated",
+			SymAddr:         "0x7f0000001110",
+		},
+		{InstructionAddr: "0x7f0000010010"},
+		{
+			Data:            symbolicatedData,
+			File:            "inline.c",
+			Function:        "run",
+			InstructionAddr: "0x7f0000001130",
+			Line:            13,
+			Package:         "/usr/lib/libinline.so",
+			Path:            "inline.c",
+			Status:          "symbolicated",
+			SymAddr:         "0x7f0000001130",
+		},
+		{
+			Data:            symbolicatedData,
+			File:            "inline.c",
+			Function:        "run",
+			InstructionAddr: "0x7f0000001139",
+			Line:            15,
+			Package:         "/usr/lib/libinline.so",
+			Path:            "inline.c",
+			Status:          "symbolicated",
+			SymAddr:         "0x7f0000001130",
+		},
+		{
+			Data:            symbolicatedData,
+			File:            "inline.c",
+			Function:        "run",
+			InstructionAddr: "0x7f0000001139",
+			Line:            14,
+			Package:         "/usr/lib/libinline.so",
+			Path:            "inline.c",
+			Status:          "symbolicated",
+			SymAddr:         "0x7f0000001130",
+		},
+	}
+	if diff := testutil.Diff(symbolicated, wantFrames); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if diff := testutil.Diff(indexes, [][]int{{0}, {1, 2}, {3}, {4}, {5}, {6}}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+	if diff := testutil.Diff(copies, map[int]int{4: 5}); diff != "" {
+		t.Fatalf("Result mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestSymbolicateUnresolvedFrames(t *testing.T) {
+	ctx := context.Background()
+	bucket := memblob.OpenBucket(nil)
+	defer bucket.Close()
+	dm := debugmeta.DebugMeta{
+		Images: []debugmeta.Image{
+			{
+				CodeFile:  "/usr/lib/libmissing.so",
+				DebugID:   "00000000-0000-0000-0000-000000000001",
+				ImageAddr: "0x7f0000010000",
+				ImageSize: 0x5000,
+				Type:      "elf",
+			},
+		},
+	}
+	frames := []frame.Frame{
+		{Function: "main", Package: "/usr/bin/app"},
+		{InstructionAddr: "0x7f0000010010"},
+	}
+
+	s := NewStore(bucket, 1)
+	symbolicated, indexes, copies, err := s.Symbolicate(ctx, 1, 2, dm, frames, []bool{false, true}, []bool{true, true})
+	if err != nil {
+		t.Fatalf("couldn't symbolicate: %v", err)
+	}
+	if symbolicated != nil || indexes != nil || copies != nil {
+		t.Fatalf("expected nothing to replace, got %v, %v and %v", symbolicated, indexes, copies)
+	}
+}
+
+func TestDemangle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "_ZN4core3ptr13drop_in_place17h1a2b3c4d5e6f7a8bE",
+			want: "core::ptr::drop_in_place",
+		},
+		{
+			name: "_ZN66_$LT$alloc..vec..Vec$LT$T$GT$$u20$as$u20$core..ops..drop..Drop$GT$4drop17h0123456789abcdefE",
+			want: "<alloc::vec::Vec<T> as core::ops::drop::Drop>::drop",
+		},
+		{
+			name: "compute",
+			want: "compute",
+		},
+		{
+			name: "_ZN3foo",
+			want: "_ZN3foo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := demangle(tt.name); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
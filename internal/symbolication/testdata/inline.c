@@ -0,0 +1,15 @@
+// Built with: gcc -g -O2 -shared -fPIC -o libinline.so inline.c
+
+static inline __attribute__((always_inline)) int square(int x) {
+	volatile int y = x;
+	return y * y;
+}
+
+__attribute__((noinline)) int compute(int x) {
+	int s = square(x);
+	return s + 1;
+}
+
+int run(int x) {
+	return compute(x) * 2;
+}
@@ -4,6 +4,17 @@ import "encoding/json"
 
 type Options struct {
 	ProjectDSN string `json:"dsn"`
+	// StackTraceRules are applied after the rules of the project, one per
+	// line, see the stacktracerules package.
+	StackTraceRules string `json:"stacktrace_rules"`
+	// GoMainModule is the import path of the main module of a Go program,
+	// as found in its build info, used to tell its frames from the ones of
+	// its dependencies.
+	GoMainModule string `json:"go_main_module"`
+	// StitchAsyncStacks attributes the samples taken while an async task was
+	// running to the tasks awaiting it, using the async task metadata sent
+	// by the SDK.
+	StitchAsyncStacks bool `json:"stitch_async_stacks"`
 }
 
 func (o Options) MarshalJSON() ([]byte, error) {
@@ -42,6 +42,7 @@ type (
 		Package     string            `json:"package"`
 		Fingerprint uint64            `json:"fingerprint"`
 		InApp       bool              `json:"in_app"`
+		P50         uint64            `json:"p50"`
 		P75         uint64            `json:"p75"`
 		P95         uint64            `json:"p95"`
 		P99         uint64            `json:"p99"`
@@ -50,6 +51,9 @@ type (
 		Count       uint64            `json:"count"`
 		Worst       ExampleMetadata   `json:"worst"`
 		Examples    []ExampleMetadata `json:"examples"`
+		// WorstExamples are the slowest examples seen, slowest first,
+		// while Examples are a uniform sample of all the examples seen.
+		WorstExamples []ExampleMetadata `json:"worst_examples"`
 	}
 )
 
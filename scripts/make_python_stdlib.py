@@ -4,6 +4,7 @@ import logging
 import os
 import os.path
 import tempfile
+from collections import defaultdict
 
 from git import Repo
 from sphinx.ext.intersphinx import fetch_inventory
@@ -23,6 +24,8 @@ VERSIONS = [
     "3.9",
     "3.10",
     "3.11",
+    "3.12",
+    "3.13",
 ]
 TARGET_GO_MODULE = "internal/frame/python_std_lib.go"
 
@@ -60,7 +63,7 @@ def fetch_documented_modules(versions):
         for module in invdata["py:module"]:
             root = module.split(".", 1)[0]
             if root not in SKIPPED_MODULES:
-                yield root
+                yield version, root
 
 
 def fetch_git_modules(versions):
@@ -106,7 +109,7 @@ def fetch_git_modules(versions):
                         continue
                     module = os.path.basename(module)
 
-                yield module
+                yield version, module
 
 
 PYTHON_STD_LIB_GO_TEMPLATE = \
@@ -116,22 +119,44 @@ PYTHON_STD_LIB_GO_TEMPLATE = \
 package frame
 
 var (
-\tpythonStdlib = map[string]struct{{}}{{
+\t// pythonStdlib maps the modules of the standard library to the minor
+\t// versions of Python 3 having them.
+\tpythonStdlib = map[string]pythonVersions{{
 {modules}
 \t}}
 )
 """
 
 
+def minor_version(version):
+    return int(version.split(".")[1])
+
+
+def format_versions(versions):
+    """
+    Formats the range of versions having a module, leaving out the bounds
+    of the range matching the first or the last version we know of.
+    """
+    fields = []
+    first = min(versions, key=minor_version)
+    last = max(versions, key=minor_version)
+    if first != VERSIONS[0]:
+        fields.append(f"added: {minor_version(first)}")
+    if last != VERSIONS[-1]:
+        fields.append(f"removed: {minor_version(last) + 1}")
+    return "{" + ", ".join(fields) + "}"
+
+
 def generate_python_std_lib_go(modules):
     logging.info("Generating Python stdlib module")
     max_len = max(len(module) for module in modules)
-    module_keys = [
-        f'"{module}":'.ljust(max_len + 3, " ")
-        for module in sorted(modules)
-    ]
+    module_keys = {
+        module: f'"{module}":'.ljust(max_len + 3, " ")
+        for module in modules
+    }
     formatted_modules = "\n".join(
-        f'\t\t{module} {{}},' for module in module_keys
+        f'\t\t{module_keys[module]} {format_versions(modules[module])},'
+        for module in sorted(modules)
     )
     return PYTHON_STD_LIB_GO_TEMPLATE.format(modules=formatted_modules)
 
@@ -140,7 +165,8 @@ def main():
 
     # Any modules we want to enforce across Python versions stdlib can be
     # included in set init
-    modules = {
+    modules = defaultdict(set)
+    for module in {
         "_ast",
         "posixpath",
         "ntpath",
@@ -148,13 +174,14 @@ def main():
         "sre_parse",
         "sre_compile",
         "sre",
-    }
+    }:
+        modules[module].update(VERSIONS)
 
-    for module in fetch_documented_modules(VERSIONS):
-        modules.add(module)
+    for version, module in fetch_documented_modules(VERSIONS):
+        modules[module].add(version)
 
-    for module in fetch_git_modules(VERSIONS):
-        modules.add(module)
+    for version, module in fetch_git_modules(VERSIONS):
+        modules[module].add(version)
 
     with open(TARGET_GO_MODULE, "w") as f:
         f.write(generate_python_std_lib_go(modules))